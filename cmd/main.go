@@ -2,47 +2,102 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io/fs"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/BurntSushi/toml"
-	"github.com/jackc/pgx/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 
 	"avito-tech-task/config"
+	"avito-tech-task/db"
 	deliveryBalance "avito-tech-task/internal/app/balance/delivery"
 	repositoryBalance "avito-tech-task/internal/app/balance/repository"
 	usecaseBalance "avito-tech-task/internal/app/balance/usecase"
+	deliveryCurrencies "avito-tech-task/internal/app/currencies/delivery"
+	usecaseCurrencies "avito-tech-task/internal/app/currencies/usecase"
+	deliveryHealth "avito-tech-task/internal/app/health/delivery"
+	usecaseHealth "avito-tech-task/internal/app/health/usecase"
+	"avito-tech-task/internal/app/idempotency"
+	deliveryIdempotency "avito-tech-task/internal/app/idempotency/delivery"
+	repositoryIdempotency "avito-tech-task/internal/app/idempotency/repository"
+	usecaseIdempotency "avito-tech-task/internal/app/idempotency/usecase"
+	deliveryLogging "avito-tech-task/internal/app/logging/delivery"
+	deliveryReserve "avito-tech-task/internal/app/reserve/delivery"
+	repositoryReserve "avito-tech-task/internal/app/reserve/repository"
+	usecaseReserve "avito-tech-task/internal/app/reserve/usecase"
 	deliveryTransactions "avito-tech-task/internal/app/transactions/delivery"
 	repositoryTransactions "avito-tech-task/internal/app/transactions/repository"
 	usecaseTransactions "avito-tech-task/internal/app/transactions/usecase"
 	"avito-tech-task/internal/pkg/constants"
 	"avito-tech-task/internal/pkg/currency"
+	"avito-tech-task/internal/pkg/lifecycle"
+	"avito-tech-task/internal/pkg/metrics"
+	"avito-tech-task/internal/pkg/migrator"
 	"avito-tech-task/internal/pkg/utils"
 )
 
 type Handlers struct {
-	BalanceHandlers      deliveryBalance.Handlers
-	TransactionsHandlers deliveryTransactions.Handlers
+	BalanceHandlers       deliveryBalance.Handlers
+	TransactionsHandlers  deliveryTransactions.Handlers
+	ReserveHandlers       deliveryReserve.Handlers
+	CurrenciesHandlers    deliveryCurrencies.Handlers
+	HealthHandlers        deliveryHealth.Handlers
+	IdempotencyMiddleware deliveryIdempotency.Middleware
+	IdempotencyService    idempotency.Service
 }
 
-func NewHandlers(conn utils.PgxIface, logger *logrus.Logger, validator *utils.Validation, converter *currency.Converter) *Handlers {
-	balanceStorage := repositoryBalance.NewStorage(conn)
-	balanceService := usecaseBalance.NewService(balanceStorage, validator, converter)
-	balanceHandlers := deliveryBalance.NewHandlers(balanceService, logger)
+func NewHandlers(pool utils.PgxIface, validator *utils.Validation, converter *currency.Converter,
+	serviceMetrics *metrics.Metrics, config *config.Config) *Handlers {
+	balanceStorage := repositoryBalance.NewStorage(pool)
+	balanceService := usecaseBalance.NewService(balanceStorage, validator, converter, serviceMetrics,
+		config.Currency.ExchangeSpread, config.Currency.QuoteTTL.Duration)
+	balanceHandlers := deliveryBalance.NewHandlers(balanceService)
 
-	transactionsStorage := repositoryTransactions.NewStorage(conn)
-	transactionsService := usecaseTransactions.NewService(transactionsStorage, validator)
-	transactionsHandlers := deliveryTransactions.NewHandlers(transactionsService, logger)
+	transactionsStorage := repositoryTransactions.NewStorage(pool)
+	transactionsService := usecaseTransactions.NewService(transactionsStorage, validator, converter, serviceMetrics)
+	transactionsHandlers := deliveryTransactions.NewHandlers(transactionsService)
+
+	reserveStorage := repositoryReserve.NewStorage(pool)
+	reserveService := usecaseReserve.NewService(reserveStorage, validator, serviceMetrics)
+	reserveHandlers := deliveryReserve.NewHandlers(reserveService)
+
+	currenciesService := usecaseCurrencies.NewService(converter)
+	currenciesHandlers := deliveryCurrencies.NewHandlers(currenciesService)
+
+	healthService := usecaseHealth.NewService(pool, converter)
+	healthHandlers := deliveryHealth.NewHandlers(healthService)
+
+	idempotencyStorage := repositoryIdempotency.NewStorage(pool)
+	idempotencyService := usecaseIdempotency.NewService(idempotencyStorage, config.IdempotencyKeyTTL.Duration,
+		config.IdempotencyLockLease.Duration)
+	idempotencyMiddleware := deliveryIdempotency.NewMiddleware(idempotencyService)
 
 	return &Handlers{
-		BalanceHandlers:      *balanceHandlers,
-		TransactionsHandlers: *transactionsHandlers,
+		BalanceHandlers:       *balanceHandlers,
+		TransactionsHandlers:  *transactionsHandlers,
+		ReserveHandlers:       *reserveHandlers,
+		CurrenciesHandlers:    *currenciesHandlers,
+		HealthHandlers:        *healthHandlers,
+		IdempotencyMiddleware: *idempotencyMiddleware,
+		IdempotencyService:    idempotencyService,
 	}
 }
 
+// migrationsFS returns the directory with migrations embedded into the binary.
+func migrationsFS() fs.FS {
+	migrations, err := fs.Sub(db.Migrations, "migrations")
+	if err != nil {
+		logrus.Fatalf("Could not open embedded migrations: %s", err)
+	}
+
+	return migrations
+}
+
 // @title        BalanceApplication
 // @version      1.0
 // @description  API for BalanceApplication
@@ -61,38 +116,104 @@ func main() {
 		logrus.Fatalf("Could not decode config: %s", err)
 	}
 
-	conn := utils.NewPostgresConnection(config)
-	defer func(conn *pgx.Conn, ctx context.Context) {
-		err := conn.Close(ctx)
+	pool := utils.NewPostgresPool(config)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		defer pool.Close()
+		migrations, err := migrator.NewMigrator(pool, migrationsFS(), logrus.StandardLogger())
 		if err != nil {
-			logrus.Fatalf("Could not close database connection: %s", err)
+			logrus.Fatalf("Could not load migrations: %s", err)
+		}
+		if err = runMigrate(context.Background(), migrations, os.Args[2:]); err != nil {
+			logrus.Fatalf("Could not migrate database: %s", err)
 		}
-	}(conn, context.Background())
+		return
+	}
 
 	logger, closeF := utils.NewLogger(config)
-	defer func(closeF func() error) {
-		if err := closeF(); err != nil {
-			logrus.Fatalf("Could not close file: %s", err)
+
+	if config.Server.AutoMigrate {
+		migrations, err := migrator.NewMigrator(pool, migrationsFS(), logger)
+		if err != nil {
+			logger.Fatalf("Could not load migrations: %s", err)
 		}
-	}(closeF)
+		if err = migrations.Up(context.Background()); err != nil {
+			logger.Fatalf("Could not migrate database: %s", err)
+		}
+	}
 
 	validator := utils.NewValidator()
 
-	converter := currency.NewConverter(config, logger)
-
-	api := NewHandlers(conn, logger, validator, converter)
-	api.BalanceHandlers.InitHandlers(server)
-	api.TransactionsHandlers.InitHandlers(server)
+	if config.Currency.ExchangeSpread < 0 || config.Currency.ExchangeSpread >= 1 {
+		logger.Fatalf("Exchange spread must be in [0, 1), got %v", config.Currency.ExchangeSpread)
+	}
+	providers, err := currency.NewProviders(config.Currency.Providers)
+	if err != nil {
+		logger.Fatalf("Could not configure exchange rate providers: %s", err)
+	}
+	serviceMetrics := metrics.New()
+	serviceMetrics.RegisterPool(pool)
+	converter := currency.NewConverter(currency.NewChain(constants.BaseCurrency, providers, logger),
+		currency.NewStorage(pool), config.Currency.CachePath, serviceMetrics, logger)
+	updater, err := currency.NewUpdater(converter, config.Currency, currency.RealClock(), logger)
+	if err != nil {
+		logger.Fatalf("Could not configure refresh of exchange rates: %s", err)
+	}
 
-	go func() {
-		server.Logger.Fatal(server.Start("0.0.0.0:5000"))
-	}()
+	server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+	server.Use(serviceMetrics.Middleware())
+	server.Use(utils.RequestLogger(logger))
+	server.Use(utils.ContextTimeout(config.Server.DBTimeout.Duration))
 
-	cancel := make(chan struct{})
-	go currency.UpdateCurrency(converter, cancel)
+	server.GET("/metrics", serviceMetrics.Handler())
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	<-done
-	cancel <- struct{}{}
+	api := NewHandlers(pool, validator, converter, serviceMetrics, config)
+	api.BalanceHandlers.InitHandlers(server, api.IdempotencyMiddleware.Handle)
+	api.TransactionsHandlers.InitHandlers(server)
+	api.ReserveHandlers.InitHandlers(server)
+	api.HealthHandlers.InitHandlers(server)
+	admin := utils.AdminOnly(config.Server.AdminToken)
+	api.CurrenciesHandlers.InitHandlers(server, admin)
+	deliveryLogging.NewHandlers(logger).InitHandlers(server, admin)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTimeout := config.Server.ShutdownTimeout.Duration
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = constants.DefaultShutdownTimeout
+	}
+	// the HTTP server is stopped first, so in-flight requests are finished while the jobs still run
+	workers := lifecycle.NewGroup(ctx, shutdownTimeout, logger)
+	workers.Go("HTTP server", func(context.Context) error {
+		if err := server.Start(constants.ServerAddress); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}, server.Shutdown)
+	workers.Go("currency updater", func(ctx context.Context) error {
+		updater.Run(ctx)
+		return nil
+	}, nil)
+	workers.Go("idempotency cleaner", func(ctx context.Context) error {
+		idempotency.CleanExpired(ctx, api.IdempotencyService, logger)
+		return nil
+	}, nil)
+	workers.Go("log level reloader", func(ctx context.Context) error {
+		utils.ReloadLogLevel(ctx, logger, constants.ConfigPath)
+		return nil
+	}, nil)
+
+	err = workers.Wait()
+	if err != nil {
+		logger.Errorf("Could not shut down gracefully: %s", err)
+	}
+	pool.Close()
+	logger.Info("Service stopped")
+	if closeErr := closeF(); closeErr != nil {
+		logrus.Fatalf("Could not close file: %s", closeErr)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
 }
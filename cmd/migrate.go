@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"avito-tech-task/internal/pkg/migrator"
+)
+
+const migrateUsage = `Usage: main migrate <command>
+
+Commands:
+  up        apply all pending migrations
+  down      revert the last applied migration
+  status    show applied and pending migrations
+  to N      migrate the schema up or down to version N, 0 reverts all migrations
+  force N   mark migrations up to N as applied without running them`
+
+// runMigrate executes the migrate subcommand with the arguments following it.
+func runMigrate(ctx context.Context, m *migrator.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("command is required\n%s", migrateUsage)
+	}
+
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		return m.Up(ctx)
+	case command == "down" && len(args) == 1:
+		return m.Down(ctx)
+	case command == "status" && len(args) == 1:
+		return printStatus(ctx, m)
+	case (command == "to" || command == "force") && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q\n%s", args[1], migrateUsage)
+		}
+		if command == "to" {
+			return m.To(ctx, version)
+		}
+		return m.Force(ctx, version)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args, migrateUsage)
+	}
+}
+
+func printStatus(ctx context.Context, m *migrator.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.Applied {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(writer, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+
+	return writer.Flush()
+}
@@ -1,14 +1,92 @@
 package config
 
+import "time"
+
 type ServerConfig struct {
-	DatabaseConnString string `toml:"database_conn_string"`
+	DatabaseConnString string   `toml:"database_conn_string"`
+	MaxConns           int32    `toml:"max_conns"`
+	MinConns           int32    `toml:"min_conns"`
+	MaxConnIdleTime    Duration `toml:"max_conn_idle_time"`
+	MaxConnLifetime    Duration `toml:"max_conn_lifetime"`
+	HealthCheckPeriod  Duration `toml:"health_check_period"`
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool `toml:"auto_migrate"`
+	// DBTimeout limits the time of database queries made while handling one request
+	DBTimeout Duration `toml:"db_timeout"`
+	// AdminToken is the bearer token of admin endpoints, they are disabled if it is empty
+	AdminToken string `toml:"admin_token"`
+	// ShutdownTimeout limits the time of draining in-flight requests and stopping background jobs on shutdown
+	ShutdownTimeout Duration `toml:"shutdown_timeout"`
+}
+
+// RateProviderConfig describes one exchange rates provider of the fallback chain.
+type RateProviderConfig struct {
+	// Type is one of cbr_json, cbr_xml and static, ecb is rejected because it does not quote RUB
+	Type    string   `toml:"type"`
+	URL     string   `toml:"url"`
+	Timeout Duration `toml:"timeout"`
+	// Path is a JSON file with rates of the static provider, Base and Rates are used if it is empty
+	Path  string             `toml:"path"`
+	Base  string             `toml:"base"`
+	Rates map[string]float64 `toml:"rates"`
+}
+
+type CurrencyConfig struct {
+	// Providers are asked in order until one of them returns rates
+	Providers []RateProviderConfig `toml:"providers"`
+	// CachePath is a file the last received rates are saved to, they are used on startup if all providers fail
+	CachePath string `toml:"cache_path"`
+	// ExchangeSpread is the share of the rate kept by the service on exchange between wallets of a user,
+	// e.g. 0.01 is 1%
+	ExchangeSpread float64 `toml:"exchange_spread"`
+	// QuoteTTL is the time an exchange quote locks the rate for
+	QuoteTTL Duration `toml:"quote_ttl"`
+	// RefreshAt is the time of the day in the HH:MM format the rates are refreshed at in RefreshTimezone
+	RefreshAt       string `toml:"refresh_at"`
+	RefreshTimezone string `toml:"refresh_timezone"`
+	// RetryMinBackoff is the delay before the first retry of a failed refresh, it doubles up to RetryMaxBackoff
+	RetryMinBackoff Duration `toml:"retry_min_backoff"`
+	RetryMaxBackoff Duration `toml:"retry_max_backoff"`
+}
+
+// LogRotationConfig limits the size and the number of log files, zero values are not limited
+// except MaxSizeMB which is 100 by default.
+type LogRotationConfig struct {
+	// MaxSizeMB is the size the log file is rotated at, the rotated file gets a timestamp in its name
+	MaxSizeMB int `toml:"max_size_mb"`
+	// MaxAge is the time rotated files are kept for, it is rounded up to days
+	MaxAge     Duration `toml:"max_age"`
+	MaxBackups int      `toml:"max_backups"`
+	Compress   bool     `toml:"compress"`
 }
 
 type Config struct {
-	LoggingLevel    string       `toml:"logging_level"`
-	LoggingFilePath string       `toml:"logging_file_path"`
-	CurrencyAPIURL  string       `toml:"currency_api_url"`
-	Server          ServerConfig `toml:"server"`
+	LoggingLevel string `toml:"logging_level"`
+	// LoggingFormat is json or text, json by default
+	LoggingFormat string `toml:"logging_format"`
+	// LoggingOutputs are stdout and file, records are written to all of them, only to the file by default
+	LoggingOutputs []string `toml:"logging_outputs"`
+	// LoggingFilePath is the directory of the log file
+	LoggingFilePath   string            `toml:"logging_file_path"`
+	LogRotation       LogRotationConfig `toml:"log_rotation"`
+	IdempotencyKeyTTL Duration          `toml:"idempotency_key_ttl"`
+	// IdempotencyLockLease is the time a key stays locked by a request that is not completed, it must be
+	// longer than requests are processed for
+	IdempotencyLockLease Duration       `toml:"idempotency_lock_lease"`
+	Currency             CurrencyConfig `toml:"currency"`
+	Server               ServerConfig   `toml:"server"`
+}
+
+// Duration is a time.Duration that can be decoded from strings like "24h" or "30s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+
+	return err
 }
 
 func NewConfig() *Config {
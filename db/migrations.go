@@ -0,0 +1,9 @@
+// Package db contains versioned schema migrations embedded into the binary.
+package db
+
+import "embed"
+
+// Migrations holds files named <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,3 @@
+drop table if exists transactions;
+drop type if exists operation_type;
+drop table if exists balance;
@@ -45,4 +45,4 @@ create unique index transactions_id_uindex
 
 create index transactions_sender_operation on transactions (sender, operation_type);
 create index transactions_sender_created on transactions (sender, created);
---|------------------Transactions------------------|--
\ No newline at end of file
+--|------------------Transactions------------------|--
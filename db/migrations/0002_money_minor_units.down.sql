@@ -0,0 +1,5 @@
+alter table balance
+    alter column balance type double precision using balance / 100.0;
+
+alter table transactions
+    alter column amount type double precision using amount / 100.0;
@@ -0,0 +1,7 @@
+-- Converts money columns from rubles stored as double precision into integer minor units (kopecks).
+-- Fractions of a kopeck accumulated by floating point errors are rounded to the nearest kopeck.
+alter table balance
+    alter column balance type bigint using round(balance::numeric * 100)::bigint;
+
+alter table transactions
+    alter column amount type bigint using round(amount::numeric * 100)::bigint;
@@ -0,0 +1,3 @@
+-- values 'reserve', 'revenue' and 'release' stay in operation_type, postgres can not drop enum values
+drop table if exists reservations;
+drop type if exists reservation_status;
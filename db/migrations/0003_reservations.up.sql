@@ -0,0 +1,29 @@
+alter type operation_type add value if not exists 'reserve';
+alter type operation_type add value if not exists 'revenue';
+alter type operation_type add value if not exists 'release';
+
+create type reservation_status as
+    enum ('reserved', 'committed', 'cancelled');
+
+create table reservations
+(
+    id         serial
+        constraint reservations_pk
+            primary key,
+    user_id    bigint                                        not null
+        constraint reservations_balance_user_id_fk
+            references balance (user_id)
+            on delete cascade,
+    order_id   bigint                                        not null,
+    service_id bigint                                        not null,
+    -- amount in minor units (kopecks)
+    amount     bigint                                        not null,
+    status     reservation_status       default 'reserved'   not null,
+    created    timestamp with time zone default now()        not null,
+    updated    timestamp with time zone default now()        not null
+);
+
+create unique index reservations_user_order_service_uindex
+    on reservations (user_id, order_id, service_id);
+
+create index reservations_user_status on reservations (user_id, status);
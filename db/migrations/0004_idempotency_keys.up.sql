@@ -0,0 +1,15 @@
+create table idempotency_keys
+(
+    key         varchar(255)                           not null
+        constraint idempotency_keys_pk
+            primary key,
+    -- sha256 of the request method, URI and body
+    fingerprint char(64)                               not null,
+    -- null while the request is still being processed
+    status_code integer,
+    response    bytea,
+    created     timestamp with time zone default now() not null,
+    expires     timestamp with time zone               not null
+);
+
+create index idempotency_keys_expires on idempotency_keys (expires);
@@ -0,0 +1,2 @@
+alter table balance
+    drop constraint if exists balance_non_negative;
@@ -0,0 +1,18 @@
+-- the constraint cannot be added while some balances are negative, they have to be corrected by hand first
+do $$
+declare
+    negative bigint;
+begin
+    select count(*) into negative from balance where balance < 0;
+    if negative > 0 then
+        raise exception 'cannot add constraint balance_non_negative: % users have negative balance', negative
+            using hint = 'correct balances from "select user_id, balance from balance where balance < 0"';
+    end if;
+end
+$$;
+
+alter table balance
+    drop constraint if exists balance_non_negative;
+alter table balance
+    add constraint balance_non_negative
+        check (balance >= 0);
@@ -0,0 +1,2 @@
+-- postgres can not drop enum values, 'correction' stays in operation_type
+select 1;
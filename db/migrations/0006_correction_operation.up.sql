@@ -0,0 +1,3 @@
+-- Postgres does not allow to use a new enum value in the transaction that added it,
+-- so the value used by the ledger backfill is added by a separate migration.
+alter type operation_type add value if not exists 'correction';
@@ -0,0 +1,5 @@
+drop view if exists balance_ledger_mismatches;
+drop table if exists postings;
+drop function if exists check_entry_is_balanced();
+drop type if exists system_account;
+delete from transactions where operation_type = 'correction';
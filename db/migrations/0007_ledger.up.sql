@@ -0,0 +1,120 @@
+--|------------------Ledger------------------|--
+-- Every operation in transactions is a journal entry with balanced postings: the sum of amounts
+-- of the entry postings is zero. A posting credits (positive amount) or debits (negative amount)
+-- either a user account or a system account, balance.balance is a cached sum of the user postings.
+create type system_account as
+    enum ('top_ups', 'write_offs', 'reservations', 'revenue');
+
+create table postings
+(
+    id             bigserial
+        constraint postings_pk
+            primary key,
+    transaction_id bigint not null
+        constraint postings_transactions_id_fk
+            references transactions (id)
+            on delete cascade,
+    user_id        bigint
+        constraint postings_balance_user_id_fk
+            references balance (user_id)
+            on delete cascade,
+    system_account system_account,
+    -- amount in minor units (kopecks)
+    amount         bigint not null,
+    -- balance of the user account after the posting, null for system accounts
+    balance_after  bigint,
+    constraint postings_one_account
+        check ((user_id is null) <> (system_account is null))
+);
+
+create index postings_transaction_id on postings (transaction_id);
+create index postings_user_id on postings (user_id, transaction_id);
+
+create function check_entry_is_balanced() returns trigger as
+$$
+begin
+    if (select coalesce(sum(amount), 0) <> 0 or count(*) < 2
+        from postings
+        where transaction_id = new.transaction_id) then
+        raise exception 'postings of transaction % are not balanced', new.transaction_id;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+-- checked at commit, when all postings of the entry are written
+create constraint trigger postings_balanced
+    after insert or update
+    on postings
+    deferrable initially deferred
+    for each row
+execute procedure check_entry_is_balanced();
+
+-- users whose cached balance differs from the sum of their postings, must always be empty
+create view balance_ledger_mismatches as
+select b.user_id, b.balance, coalesce(sum(p.amount), 0) as ledger_balance
+from balance b
+         left join postings p on p.user_id = b.user_id
+group by b.user_id, b.balance
+having b.balance <> coalesce(sum(p.amount), 0);
+--|------------------Ledger------------------|--
+
+
+--|------------------Backfill------------------|--
+insert into postings (transaction_id, user_id, system_account, amount)
+select t.id, p.user_id, p.system_account::system_account, p.amount
+from transactions t
+         cross join lateral (values
+    -- account credited by the operation
+    (case t.operation_type
+         when 'add' then t.sender
+         when 'transfer' then t.receiver
+         when 'release' then t.sender
+        end,
+     case t.operation_type
+         when 'write_off' then 'write_offs'
+         when 'reserve' then 'reservations'
+         when 'revenue' then 'revenue'
+        end,
+     coalesce(t.amount, 0)),
+    -- account debited by the operation
+    (case t.operation_type
+         when 'write_off' then t.sender
+         when 'transfer' then t.sender
+         when 'reserve' then t.sender
+        end,
+     case t.operation_type
+         when 'add' then 'top_ups'
+         when 'revenue' then 'reservations'
+         when 'release' then 'reservations'
+        end,
+     -coalesce(t.amount, 0))
+    ) as p(user_id, system_account, amount)
+where (p.user_id is null) <> (p.system_account is null);
+
+-- history written before the ledger may not add up to the balance, the difference is recorded as a correction
+with corrections as (
+    select b.user_id, b.balance - coalesce(sum(p.amount), 0) as amount
+    from balance b
+             left join postings p on p.user_id = b.user_id
+    group by b.user_id, b.balance
+    having b.balance <> coalesce(sum(p.amount), 0)
+),
+     entries as (
+         insert into transactions (operation_type, sender, amount)
+             select 'correction', user_id, amount from corrections
+             returning id, sender, amount
+     )
+insert
+into postings (transaction_id, user_id, system_account, amount)
+select id, sender, null::system_account, amount from entries
+union all
+select id, null, 'top_ups'::system_account, -amount from entries;
+
+update postings p
+set balance_after = running.balance_after
+from (select id, sum(amount) over (partition by user_id order by transaction_id, id) as balance_after
+      from postings
+      where user_id is not null) running
+where p.id = running.id;
+--|------------------Backfill------------------|--
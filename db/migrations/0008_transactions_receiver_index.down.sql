@@ -0,0 +1 @@
+drop index transactions_receiver_created;
@@ -0,0 +1,2 @@
+-- history of a user includes transfers received by the user
+create index transactions_receiver_created on transactions (receiver, created);
@@ -0,0 +1,4 @@
+alter table transactions
+    drop column comment,
+    drop column reason,
+    drop column source;
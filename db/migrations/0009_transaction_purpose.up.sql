@@ -0,0 +1,5 @@
+-- where and why the money was moved, all columns are optional
+alter table transactions
+    add column comment text,
+    add column reason  varchar(64),
+    add column source  jsonb;
@@ -0,0 +1 @@
+drop table currency_rates;
@@ -0,0 +1,10 @@
+-- every fetched snapshot of exchange rates, rate is the amount of the currency equal to one ruble on the date
+create table currency_rates
+(
+    currency varchar(3)       not null,
+    date     date             not null,
+    rate     double precision not null check (rate > 0),
+    source   varchar(32)      not null,
+    created  timestamptz      not null default now(),
+    primary key (currency, date)
+);
@@ -0,0 +1,71 @@
+-- wallets in other currencies and operations with them can not be represented with one ruble balance per user,
+-- so they are deleted
+delete
+from transactions
+where currency <> 'RUB'
+   or receiver_currency is not null;
+delete
+from balance
+where currency <> 'RUB';
+delete
+from users u
+where not exists(select from balance b where b.user_id = u.id);
+
+drop view balance_ledger_mismatches;
+create view balance_ledger_mismatches as
+select b.user_id, b.balance, coalesce(sum(p.amount), 0) as ledger_balance
+from balance b
+         left join postings p on p.user_id = b.user_id
+group by b.user_id, b.balance
+having b.balance <> coalesce(sum(p.amount), 0);
+
+create or replace function check_entry_is_balanced() returns trigger as
+$$
+begin
+    if (select coalesce(sum(amount), 0) <> 0 or count(*) < 2
+        from postings
+        where transaction_id = new.transaction_id) then
+        raise exception 'postings of transaction % are not balanced', new.transaction_id;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+alter table transactions
+    drop column currency,
+    drop column receiver_currency,
+    drop column receiver_amount,
+    drop column rate;
+
+alter table postings
+    drop column currency;
+
+-- postgres can not drop a value of an enum, 'exchange' stays unused
+
+drop index balance_user_id_currency_uindex;
+create unique index balance_user_id_uindex
+    on balance (user_id);
+
+alter table balance
+    drop constraint balance_users_id_fk,
+    drop column currency;
+
+alter table postings
+    drop constraint postings_users_id_fk,
+    add constraint postings_balance_user_id_fk
+        foreign key (user_id) references balance (user_id) on delete cascade;
+
+alter table reservations
+    drop constraint reservations_users_id_fk,
+    add constraint reservations_balance_user_id_fk
+        foreign key (user_id) references balance (user_id) on delete cascade;
+
+alter table transactions
+    drop constraint transactions_users_id_fk_2,
+    drop constraint transactions_users_id_fk,
+    add constraint transactions_balance_user_id_fk_2
+        foreign key (sender) references balance (user_id) on delete cascade,
+    add constraint transactions_balance_user_id_fk
+        foreign key (receiver) references balance (user_id) on delete cascade;
+
+drop table users;
@@ -0,0 +1,86 @@
+--|------------------Users------------------|--
+-- A user holds one wallet (balance row) per currency, so other tables reference users instead of balance.
+create table users
+(
+    id      bigint                                 not null
+        constraint users_pk
+            primary key,
+    created timestamp with time zone default now() not null
+);
+
+insert into users (id)
+select user_id
+from balance;
+
+alter table transactions
+    drop constraint transactions_balance_user_id_fk_2,
+    drop constraint transactions_balance_user_id_fk,
+    add constraint transactions_users_id_fk_2
+        foreign key (sender) references users (id) on delete cascade,
+    add constraint transactions_users_id_fk
+        foreign key (receiver) references users (id) on delete cascade;
+
+alter table reservations
+    drop constraint reservations_balance_user_id_fk,
+    add constraint reservations_users_id_fk
+        foreign key (user_id) references users (id) on delete cascade;
+
+alter table postings
+    drop constraint postings_balance_user_id_fk,
+    add constraint postings_users_id_fk
+        foreign key (user_id) references users (id) on delete cascade;
+--|------------------Users------------------|--
+
+
+--|------------------Wallets------------------|--
+-- existing balances are rubles, reservations are always made from the ruble wallet
+alter table balance
+    add column currency varchar(3) default 'RUB' not null,
+    add constraint balance_users_id_fk
+        foreign key (user_id) references users (id) on delete cascade;
+
+drop index balance_user_id_uindex;
+create unique index balance_user_id_currency_uindex
+    on balance (user_id, currency);
+--|------------------Wallets------------------|--
+
+
+--|------------------Ledger------------------|--
+-- postings are in the currency of the account, an entry must be balanced in every currency,
+-- so a conversion goes through the exchange system account
+alter type system_account add value if not exists 'exchange';
+
+alter table postings
+    add column currency varchar(3) default 'RUB' not null;
+
+-- amount is in currency, a cross-currency transfer also records the amount credited to the receiver
+-- in receiver_currency and the rate used: receiver_amount = amount * rate
+alter table transactions
+    add column currency          varchar(3) default 'RUB' not null,
+    add column receiver_currency varchar(3),
+    add column receiver_amount   bigint,
+    add column rate              double precision;
+
+create or replace function check_entry_is_balanced() returns trigger as
+$$
+begin
+    if (select count(*) < 2 from postings where transaction_id = new.transaction_id) or exists(
+            select
+            from postings
+            where transaction_id = new.transaction_id
+            group by currency
+            having sum(amount) <> 0) then
+        raise exception 'postings of transaction % are not balanced', new.transaction_id;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+drop view balance_ledger_mismatches;
+create view balance_ledger_mismatches as
+select b.user_id, b.currency, b.balance, coalesce(sum(p.amount), 0) as ledger_balance
+from balance b
+         left join postings p on p.user_id = b.user_id and p.currency = b.currency
+group by b.user_id, b.currency, b.balance
+having b.balance <> coalesce(sum(p.amount), 0);
+--|------------------Ledger------------------|--
@@ -0,0 +1,5 @@
+-- value 'exchange' stays in operation_type, postgres can not drop enum values
+delete
+from transactions
+where operation_type = 'exchange';
+drop table if exists exchange_quotes;
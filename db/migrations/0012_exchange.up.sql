@@ -0,0 +1,21 @@
+alter type operation_type add value if not exists 'exchange';
+
+-- a quote locks the exchange rate for the user until it expires, it is deleted when the exchange is made
+create table exchange_quotes
+(
+    id                uuid                     default gen_random_uuid() not null
+        constraint exchange_quotes_pk
+            primary key,
+    user_id           bigint                                             not null
+        constraint exchange_quotes_users_id_fk
+            references users (id)
+            on delete cascade,
+    currency          varchar(3)                                         not null,
+    receiver_currency varchar(3)                                         not null,
+    -- amount of receiver_currency for one unit of currency, the spread is already applied
+    rate              double precision                                   not null check (rate > 0),
+    expires           timestamp with time zone                           not null,
+    created           timestamp with time zone default now()             not null
+);
+
+create index exchange_quotes_user_expires on exchange_quotes (user_id, expires);
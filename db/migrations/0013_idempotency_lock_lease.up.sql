@@ -0,0 +1,4 @@
+-- the key of a request that is still being processed can be taken over after locked_until,
+-- so that a crashed request does not block retries until the key expires
+alter table idempotency_keys
+    add column if not exists locked_until timestamp with time zone default now() not null;
@@ -0,0 +1,2 @@
+alter table idempotency_keys
+    drop column if exists content_type;
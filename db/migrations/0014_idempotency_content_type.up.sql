@@ -0,0 +1,4 @@
+-- responses are replayed with their content type, e.g. application/problem+json for errors,
+-- null for responses stored before it was kept, they are replayed as application/json
+alter table idempotency_keys
+    add column if not exists content_type varchar(255);
@@ -0,0 +1,4 @@
+alter table idempotency_keys
+    drop column if exists applied;
+alter table idempotency_keys
+    drop column if exists lock_token;
@@ -0,0 +1,8 @@
+-- lock_token identifies the request holding the key, only it can save the response or release the key,
+-- so a request that was taken over after its lock lease can not overwrite the outcome of the retry
+alter table idempotency_keys
+    add column if not exists lock_token uuid;
+-- applied is set in the transaction that changes balances for the request, such a key is not taken over
+-- after the lock lease or released, so the money is never moved twice for one key
+alter table idempotency_keys
+    add column if not exists applied boolean default false not null;
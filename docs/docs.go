@@ -1,6 +1,6 @@
 // GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
 // This file was generated by swaggo/swag at
-// 2022-01-18 22:07:49.363709 +0300 MSK m=+0.019454376
+// 2026-10-17 08:45:28.427420482 +0000 UTC m=+0.116927175
 
 package docs
 
@@ -44,40 +44,52 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "description": "Currency to convert in",
+                        "description": "Currency of the wallet, RUB by default",
+                        "name": "wallet",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency to convert in, the wallet currency by default",
                         "name": "currency",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Do not convert with rates fetched earlier, e.g. 1h30m",
+                        "name": "max_rate_age",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UserData"
+                            "$ref": "#/definitions/models.Balance"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID in query param",
+                        "description": "Invalid user ID in query param | invalid max rate age",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "404": {
                         "description": "User not found",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "422": {
-                        "description": "Unsupported currency",
+                        "description": "Unsupported currency | negative max rate age | stale rates",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
@@ -95,6 +107,12 @@ var doc = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "Key to safely retry the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "Data for updating balance, operation = 0 - add money,operation = 1 - write off money",
                         "name": "data",
@@ -113,47 +131,149 @@ var doc = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID in query param | invalid request body",
+                        "description": "Invalid user ID in query param | invalid request body | malformed amount",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key was used with a different request | request is in progress",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "422": {
-                        "description": "Not enough money | Not supported operation type | Amount field is required | Negative user ID",
+                        "description": "Not enough money | Not supported operation type | Amount field is required | Negative user ID | Invalid comment, reason or source | Unsupported currency",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
             }
         },
-        "/transactions/{user_id}": {
+        "/currencies": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get supported currencies with actual rates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rates fetched earlier are reported as stale, e.g. 1h30m, 48h by default",
+                        "name": "max_age",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CurrencyRates"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query params",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Negative max age",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/currencies/refresh": {
             "post": {
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get list of user transactions",
+                "summary": "Get actual rates from providers right away",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID in BalanceApplication",
-                        "name": "user_id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CurrencyRates"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "Rates are unavailable from all providers, the current rates are kept",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/exchange": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Exchange money between wallets of the user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key to safely retry the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     },
                     {
-                        "description": "Parameters for transactions selection",
-                        "name": "params",
+                        "description": "Wallets, amount and optional quote of the exchange",
+                        "name": "data",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.TransactionsSelectionParams"
+                            "$ref": "#/definitions/models.ExchangeRequest"
                         }
                     }
                 ],
@@ -161,44 +281,56 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Transactions"
+                            "$ref": "#/definitions/models.ExchangeResult"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID in query param | invalid body",
+                        "description": "Invalid request body | malformed amount",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "404": {
-                        "description": "User not found",
+                        "description": "User not found | wallet not found | quote not found or expired",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key was used with a different request | request is in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Not enough money | negative user ID | amount field is required | unsupported currency | same currencies | quote for other currencies | amount is too small to convert | invalid comment, reason or source",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
             }
         },
-        "/transfer": {
+        "/exchange/quote": {
             "post": {
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Transfer money between users",
+                "summary": "Lock the exchange rate between wallets of the user",
                 "parameters": [
                     {
-                        "description": "Data for transferring money",
+                        "description": "User and currencies of the exchange",
                         "name": "data",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.TransferRequest"
+                            "$ref": "#/definitions/models.ExchangeQuoteRequest"
                         }
                     }
                 ],
@@ -206,102 +338,1113 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.TransferUsersData"
+                            "$ref": "#/definitions/models.ExchangeQuote"
                         }
                     },
                     "400": {
                         "description": "Invalid request body",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "404": {
-                        "description": "Sender not found | receiver not found",
+                        "description": "User not found",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "422": {
-                        "description": "Not enough money",
+                        "description": "Negative user ID | unsupported currency | same currencies",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/models.ResponseMessage"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.RequestUpdateBalance": {
-            "type": "object",
-            "required": [
-                "amount"
-            ],
-            "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "operation_type": {
-                    "type": "integer"
-                },
-                "user_id": {
-                    "type": "integer"
-                }
-            }
         },
-        "models.ResponseMessage": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
+        "/logging/level": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the current log level",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LogLevel"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
                 }
-            }
-        },
-        "models.Transaction": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "created": {
-                    "type": "string"
-                },
-                "operation_type": {
-                    "type": "string"
-                },
-                "receiver_id": {
-                    "type": "integer"
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change the log level until restart, the level of the config file is set again on SIGHUP",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "New log level",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LogLevel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LogLevel"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unknown log level",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
                 }
             }
         },
-        "models.Transactions": {
-            "type": "array",
-            "items": {
-                "$ref": "#/definitions/models.Transaction"
+        "/reserve": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Reserve money on user balance for an order",
+                "parameters": [
+                    {
+                        "description": "Order, service and amount to reserve",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Reservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body | malformed amount",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Reservation already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Not enough money | Amount field is required | Invalid IDs",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
             }
         },
-        "models.TransactionsSelectionParams": {
-            "type": "object",
-            "properties": {
+        "/reserve/cancel": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Release reserved money back to user balance",
+                "parameters": [
+                    {
+                        "description": "Order and service of the reservation",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Reservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid IDs",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reserve/commit": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Recognize reserved money as revenue",
+                "parameters": [
+                    {
+                        "description": "Order and service of the reservation",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Reservation"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Reservation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid IDs",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{user_id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get list of user transactions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID in BalanceApplication",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of transactions, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transactions created at or after the date in RFC3339 format",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Operation type",
+                        "name": "operation_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "format": "multi",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Operation types, combined with operation_type",
+                        "name": "operation_types",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "format": "multi",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "description": "Users transfers were made from or to",
+                        "name": "counterparty_ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Sort by amount",
+                        "name": "order_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Sort by date",
+                        "name": "order_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Direction of sorting",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort keys with optional direction, e.g. amount:asc,created:desc",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason of the operation",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transactions created at or after the date in RFC3339 format",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transactions created before the date in RFC3339 format",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Min amount of transaction",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Max amount of transaction",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency to convert transactions in at the rate of the day they were created",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID in query param | invalid query params | malformed amount",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Negative limit | invalid order, sort or cursor | negative amount filter | not supported operation type or currency",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Search user transactions with parameters in body",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID in BalanceApplication",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Parameters for transactions selection",
+                        "name": "params",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionsSelectionParams"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionsPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID in query param | invalid body | malformed amount",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Negative limit | invalid order, sort or cursor | negative amount filter | not supported operation type or currency",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/transfer": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Transfer money between users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key to safely retry the request",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Data for transferring money",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferUsersData"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body | malformed amount",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Sender not found | receiver not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key was used with a different request | request is in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Not enough money | transfer to the same user | invalid comment, reason or source | unsupported currency | amount is too small to convert",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.Balance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 13.1
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "rate": {
+                    "description": "Rate is the amount of currency for one unit of the wallet currency",
+                    "type": "number",
+                    "example": 0.0131
+                },
+                "rate_date": {
+                    "type": "string",
+                    "example": "2022-01-19"
+                },
+                "reserved": {
+                    "type": "number",
+                    "example": 0
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "wallet": {
+                    "type": "string",
+                    "example": "RUB"
+                }
+            }
+        },
+        "models.ConvertedAmount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount and BalanceAfter have as many fractional digits as the minor unit of the currency, e.g. none for JPY",
+                    "type": "number",
+                    "example": 13.1
+                },
+                "balance_after": {
+                    "type": "number",
+                    "example": 19.65
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "rate": {
+                    "description": "Rate is the amount of currency for one unit of the transaction currency",
+                    "type": "number"
+                },
+                "rate_date": {
+                    "description": "RateDate is the date of the rate used, it is the closest date at or before the transaction date in UTC",
+                    "type": "string",
+                    "example": "2022-01-19"
+                }
+            }
+        },
+        "models.CurrencyRate": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "digits": {
+                    "description": "Digits is the number of minor unit digits of the currency according to ISO 4217",
+                    "type": "integer",
+                    "example": 2
+                },
+                "rate": {
+                    "description": "Rate is the amount of the currency for one unit of the base currency",
+                    "type": "number",
+                    "example": 0.0131
+                }
+            }
+        },
+        "models.CurrencyRates": {
+            "type": "object",
+            "properties": {
+                "age_seconds": {
+                    "description": "AgeSeconds is the number of seconds passed since the rates were fetched",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "base": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "date": {
+                    "description": "Date is the day the rates were set for by the provider",
+                    "type": "string",
+                    "example": "2022-01-19"
+                },
+                "fetched": {
+                    "description": "Fetched is the time the rates were received from the provider, it is absent if they never were",
+                    "type": "string"
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CurrencyRate"
+                    }
+                },
+                "source": {
+                    "type": "string",
+                    "example": "cbr_json"
+                },
+                "stale": {
+                    "description": "Stale is true if the rates are older than the requested max age",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ExchangeQuote": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "quote_id": {
+                    "type": "string",
+                    "example": "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
+                },
+                "rate": {
+                    "description": "Rate is the amount of to for one unit of from, the spread is already applied",
+                    "type": "number",
+                    "example": 0.013
+                },
+                "to": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ExchangeQuoteRequest": {
+            "type": "object",
+            "required": [
+                "from",
+                "to"
+            ],
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.ExchangeRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "from",
+                "to"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 1000.5
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Payment for order #10"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "quote_id": {
+                    "description": "QuoteID exchanges at the rate of the quote, the current rate is used if it is empty",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Reason is a short machine-readable category of the operation, history can be filtered by it",
+                    "type": "string",
+                    "example": "order_payment"
+                },
+                "source": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.Source"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.ExchangeResult": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount is debited from the wallet in from, Received is credited to the wallet in to",
+                    "type": "number"
+                },
+                "from": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.UserData"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "received": {
+                    "type": "number"
+                },
+                "to": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.UserData"
+                }
+            }
+        },
+        "models.LogLevel": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "example": "info"
+                }
+            }
+        },
+        "models.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is a stable machine-readable identifier of the error",
+                    "type": "string",
+                    "example": "not_enough_money"
+                },
+                "correlation_id": {
+                    "description": "CorrelationID identifies the log record of an internal error, details of it are not shown",
+                    "type": "string",
+                    "example": "5f0c2a6e9d1b4c3a8e7f6d5c4b3a2910"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "not enough money on balance"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 422
+                },
+                "supported_currencies": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "EUR",
+                        "RUB",
+                        "USD"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unprocessable Entity"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "models.RequestUpdateBalance": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 1000.5
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Payment for order #10"
+                },
+                "currency": {
+                    "description": "Currency is the wallet to update, RUB by default",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "operation_type": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "Reason is a short machine-readable category of the operation, history can be filtered by it",
+                    "type": "string",
+                    "example": "order_payment"
+                },
+                "source": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.Source"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Reservation": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReservationRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Amount is only used to reserve money, commit and cancel take the amount of the reservation",
+                    "type": "number",
+                    "example": 1000.5
+                },
+                "order_id": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "service_id": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.Source": {
+            "type": "object",
+            "properties": {
+                "order_id": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "promo_code": {
+                    "type": "string",
+                    "example": "WELCOME100"
+                },
+                "service_id": {
+                    "type": "integer",
+                    "example": 100
+                }
+            }
+        },
+        "models.Transaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "balance_after": {
+                    "description": "BalanceAfter is the balance of the user wallet after the operation, it is missing for operations\nthat do not change the balance such as commit of a reservation",
+                    "type": "number"
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Payment for order #10"
+                },
+                "converted": {
+                    "description": "Converted is set if the list was requested in another currency",
+                    "type": "object",
+                    "$ref": "#/definitions/models.ConvertedAmount"
+                },
+                "counterparty_id": {
+                    "description": "CounterpartyID is the other user of a transfer",
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "Currency is the currency of amount",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "direction": {
+                    "description": "Direction is incoming if the operation credited the user balance and outgoing otherwise",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "operation_type": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "reason": {
+                    "description": "Reason is a short machine-readable category of the operation, history can be filtered by it",
+                    "type": "string",
+                    "example": "order_payment"
+                },
+                "receiver_amount": {
+                    "type": "number"
+                },
+                "receiver_currency": {
+                    "description": "ReceiverCurrency, ReceiverAmount and Rate are set for transfers with conversion,\nthe receiver got receiver_amount = amount * rate",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "source": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.Source"
+                }
+            }
+        },
+        "models.Transactions": {
+            "type": "array",
+            "items": {
+                "$ref": "#/definitions/models.Transaction"
+            }
+        },
+        "models.TransactionsPage": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.Transactions"
+                },
+                "next_cursor": {
+                    "description": "NextCursor is passed as cursor to get the next page, it is empty on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TransactionsSelectionParams": {
+            "type": "object",
+            "properties": {
+                "counterparty_ids": {
+                    "description": "CounterpartyIDs selects transfers from and to any of the users",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "currency": {
+                    "description": "Currency converts every transaction at the rate of the day it was created",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "cursor": {
+                    "description": "Cursor is next_cursor of the previous page, it must be used with the same sorting",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "From and To select transactions created in [from, to)",
+                    "type": "string"
+                },
                 "limit": {
                     "type": "integer"
                 },
+                "max_amount": {
+                    "type": "number"
+                },
+                "min_amount": {
+                    "description": "MinAmount and MaxAmount select transactions with amount in [min_amount, max_amount]",
+                    "type": "number"
+                },
                 "operation_type": {
                     "type": "integer"
                 },
+                "operation_types": {
+                    "description": "OperationTypes selects transactions of any of the types, it is combined with OperationType",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "order": {
+                    "description": "Order is the direction of sorting, desc by default",
+                    "type": "string",
+                    "enum": [
+                        "asc",
+                        "desc"
+                    ]
+                },
                 "order_amount": {
                     "type": "boolean"
                 },
                 "order_date": {
                     "type": "boolean"
                 },
+                "reason": {
+                    "type": "string"
+                },
                 "since": {
+                    "description": "Since selects transactions created at or after it, it is the same as From",
+                    "type": "string"
+                },
+                "sort": {
+                    "description": "Sort is a list of amount and created keys with optional direction, e.g. amount:asc,created:desc,\nit overrides order_amount, order_date and order",
+                    "type": "string",
+                    "example": "amount:asc,created:desc"
+                },
+                "to": {
                     "type": "string"
                 }
             }
@@ -316,7 +1459,26 @@ var doc = `{
             "properties": {
                 "amount": {
                     "type": "number",
-                    "example": 1000
+                    "example": 1000.5
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Payment for order #10"
+                },
+                "currency": {
+                    "description": "Currency is the sender wallet and the currency of amount, RUB by default",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "reason": {
+                    "description": "Reason is a short machine-readable category of the operation, history can be filtered by it",
+                    "type": "string",
+                    "example": "order_payment"
+                },
+                "receiver_currency": {
+                    "description": "ReceiverCurrency is the receiver wallet, it is the sender currency by default. If it differs,\namount is converted at the current rate",
+                    "type": "string",
+                    "example": "USD"
                 },
                 "receiver_id": {
                     "type": "integer",
@@ -325,6 +1487,10 @@ var doc = `{
                 "sender_id": {
                     "type": "integer",
                     "example": 1
+                },
+                "source": {
+                    "type": "object",
+                    "$ref": "#/definitions/models.Source"
                 }
             }
         },
@@ -347,10 +1513,30 @@ var doc = `{
                 "balance": {
                     "type": "number"
                 },
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "reserved": {
+                    "type": "number"
+                },
                 "user_id": {
                     "type": "integer"
                 }
             }
+        },
+        "money.Amount": {
+            "type": "object",
+            "properties": {
+                "digits": {
+                    "description": "Digits is the number of minor unit digits of the currency, 2 for USD and 0 for JPY",
+                    "type": "integer"
+                },
+                "minor": {
+                    "description": "Minor is the amount in minor units of the currency, e.g. cents or yen",
+                    "type": "integer"
+                }
+            }
         }
     },
     "x-extension-openapi": {
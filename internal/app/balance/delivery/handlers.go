@@ -1,33 +1,36 @@
 package delivery
 
 import (
-	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 
 	"avito-tech-task/internal/app/balance"
 	"avito-tech-task/internal/app/models"
-	"avito-tech-task/internal/pkg/constants"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
 )
 
 type Handlers struct {
 	service balance.Service
-	logger  *logrus.Logger
 }
 
-func NewHandlers(service balance.Service, logger *logrus.Logger) *Handlers {
+func NewHandlers(service balance.Service) *Handlers {
 	return &Handlers{
 		service: service,
-		logger:  logger}
+	}
 }
 
-func (h *Handlers) InitHandlers(server *echo.Echo) {
-	server.POST("/api/v1/balance/:user_id", h.UpdateBalance)
-	server.POST("/api/v1/transfer", h.Transfer)
+// InitHandlers registers balance routes, idempotency middleware guards the routes that move money.
+func (h *Handlers) InitHandlers(server *echo.Echo, idempotency echo.MiddlewareFunc) {
+	server.POST("/api/v1/balance/:user_id", h.UpdateBalance, idempotency)
+	server.POST("/api/v1/transfer", h.Transfer, idempotency)
+	server.POST("/api/v1/exchange", h.Exchange, idempotency)
+	server.POST("/api/v1/exchange/quote", h.CreateQuote)
 
 	server.GET("/api/v1/balance/:user_id", h.GetBalance)
 }
@@ -35,52 +38,32 @@ func (h *Handlers) InitHandlers(server *echo.Echo) {
 // Transfer
 // @Summary 	Transfer money between users
 // @Produce 	json
+// @Param 		Idempotency-Key header string false "Key to safely retry the request"
 // @Param 		data body models.TransferRequest true "Data for transferring money"
 // @Success 	200 {object} models.TransferUsersData
-// @Failure		400 {object} models.ResponseMessage "Invalid request body"
-// @Failure		404 {object} models.ResponseMessage "Sender not found | receiver not found"
-// @Failure		422 {object} models.ResponseMessage "Not enough money"
-// @Failure		500 {object} models.ResponseMessage "Internal server error"
+// @Failure		400 {object} models.Problem "Invalid request body | malformed amount"
+// @Failure		404 {object} models.Problem "Sender not found | receiver not found"
+// @Failure		409 {object} models.Problem "Idempotency key was used with a different request | request is in progress"
+// @Failure		422 {object} models.Problem "Not enough money | transfer to the same user | invalid comment, reason or source | unsupported currency | amount is too small to convert"
+// @Failure		500 {object} models.Problem "Internal server error"
 // @Router 		/transfer [POST]
 func (h *Handlers) Transfer(ctx echo.Context) error {
-	h.logger.Info("Called handler Transfer for POST /api/v1/transfer")
+	log := utils.Log(ctx.Request().Context())
 
 	var transferData models.TransferRequest
 	if err := ctx.Bind(&transferData); err != nil {
-		h.logger.Warnf("Could not bind request body to models.RequestUpdateBalance: %s", err)
-		return ctx.JSON(
-			http.StatusBadRequest,
-			&models.ResponseMessage{Message: constants.InvalidBodyMessage})
+		log.WithError(err).Warn("Could not bind request body to models.RequestUpdateBalance")
+		return utils.BindError(err)
 	}
-	h.logger.Infof("Request data: %v", transferData)
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": transferData.SenderID})
+	log.WithField("request", transferData).Debug("Request data")
 
-	transferResult, err := h.service.MakeTransfer(&transferData)
+	transferResult, err := h.service.MakeTransfer(ctx.Request().Context(), &transferData)
 	if err != nil {
-		switch errors.Is(err, createdErrors.ErrNotEnoughMoney) {
-		case true:
-			h.logger.Warnf("Unprocesseable request: %s", err)
-			return ctx.JSON(
-				http.StatusUnprocessableEntity,
-				&models.ResponseMessage{Message: err.Error()})
-		default:
-			switch errors.Is(err, createdErrors.ErrSenderDoesNotExist) ||
-				errors.Is(err, createdErrors.ErrReceiverDoesNotExist) {
-			case true:
-				h.logger.Warnf("%s", err)
-				return ctx.JSON(
-					http.StatusNotFound,
-					&models.ResponseMessage{Message: err.Error()})
-			default:
-				h.logger.Errorf("Internal server error: %s", err)
-				return ctx.JSON(
-					http.StatusInternalServerError,
-					&models.ResponseMessage{Message: err.Error()})
-			}
-		}
+		return err
 	}
 
-	h.logger.Infof("Money transfer from %d to %d was successfully processed, received response: %v",
-		transferData.SenderID, transferData.ReceiverID, transferResult)
+	log.WithField("response", transferResult).Debug("Money transfer was processed")
 	return ctx.JSON(http.StatusOK, transferResult)
 }
 
@@ -88,51 +71,41 @@ func (h *Handlers) Transfer(ctx echo.Context) error {
 // @Summary 	Get user balance
 // @Produce 	json
 // @Param 		user_id path int true "User ID in BalanceApplication"
-// @Param 		currency query string false "Currency to convert in"
-// @Success 	200 {object} models.UserData
-// @Failure		400 {object} models.ResponseMessage "Invalid user ID in query param"
-// @Failure		404 {object} models.ResponseMessage "User not found"
-// @Failure		422 {object} models.ResponseMessage "Unsupported currency"
-// @Failure		500 {object} models.ResponseMessage "Internal server error"
+// @Param 		wallet query string false "Currency of the wallet, RUB by default"
+// @Param 		currency query string false "Currency to convert in, the wallet currency by default"
+// @Param 		max_rate_age query string false "Do not convert with rates fetched earlier, e.g. 1h30m"
+// @Success 	200 {object} models.Balance
+// @Failure		400 {object} models.Problem "Invalid user ID in query param | invalid max rate age"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		422 {object} models.Problem "Unsupported currency | negative max rate age | stale rates"
+// @Failure		500 {object} models.Problem "Internal server error"
 // @Router 		/balance/{user_id} [GET]
 func (h *Handlers) GetBalance(ctx echo.Context) error {
-	h.logger.Info("Called handler GetBalance for GET /api/v1/balance/:id")
+	log := utils.Log(ctx.Request().Context())
 
 	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
 	if err != nil {
-		h.logger.Warnf("Could not convert user id from string to int: %s", err)
-		return ctx.JSON(
-			http.StatusBadRequest,
-			&models.ResponseMessage{Message: constants.InvalidUserIDMessage})
+		log.WithError(err).Warn("Could not convert user id from string to int")
+		return createdErrors.ErrInvalidUserID
 	}
-	currency := ctx.QueryParam("currency")
-	h.logger.Infof("Request data: userID: %d, currency: %s", userID, currency)
-
-	balance, err := h.service.GetBalance(userID, currency)
-	switch errors.Is(err, createdErrors.ErrNotSupportedCurrency) {
-	case true:
-		h.logger.Warnf("Bad request: %s", err)
-		return ctx.JSON(
-			http.StatusUnprocessableEntity,
-			&models.ResponseMessage{Message: err.Error()})
-	case false:
-		switch errors.Is(err, createdErrors.ErrUserDoesNotExist) {
-		case true:
-			h.logger.Warnf("%s", err)
-			return ctx.JSON(
-				http.StatusNotFound,
-				&models.ResponseMessage{Message: err.Error()})
-		case false:
-			if err != nil {
-				h.logger.Errorf("Internal server error: %s", err)
-				return ctx.JSON(
-					http.StatusInternalServerError,
-					&models.ResponseMessage{Message: err.Error()})
-			}
+	wallet, currency := ctx.QueryParam("wallet"), ctx.QueryParam("currency")
+	var maxRateAge time.Duration
+	if param := ctx.QueryParam("max_rate_age"); param != "" {
+		if maxRateAge, err = time.ParseDuration(param); err != nil {
+			log.WithError(err).Warn("Could not parse max rate age")
+			return fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, err)
 		}
 	}
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": userID})
+	log.WithFields(logrus.Fields{"wallet": wallet, "currency": currency, "max_rate_age": maxRateAge.String()}).
+		Debug("Request data")
+
+	balance, err := h.service.GetBalance(ctx.Request().Context(), userID, wallet, currency, maxRateAge)
+	if err != nil {
+		return err
+	}
 
-	h.logger.Infof("Request was successfully processed, received user data: %v", balance)
+	log.WithField("response", balance).Debug("Request was processed")
 	return ctx.JSON(http.StatusOK, balance)
 }
 
@@ -140,38 +113,93 @@ func (h *Handlers) GetBalance(ctx echo.Context) error {
 // @Summary 	Update user balance
 // @Produce 	json
 // @Param 		user_id path int true "User ID in BalanceApplication"
+// @Param 		Idempotency-Key header string false "Key to safely retry the request"
 // @Param 		data body models.RequestUpdateBalance true "Data for updating balance, operation = 0 - add money,operation = 1 - write off money"
 // @Success 	200 {object} models.UserData
-// @Failure		400 {object} models.ResponseMessage "Invalid user ID in query param | invalid request body"
-// @Failure		422 {object} models.ResponseMessage "Not enough money | Not supported operation type | Amount field is required | Negative user ID"
-// @Failure		500 {object} models.ResponseMessage "Internal server error"
+// @Failure		400 {object} models.Problem "Invalid user ID in query param | invalid request body | malformed amount"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		409 {object} models.Problem "Idempotency key was used with a different request | request is in progress"
+// @Failure		422 {object} models.Problem "Not enough money | Not supported operation type | Amount field is required | Negative user ID | Invalid comment, reason or source | Unsupported currency"
+// @Failure		500 {object} models.Problem "Internal server error"
 // @Router 		/balance/{user_id} [POST]
 func (h *Handlers) UpdateBalance(ctx echo.Context) error {
-	h.logger.Info("Called handler UpdateBalance for POST /api/v1/balance/:id")
+	log := utils.Log(ctx.Request().Context())
 
 	var updateData models.RequestUpdateBalance
 	if err := ctx.Bind(&updateData); err != nil {
-		h.logger.Warnf("Could not bind request body to models.RequestUpdateBalance: %s", err)
-		return ctx.JSON(
-			http.StatusBadRequest,
-			&models.ResponseMessage{Message: constants.InvalidBodyMessage})
+		log.WithError(err).Warn("Could not bind request body to models.RequestUpdateBalance")
+		return utils.BindError(err)
 	}
-	h.logger.Infof("Request data: %v", updateData)
-
-	userData, err := h.service.UpdateBalance(&updateData)
-	if errors.Is(err, createdErrors.ErrNotEnoughMoney) || errors.Is(err, createdErrors.ErrNotSupportedOperationType) ||
-		errors.Is(err, createdErrors.ErrAmountFiledIsRequired) || errors.Is(err, createdErrors.ErrNegativeUserID) {
-		h.logger.Warnf("Bad request: %s", err)
-		return ctx.JSON(
-			http.StatusUnprocessableEntity,
-			&models.ResponseMessage{Message: err.Error()})
-	} else if err != nil {
-		h.logger.Errorf("Internal server error: %s", err)
-		return ctx.JSON(
-			http.StatusInternalServerError,
-			&models.ResponseMessage{Message: err.Error()})
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": updateData.UserID})
+	log.WithField("request", updateData).Debug("Request data")
+
+	userData, err := h.service.UpdateBalance(ctx.Request().Context(), &updateData)
+	if err != nil {
+		return err
 	}
 
-	h.logger.Infof("Request was successfully processed, received response: %v", userData)
+	log.WithField("response", userData).Debug("Request was processed")
 	return ctx.JSON(http.StatusOK, userData)
 }
+
+// CreateQuote
+// @Summary 	Lock the exchange rate between wallets of the user
+// @Produce 	json
+// @Param 		data body models.ExchangeQuoteRequest true "User and currencies of the exchange"
+// @Success 	200 {object} models.ExchangeQuote
+// @Failure		400 {object} models.Problem "Invalid request body"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		422 {object} models.Problem "Negative user ID | unsupported currency | same currencies"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/exchange/quote [POST]
+func (h *Handlers) CreateQuote(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	var quoteData models.ExchangeQuoteRequest
+	if err := ctx.Bind(&quoteData); err != nil {
+		log.WithError(err).Warn("Could not bind request body to models.ExchangeQuoteRequest")
+		return utils.BindError(err)
+	}
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": quoteData.UserID})
+	log.WithField("request", quoteData).Debug("Request data")
+
+	quote, err := h.service.CreateQuote(ctx.Request().Context(), &quoteData)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("response", quote).Debug("Request was processed")
+	return ctx.JSON(http.StatusOK, quote)
+}
+
+// Exchange
+// @Summary 	Exchange money between wallets of the user
+// @Produce 	json
+// @Param 		Idempotency-Key header string false "Key to safely retry the request"
+// @Param 		data body models.ExchangeRequest true "Wallets, amount and optional quote of the exchange"
+// @Success 	200 {object} models.ExchangeResult
+// @Failure		400 {object} models.Problem "Invalid request body | malformed amount"
+// @Failure		404 {object} models.Problem "User not found | wallet not found | quote not found or expired"
+// @Failure		409 {object} models.Problem "Idempotency key was used with a different request | request is in progress"
+// @Failure		422 {object} models.Problem "Not enough money | negative user ID | amount field is required | unsupported currency | same currencies | quote for other currencies | amount is too small to convert | invalid comment, reason or source"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/exchange [POST]
+func (h *Handlers) Exchange(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	var exchangeData models.ExchangeRequest
+	if err := ctx.Bind(&exchangeData); err != nil {
+		log.WithError(err).Warn("Could not bind request body to models.ExchangeRequest")
+		return utils.BindError(err)
+	}
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": exchangeData.UserID})
+	log.WithField("request", exchangeData).Debug("Request data")
+
+	exchangeResult, err := h.service.Exchange(ctx.Request().Context(), &exchangeData)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("response", exchangeResult).Debug("Exchange was processed")
+	return ctx.JSON(http.StatusOK, exchangeResult)
+}
@@ -1,13 +1,16 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -15,18 +18,20 @@ import (
 	"avito-tech-task/config"
 	"avito-tech-task/internal/app/balance/mock"
 	"avito-tech-task/internal/app/models"
-	"avito-tech-task/internal/pkg/constants"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
 )
 
+// requestID is the correlation ID of internal errors in responses of tests
+const requestID = "test-request-id"
+
 func TestHandlers_GetBalance(t *testing.T) {
 	const removeLogs = true // set false to deny deleting logs after test
 
 	config := &config.Config{
 		LoggingLevel:    "debug",
 		LoggingFilePath: "./logs/",
-		CurrencyAPIURL:  "",
 		Server:          config.ServerConfig{},
 	}
 
@@ -50,64 +55,100 @@ func TestHandlers_GetBalance(t *testing.T) {
 		name           string
 		serviceMock    *mock.MockService
 		userIDParam    string
+		query          string
 		expectedStatus int
 		expected       interface{}
 	}{
 		{
 			name: "Successfully get user balance",
 			serviceMock: &mock.MockService{
-				GetBalanceFunc: func(n int64, s string) (*models.UserData, error) {
-					return &models.UserData{
-						UserID:  1,
-						Balance: 1000,
+				GetBalanceFunc: func(ctx context.Context, n int64, wallet string, currency string, maxRateAge time.Duration) (*models.Balance, error) {
+					return &models.Balance{
+						UserID:   1,
+						Wallet:   wallet,
+						Balance:  money.Amount{Minor: 1310, Digits: 2},
+						Reserved: money.Amount{Digits: 2},
+						Currency: currency,
+						Rate:     0.0131,
+						RateDate: "2022-01-19",
 					}, nil
 				},
 			},
 			userIDParam:    "1",
+			query:          "?wallet=RUB&currency=USD",
 			expectedStatus: http.StatusOK,
-			expected: &models.UserData{
-				UserID:  1,
-				Balance: 1000,
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "RUB",
+				Balance:  money.Amount{Minor: 1310, Digits: 2},
+				Reserved: money.Amount{Digits: 2},
+				Currency: "USD",
+				Rate:     0.0131,
+				RateDate: "2022-01-19",
 			},
 		},
 		{
 			name:           "Invalid user id in param",
 			userIDParam:    "string???",
 			expectedStatus: http.StatusBadRequest,
-			expected:       &models.ResponseMessage{Message: constants.InvalidUserIDMessage},
+			expected:       utils.NewProblem(createdErrors.ErrInvalidUserID, ""),
 		},
 		{
 			name: "Not supported currency",
 			serviceMock: &mock.MockService{
-				GetBalanceFunc: func(n int64, s string) (*models.UserData, error) {
-					return nil, createdErrors.ErrNotSupportedCurrency
+				GetBalanceFunc: func(ctx context.Context, n int64, wallet string, currency string, maxRateAge time.Duration) (*models.Balance, error) {
+					return nil, &createdErrors.NotSupportedCurrencyError{Currency: "GBP", Supported: []string{"RUB", "USD"}}
 				},
 			},
 			userIDParam:    "1",
+			query:          "?currency=GBP",
 			expectedStatus: http.StatusUnprocessableEntity,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrNotSupportedCurrency.Error()},
+			expected: utils.NewProblem(
+				&createdErrors.NotSupportedCurrencyError{Currency: "GBP", Supported: []string{"RUB", "USD"}}, ""),
+		},
+		{
+			name: "Rates are older than the max rate age",
+			serviceMock: &mock.MockService{
+				GetBalanceFunc: func(ctx context.Context, n int64, wallet string, currency string, maxRateAge time.Duration) (*models.Balance, error) {
+					if maxRateAge != 90*time.Minute {
+						return nil, internalServerErr
+					}
+					return nil, createdErrors.ErrStaleRates
+				},
+			},
+			userIDParam:    "1",
+			query:          "?currency=USD&max_rate_age=1h30m",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrStaleRates, ""),
+		},
+		{
+			name:           "Invalid max rate age",
+			userIDParam:    "1",
+			query:          "?currency=USD&max_rate_age=hour",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `time: invalid duration "hour"`), ""),
 		},
 		{
 			name: "User does not exist",
 			serviceMock: &mock.MockService{
-				GetBalanceFunc: func(n int64, s string) (*models.UserData, error) {
+				GetBalanceFunc: func(ctx context.Context, n int64, wallet string, currency string, maxRateAge time.Duration) (*models.Balance, error) {
 					return nil, createdErrors.ErrUserDoesNotExist
 				},
 			},
 			userIDParam:    "1",
 			expectedStatus: http.StatusNotFound,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrUserDoesNotExist.Error()},
+			expected:       utils.NewProblem(createdErrors.ErrUserDoesNotExist, ""),
 		},
 		{
 			name: "Internal server error",
 			serviceMock: &mock.MockService{
-				GetBalanceFunc: func(n int64, s string) (*models.UserData, error) {
+				GetBalanceFunc: func(ctx context.Context, n int64, wallet string, currency string, maxRateAge time.Duration) (*models.Balance, error) {
 					return nil, internalServerErr
 				},
 			},
 			userIDParam:    "1",
 			expectedStatus: http.StatusInternalServerError,
-			expected:       &models.ResponseMessage{Message: internalServerErr.Error()},
+			expected:       utils.NewProblem(internalServerErr, requestID),
 		},
 	}
 
@@ -115,15 +156,17 @@ func TestHandlers_GetBalance(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			server := echo.New()
-			req := httptest.NewRequest(echo.GET, "/", nil)
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+			req := httptest.NewRequest(echo.GET, "/"+test.query, nil)
 			rec := httptest.NewRecorder()
 			ctx := server.NewContext(req, rec)
 			ctx.SetPath("/api/v1/balance/:user_id")
 			ctx.SetParamNames("user_id")
 			ctx.SetParamValues(test.userIDParam)
 
-			handlers := NewHandlers(test.serviceMock, logger)
-			if assert.NoError(t, handlers.GetBalance(ctx)) {
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.GetBalance)(ctx)) {
 				assert.Equal(t, test.expectedStatus, rec.Code)
 
 				expectedString, _ := json.Marshal(test.expected)
@@ -139,7 +182,6 @@ func TestHandlers_UpdateBalance(t *testing.T) {
 	config := &config.Config{
 		LoggingLevel:    "debug",
 		LoggingFilePath: "./logs/",
-		CurrencyAPIURL:  "",
 		Server:          config.ServerConfig{},
 	}
 
@@ -170,7 +212,7 @@ func TestHandlers_UpdateBalance(t *testing.T) {
 		{
 			name: "Successfully updated user balance",
 			serviceMock: &mock.MockService{
-				UpdateBalanceFunc: func(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+				UpdateBalanceFunc: func(ctx context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
 					return &models.UserData{
 						UserID:  1,
 						Balance: 1000,
@@ -186,35 +228,54 @@ func TestHandlers_UpdateBalance(t *testing.T) {
 			},
 		},
 		{
-			name:           "Invalid body",
+			name:           "Malformed amount",
 			userIDParam:    "1",
 			body:           `{"operation_type": 0, "amount": "hello"}`,
 			expectedStatus: http.StatusBadRequest,
-			expected:       &models.ResponseMessage{Message: constants.InvalidBodyMessage},
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAmount, ""),
+		},
+		{
+			name:           "Invalid body",
+			userIDParam:    "1",
+			body:           `{"operation_type": "add", "amount": 1000}`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
 		},
 		{
 			name: "Not enough money | Not supported operation type | Amount field was not set | Negative user ID",
 			serviceMock: &mock.MockService{
-				UpdateBalanceFunc: func(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+				UpdateBalanceFunc: func(ctx context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
 					return nil, createdErrors.ErrNotEnoughMoney
 				},
 			},
 			userIDParam:    "1",
 			body:           `{"operation_type": 1, "amount": 1000}`,
 			expectedStatus: http.StatusUnprocessableEntity,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrNotEnoughMoney.Error()},
+			expected:       utils.NewProblem(createdErrors.ErrNotEnoughMoney, ""),
+		},
+		{
+			name: "Write off from user that does not exist",
+			serviceMock: &mock.MockService{
+				UpdateBalanceFunc: func(ctx context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+					return nil, createdErrors.ErrUserDoesNotExist
+				},
+			},
+			userIDParam:    "1",
+			body:           `{"operation_type": 1, "amount": 1000}`,
+			expectedStatus: http.StatusNotFound,
+			expected:       utils.NewProblem(createdErrors.ErrUserDoesNotExist, ""),
 		},
 		{
 			name: "Internal server error",
 			serviceMock: &mock.MockService{
-				UpdateBalanceFunc: func(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+				UpdateBalanceFunc: func(ctx context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
 					return nil, internalServerErr
 				},
 			},
 			userIDParam:    "1",
 			body:           `{"operation_type": 1, "amount": 1000}`,
 			expectedStatus: http.StatusInternalServerError,
-			expected:       &models.ResponseMessage{Message: internalServerErr.Error()},
+			expected:       utils.NewProblem(internalServerErr, requestID),
 		},
 	}
 
@@ -222,6 +283,7 @@ func TestHandlers_UpdateBalance(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
 
 			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(test.body))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -231,8 +293,9 @@ func TestHandlers_UpdateBalance(t *testing.T) {
 			ctx.SetParamNames("user_id")
 			ctx.SetParamValues(test.userIDParam)
 
-			handlers := NewHandlers(test.serviceMock, logger)
-			if assert.NoError(t, handlers.UpdateBalance(ctx)) {
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.UpdateBalance)(ctx)) {
 				assert.Equal(t, test.expectedStatus, rec.Code)
 
 				expectedString, _ := json.Marshal(test.expected)
@@ -248,7 +311,6 @@ func TestHandlers_Transfer(t *testing.T) {
 	config := &config.Config{
 		LoggingLevel:    "debug",
 		LoggingFilePath: "./logs/",
-		CurrencyAPIURL:  "",
 		Server:          config.ServerConfig{},
 	}
 
@@ -278,7 +340,7 @@ func TestHandlers_Transfer(t *testing.T) {
 		{
 			name: "Successfully transferred money",
 			serviceMock: &mock.MockService{
-				MakeTransferFunc: func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
 					return &models.TransferUsersData{
 						Sender: &models.UserData{
 							UserID:  1,
@@ -307,41 +369,58 @@ func TestHandlers_Transfer(t *testing.T) {
 		{
 			name: "Not enough money to make transfer",
 			serviceMock: &mock.MockService{
-				MakeTransferFunc: func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
 					return nil, createdErrors.ErrNotEnoughMoney
 				},
 			},
 			body:           `{"sender_id": 1, "receiver_id": 2, "amount": 1000}`,
 			expectedStatus: http.StatusUnprocessableEntity,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrNotEnoughMoney.Error()},
+			expected:       utils.NewProblem(createdErrors.ErrNotEnoughMoney, ""),
+		},
+		{
+			name: "Unsupported currency | amount is too small to convert",
+			serviceMock: &mock.MockService{
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+					return nil, createdErrors.ErrAmountTooSmallToConvert
+				},
+			},
+			body:           `{"sender_id": 1, "receiver_id": 2, "amount": 0.01, "receiver_currency": "USD"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrAmountTooSmallToConvert, ""),
 		},
 		{
 			name: "Sender not found | Receiver not found",
 			serviceMock: &mock.MockService{
-				MakeTransferFunc: func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
 					return nil, createdErrors.ErrSenderDoesNotExist
 				},
 			},
 			body:           `{"sender_id": 1, "receiver_id": 2, "amount": 1000}`,
 			expectedStatus: http.StatusNotFound,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrSenderDoesNotExist.Error()},
+			expected:       utils.NewProblem(createdErrors.ErrSenderDoesNotExist, ""),
 		},
 		{
-			name:           "Invalid body",
+			name:           "Malformed amount",
 			body:           `{"sender_id": 1, "receiver_id": 2, "amount": "hello"}`,
 			expectedStatus: http.StatusBadRequest,
-			expected:       &models.ResponseMessage{Message: constants.InvalidBodyMessage},
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAmount, ""),
+		},
+		{
+			name:           "Invalid body",
+			body:           `{"sender_id": "first", "receiver_id": 2, "amount": 1000}`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
 		},
 		{
 			name: "Internal server error",
 			serviceMock: &mock.MockService{
-				MakeTransferFunc: func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
 					return nil, internalServerErr
 				},
 			},
 			body:           `{"sender_id": 1, "receiver_id": 2, "amount": 1000}`,
 			expectedStatus: http.StatusInternalServerError,
-			expected:       &models.ResponseMessage{Message: internalServerErr.Error()},
+			expected:       utils.NewProblem(internalServerErr, requestID),
 		},
 	}
 
@@ -349,6 +428,7 @@ func TestHandlers_Transfer(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
 
 			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(test.body))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -356,8 +436,135 @@ func TestHandlers_Transfer(t *testing.T) {
 			ctx := server.NewContext(req, rec)
 			ctx.SetPath("/api/v1/transfer")
 
-			handlers := NewHandlers(test.serviceMock, logger)
-			if assert.NoError(t, handlers.Transfer(ctx)) {
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.Transfer)(ctx)) {
+				assert.Equal(t, test.expectedStatus, rec.Code)
+
+				expectedString, _ := json.Marshal(test.expected)
+				assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_Exchange(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	internalServerErr := errors.New("Internal server error")
+	tests := []struct {
+		name           string
+		serviceMock    *mock.MockService
+		body           string
+		expectedStatus int
+		expected       interface{}
+	}{
+		{
+			name: "Successfully exchanged money",
+			serviceMock: &mock.MockService{
+				ExchangeFunc: func(ctx context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+					return &models.ExchangeResult{
+						Amount:   1000,
+						Received: 450,
+						Rate:     0.45,
+						From:     &models.UserData{UserID: 1, Balance: 0, Currency: "RUB"},
+						To:       &models.UserData{UserID: 1, Balance: 450, Currency: "USD"},
+					}, nil
+				},
+			},
+			body:           `{"user_id": 1, "from": "RUB", "to": "USD", "amount": 10}`,
+			expectedStatus: http.StatusOK,
+			expected: &models.ExchangeResult{
+				Amount:   1000,
+				Received: 450,
+				Rate:     0.45,
+				From:     &models.UserData{UserID: 1, Balance: 0, Currency: "RUB"},
+				To:       &models.UserData{UserID: 1, Balance: 450, Currency: "USD"},
+			},
+		},
+		{
+			name: "Quote not found | Quote has expired",
+			serviceMock: &mock.MockService{
+				ExchangeFunc: func(ctx context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+					return nil, createdErrors.ErrQuoteDoesNotExist
+				},
+			},
+			body:           `{"user_id": 1, "from": "RUB", "to": "USD", "amount": 10, "quote_id": "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"}`,
+			expectedStatus: http.StatusNotFound,
+			expected:       utils.NewProblem(createdErrors.ErrQuoteDoesNotExist, ""),
+		},
+		{
+			name: "Quote for other currencies",
+			serviceMock: &mock.MockService{
+				ExchangeFunc: func(ctx context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+					return nil, createdErrors.ErrQuoteMismatch
+				},
+			},
+			body:           `{"user_id": 1, "from": "RUB", "to": "EUR", "amount": 10, "quote_id": "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrQuoteMismatch, ""),
+		},
+		{
+			name:           "Malformed amount",
+			body:           `{"user_id": 1, "from": "RUB", "to": "USD", "amount": "hello"}`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAmount, ""),
+		},
+		{
+			name:           "Invalid body",
+			body:           `{"user_id": 1, "from": "RUB", "to": "USD", "amount": 10`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
+		},
+		{
+			name: "Internal server error",
+			serviceMock: &mock.MockService{
+				ExchangeFunc: func(ctx context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+					return nil, internalServerErr
+				},
+			},
+			body:           `{"user_id": 1, "from": "RUB", "to": "USD", "amount": 10}`,
+			expectedStatus: http.StatusInternalServerError,
+			expected:       utils.NewProblem(internalServerErr, requestID),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := server.NewContext(req, rec)
+			ctx.SetPath("/api/v1/exchange")
+
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.Exchange)(ctx)) {
 				assert.Equal(t, test.expectedStatus, rec.Code)
 
 				expectedString, _ := json.Marshal(test.expected)
@@ -6,6 +6,8 @@ package mock
 import (
 	"avito-tech-task/internal/app/balance"
 	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/money"
+	"context"
 	"sync"
 )
 
@@ -15,180 +17,241 @@ var _ balance.Storage = &MockStorage{}
 
 // MockStorage is a mock implementation of balance.Storage.
 //
-// 	func TestSomethingThatUsesStorage(t *testing.T) {
+//	func TestSomethingThatUsesStorage(t *testing.T) {
 //
-// 		// make and configure a mocked balance.Storage
-// 		mockedStorage := &MockStorage{
-// 			CreateAccountFunc: func(n int64) error {
-// 				panic("mock out the CreateAccount method")
-// 			},
-// 			GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-// 				panic("mock out the GetTransferUsersData method")
-// 			},
-// 			GetUserDataFunc: func(n int64) (*models.UserData, error) {
-// 				panic("mock out the GetUserData method")
-// 			},
-// 			MakeTransferFunc: func(n1 int64, n2 int64, f float64) error {
-// 				panic("mock out the MakeTransfer method")
-// 			},
-// 			UpdateBalanceFunc: func(n int64, f float64) (float64, error) {
-// 				panic("mock out the UpdateBalance method")
-// 			},
-// 		}
+//		// make and configure a mocked balance.Storage
+//		mockedStorage := &MockStorage{
+//			ExchangeFunc: func(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest, moneyMoqParam money.Money, f float64) (*models.ExchangeResult, error) {
+//				panic("mock out the Exchange method")
+//			},
+//			GetQuoteFunc: func(contextMoqParam context.Context, s string, n int64) (*models.ExchangeQuote, error) {
+//				panic("mock out the GetQuote method")
+//			},
+//			GetUserDataFunc: func(contextMoqParam context.Context, n int64, s string) (*models.UserData, error) {
+//				panic("mock out the GetUserData method")
+//			},
+//			MakeTransferFunc: func(contextMoqParam context.Context, transferRequest *models.TransferRequest, moneyMoqParam money.Money, f float64) (*models.TransferUsersData, error) {
+//				panic("mock out the MakeTransfer method")
+//			},
+//			SaveQuoteFunc: func(contextMoqParam context.Context, exchangeQuote *models.ExchangeQuote) (*models.ExchangeQuote, error) {
+//				panic("mock out the SaveQuote method")
+//			},
+//			UpdateBalanceFunc: func(contextMoqParam context.Context, n int64, s string, moneyMoqParam money.Money, purpose *models.Purpose) (money.Money, error) {
+//				panic("mock out the UpdateBalance method")
+//			},
+//		}
 //
-// 		// use mockedStorage in code that requires balance.Storage
-// 		// and then make assertions.
+//		// use mockedStorage in code that requires balance.Storage
+//		// and then make assertions.
 //
-// 	}
+//	}
 type MockStorage struct {
-	// CreateAccountFunc mocks the CreateAccount method.
-	CreateAccountFunc func(n int64) error
+	// ExchangeFunc mocks the Exchange method.
+	ExchangeFunc func(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest, moneyMoqParam money.Money, f float64) (*models.ExchangeResult, error)
 
-	// GetTransferUsersDataFunc mocks the GetTransferUsersData method.
-	GetTransferUsersDataFunc func(n1 int64, n2 int64) (*models.TransferUsersData, error)
+	// GetQuoteFunc mocks the GetQuote method.
+	GetQuoteFunc func(contextMoqParam context.Context, s string, n int64) (*models.ExchangeQuote, error)
 
 	// GetUserDataFunc mocks the GetUserData method.
-	GetUserDataFunc func(n int64) (*models.UserData, error)
+	GetUserDataFunc func(contextMoqParam context.Context, n int64, s string) (*models.UserData, error)
 
 	// MakeTransferFunc mocks the MakeTransfer method.
-	MakeTransferFunc func(n1 int64, n2 int64, f float64) error
+	MakeTransferFunc func(contextMoqParam context.Context, transferRequest *models.TransferRequest, moneyMoqParam money.Money, f float64) (*models.TransferUsersData, error)
+
+	// SaveQuoteFunc mocks the SaveQuote method.
+	SaveQuoteFunc func(contextMoqParam context.Context, exchangeQuote *models.ExchangeQuote) (*models.ExchangeQuote, error)
 
 	// UpdateBalanceFunc mocks the UpdateBalance method.
-	UpdateBalanceFunc func(n int64, f float64) (float64, error)
+	UpdateBalanceFunc func(contextMoqParam context.Context, n int64, s string, moneyMoqParam money.Money, purpose *models.Purpose) (money.Money, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// CreateAccount holds details about calls to the CreateAccount method.
-		CreateAccount []struct {
+		// Exchange holds details about calls to the Exchange method.
+		Exchange []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ExchangeRequest is the exchangeRequest argument value.
+			ExchangeRequest *models.ExchangeRequest
+			// MoneyMoqParam is the moneyMoqParam argument value.
+			MoneyMoqParam money.Money
+			// F is the f argument value.
+			F float64
+		}
+		// GetQuote holds details about calls to the GetQuote method.
+		GetQuote []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// S is the s argument value.
+			S string
 			// N is the n argument value.
 			N int64
 		}
-		// GetTransferUsersData holds details about calls to the GetTransferUsersData method.
-		GetTransferUsersData []struct {
-			// N1 is the n1 argument value.
-			N1 int64
-			// N2 is the n2 argument value.
-			N2 int64
-		}
 		// GetUserData holds details about calls to the GetUserData method.
 		GetUserData []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
+			// S is the s argument value.
+			S string
 		}
 		// MakeTransfer holds details about calls to the MakeTransfer method.
 		MakeTransfer []struct {
-			// N1 is the n1 argument value.
-			N1 int64
-			// N2 is the n2 argument value.
-			N2 int64
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// TransferRequest is the transferRequest argument value.
+			TransferRequest *models.TransferRequest
+			// MoneyMoqParam is the moneyMoqParam argument value.
+			MoneyMoqParam money.Money
 			// F is the f argument value.
 			F float64
 		}
+		// SaveQuote holds details about calls to the SaveQuote method.
+		SaveQuote []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ExchangeQuote is the exchangeQuote argument value.
+			ExchangeQuote *models.ExchangeQuote
+		}
 		// UpdateBalance holds details about calls to the UpdateBalance method.
 		UpdateBalance []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
-			// F is the f argument value.
-			F float64
+			// S is the s argument value.
+			S string
+			// MoneyMoqParam is the moneyMoqParam argument value.
+			MoneyMoqParam money.Money
+			// Purpose is the purpose argument value.
+			Purpose *models.Purpose
 		}
 	}
-	lockCreateAccount        sync.RWMutex
-	lockGetTransferUsersData sync.RWMutex
-	lockGetUserData          sync.RWMutex
-	lockMakeTransfer         sync.RWMutex
-	lockUpdateBalance        sync.RWMutex
+	lockExchange      sync.RWMutex
+	lockGetQuote      sync.RWMutex
+	lockGetUserData   sync.RWMutex
+	lockMakeTransfer  sync.RWMutex
+	lockSaveQuote     sync.RWMutex
+	lockUpdateBalance sync.RWMutex
 }
 
-// CreateAccount calls CreateAccountFunc.
-func (mock *MockStorage) CreateAccount(n int64) error {
-	if mock.CreateAccountFunc == nil {
-		panic("MockStorage.CreateAccountFunc: method is nil but Storage.CreateAccount was just called")
+// Exchange calls ExchangeFunc.
+func (mock *MockStorage) Exchange(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest, moneyMoqParam money.Money, f float64) (*models.ExchangeResult, error) {
+	if mock.ExchangeFunc == nil {
+		panic("MockStorage.ExchangeFunc: method is nil but Storage.Exchange was just called")
 	}
 	callInfo := struct {
-		N int64
+		ContextMoqParam context.Context
+		ExchangeRequest *models.ExchangeRequest
+		MoneyMoqParam   money.Money
+		F               float64
 	}{
-		N: n,
+		ContextMoqParam: contextMoqParam,
+		ExchangeRequest: exchangeRequest,
+		MoneyMoqParam:   moneyMoqParam,
+		F:               f,
 	}
-	mock.lockCreateAccount.Lock()
-	mock.calls.CreateAccount = append(mock.calls.CreateAccount, callInfo)
-	mock.lockCreateAccount.Unlock()
-	return mock.CreateAccountFunc(n)
+	mock.lockExchange.Lock()
+	mock.calls.Exchange = append(mock.calls.Exchange, callInfo)
+	mock.lockExchange.Unlock()
+	return mock.ExchangeFunc(contextMoqParam, exchangeRequest, moneyMoqParam, f)
 }
 
-// CreateAccountCalls gets all the calls that were made to CreateAccount.
+// ExchangeCalls gets all the calls that were made to Exchange.
 // Check the length with:
-//     len(mockedStorage.CreateAccountCalls())
-func (mock *MockStorage) CreateAccountCalls() []struct {
-	N int64
+//
+//	len(mockedStorage.ExchangeCalls())
+func (mock *MockStorage) ExchangeCalls() []struct {
+	ContextMoqParam context.Context
+	ExchangeRequest *models.ExchangeRequest
+	MoneyMoqParam   money.Money
+	F               float64
 } {
 	var calls []struct {
-		N int64
+		ContextMoqParam context.Context
+		ExchangeRequest *models.ExchangeRequest
+		MoneyMoqParam   money.Money
+		F               float64
 	}
-	mock.lockCreateAccount.RLock()
-	calls = mock.calls.CreateAccount
-	mock.lockCreateAccount.RUnlock()
+	mock.lockExchange.RLock()
+	calls = mock.calls.Exchange
+	mock.lockExchange.RUnlock()
 	return calls
 }
 
-// GetTransferUsersData calls GetTransferUsersDataFunc.
-func (mock *MockStorage) GetTransferUsersData(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-	if mock.GetTransferUsersDataFunc == nil {
-		panic("MockStorage.GetTransferUsersDataFunc: method is nil but Storage.GetTransferUsersData was just called")
+// GetQuote calls GetQuoteFunc.
+func (mock *MockStorage) GetQuote(contextMoqParam context.Context, s string, n int64) (*models.ExchangeQuote, error) {
+	if mock.GetQuoteFunc == nil {
+		panic("MockStorage.GetQuoteFunc: method is nil but Storage.GetQuote was just called")
 	}
 	callInfo := struct {
-		N1 int64
-		N2 int64
+		ContextMoqParam context.Context
+		S               string
+		N               int64
 	}{
-		N1: n1,
-		N2: n2,
+		ContextMoqParam: contextMoqParam,
+		S:               s,
+		N:               n,
 	}
-	mock.lockGetTransferUsersData.Lock()
-	mock.calls.GetTransferUsersData = append(mock.calls.GetTransferUsersData, callInfo)
-	mock.lockGetTransferUsersData.Unlock()
-	return mock.GetTransferUsersDataFunc(n1, n2)
+	mock.lockGetQuote.Lock()
+	mock.calls.GetQuote = append(mock.calls.GetQuote, callInfo)
+	mock.lockGetQuote.Unlock()
+	return mock.GetQuoteFunc(contextMoqParam, s, n)
 }
 
-// GetTransferUsersDataCalls gets all the calls that were made to GetTransferUsersData.
+// GetQuoteCalls gets all the calls that were made to GetQuote.
 // Check the length with:
-//     len(mockedStorage.GetTransferUsersDataCalls())
-func (mock *MockStorage) GetTransferUsersDataCalls() []struct {
-	N1 int64
-	N2 int64
+//
+//	len(mockedStorage.GetQuoteCalls())
+func (mock *MockStorage) GetQuoteCalls() []struct {
+	ContextMoqParam context.Context
+	S               string
+	N               int64
 } {
 	var calls []struct {
-		N1 int64
-		N2 int64
+		ContextMoqParam context.Context
+		S               string
+		N               int64
 	}
-	mock.lockGetTransferUsersData.RLock()
-	calls = mock.calls.GetTransferUsersData
-	mock.lockGetTransferUsersData.RUnlock()
+	mock.lockGetQuote.RLock()
+	calls = mock.calls.GetQuote
+	mock.lockGetQuote.RUnlock()
 	return calls
 }
 
 // GetUserData calls GetUserDataFunc.
-func (mock *MockStorage) GetUserData(n int64) (*models.UserData, error) {
+func (mock *MockStorage) GetUserData(contextMoqParam context.Context, n int64, s string) (*models.UserData, error) {
 	if mock.GetUserDataFunc == nil {
 		panic("MockStorage.GetUserDataFunc: method is nil but Storage.GetUserData was just called")
 	}
 	callInfo := struct {
-		N int64
+		ContextMoqParam context.Context
+		N               int64
+		S               string
 	}{
-		N: n,
+		ContextMoqParam: contextMoqParam,
+		N:               n,
+		S:               s,
 	}
 	mock.lockGetUserData.Lock()
 	mock.calls.GetUserData = append(mock.calls.GetUserData, callInfo)
 	mock.lockGetUserData.Unlock()
-	return mock.GetUserDataFunc(n)
+	return mock.GetUserDataFunc(contextMoqParam, n, s)
 }
 
 // GetUserDataCalls gets all the calls that were made to GetUserData.
 // Check the length with:
-//     len(mockedStorage.GetUserDataCalls())
+//
+//	len(mockedStorage.GetUserDataCalls())
 func (mock *MockStorage) GetUserDataCalls() []struct {
-	N int64
+	ContextMoqParam context.Context
+	N               int64
+	S               string
 } {
 	var calls []struct {
-		N int64
+		ContextMoqParam context.Context
+		N               int64
+		S               string
 	}
 	mock.lockGetUserData.RLock()
 	calls = mock.calls.GetUserData
@@ -197,37 +260,42 @@ func (mock *MockStorage) GetUserDataCalls() []struct {
 }
 
 // MakeTransfer calls MakeTransferFunc.
-func (mock *MockStorage) MakeTransfer(n1 int64, n2 int64, f float64) error {
+func (mock *MockStorage) MakeTransfer(contextMoqParam context.Context, transferRequest *models.TransferRequest, moneyMoqParam money.Money, f float64) (*models.TransferUsersData, error) {
 	if mock.MakeTransferFunc == nil {
 		panic("MockStorage.MakeTransferFunc: method is nil but Storage.MakeTransfer was just called")
 	}
 	callInfo := struct {
-		N1 int64
-		N2 int64
-		F  float64
+		ContextMoqParam context.Context
+		TransferRequest *models.TransferRequest
+		MoneyMoqParam   money.Money
+		F               float64
 	}{
-		N1: n1,
-		N2: n2,
-		F:  f,
+		ContextMoqParam: contextMoqParam,
+		TransferRequest: transferRequest,
+		MoneyMoqParam:   moneyMoqParam,
+		F:               f,
 	}
 	mock.lockMakeTransfer.Lock()
 	mock.calls.MakeTransfer = append(mock.calls.MakeTransfer, callInfo)
 	mock.lockMakeTransfer.Unlock()
-	return mock.MakeTransferFunc(n1, n2, f)
+	return mock.MakeTransferFunc(contextMoqParam, transferRequest, moneyMoqParam, f)
 }
 
 // MakeTransferCalls gets all the calls that were made to MakeTransfer.
 // Check the length with:
-//     len(mockedStorage.MakeTransferCalls())
+//
+//	len(mockedStorage.MakeTransferCalls())
 func (mock *MockStorage) MakeTransferCalls() []struct {
-	N1 int64
-	N2 int64
-	F  float64
+	ContextMoqParam context.Context
+	TransferRequest *models.TransferRequest
+	MoneyMoqParam   money.Money
+	F               float64
 } {
 	var calls []struct {
-		N1 int64
-		N2 int64
-		F  float64
+		ContextMoqParam context.Context
+		TransferRequest *models.TransferRequest
+		MoneyMoqParam   money.Money
+		F               float64
 	}
 	mock.lockMakeTransfer.RLock()
 	calls = mock.calls.MakeTransfer
@@ -235,34 +303,83 @@ func (mock *MockStorage) MakeTransferCalls() []struct {
 	return calls
 }
 
+// SaveQuote calls SaveQuoteFunc.
+func (mock *MockStorage) SaveQuote(contextMoqParam context.Context, exchangeQuote *models.ExchangeQuote) (*models.ExchangeQuote, error) {
+	if mock.SaveQuoteFunc == nil {
+		panic("MockStorage.SaveQuoteFunc: method is nil but Storage.SaveQuote was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		ExchangeQuote   *models.ExchangeQuote
+	}{
+		ContextMoqParam: contextMoqParam,
+		ExchangeQuote:   exchangeQuote,
+	}
+	mock.lockSaveQuote.Lock()
+	mock.calls.SaveQuote = append(mock.calls.SaveQuote, callInfo)
+	mock.lockSaveQuote.Unlock()
+	return mock.SaveQuoteFunc(contextMoqParam, exchangeQuote)
+}
+
+// SaveQuoteCalls gets all the calls that were made to SaveQuote.
+// Check the length with:
+//
+//	len(mockedStorage.SaveQuoteCalls())
+func (mock *MockStorage) SaveQuoteCalls() []struct {
+	ContextMoqParam context.Context
+	ExchangeQuote   *models.ExchangeQuote
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		ExchangeQuote   *models.ExchangeQuote
+	}
+	mock.lockSaveQuote.RLock()
+	calls = mock.calls.SaveQuote
+	mock.lockSaveQuote.RUnlock()
+	return calls
+}
+
 // UpdateBalance calls UpdateBalanceFunc.
-func (mock *MockStorage) UpdateBalance(n int64, f float64) (float64, error) {
+func (mock *MockStorage) UpdateBalance(contextMoqParam context.Context, n int64, s string, moneyMoqParam money.Money, purpose *models.Purpose) (money.Money, error) {
 	if mock.UpdateBalanceFunc == nil {
 		panic("MockStorage.UpdateBalanceFunc: method is nil but Storage.UpdateBalance was just called")
 	}
 	callInfo := struct {
-		N int64
-		F float64
+		ContextMoqParam context.Context
+		N               int64
+		S               string
+		MoneyMoqParam   money.Money
+		Purpose         *models.Purpose
 	}{
-		N: n,
-		F: f,
+		ContextMoqParam: contextMoqParam,
+		N:               n,
+		S:               s,
+		MoneyMoqParam:   moneyMoqParam,
+		Purpose:         purpose,
 	}
 	mock.lockUpdateBalance.Lock()
 	mock.calls.UpdateBalance = append(mock.calls.UpdateBalance, callInfo)
 	mock.lockUpdateBalance.Unlock()
-	return mock.UpdateBalanceFunc(n, f)
+	return mock.UpdateBalanceFunc(contextMoqParam, n, s, moneyMoqParam, purpose)
 }
 
 // UpdateBalanceCalls gets all the calls that were made to UpdateBalance.
 // Check the length with:
-//     len(mockedStorage.UpdateBalanceCalls())
+//
+//	len(mockedStorage.UpdateBalanceCalls())
 func (mock *MockStorage) UpdateBalanceCalls() []struct {
-	N int64
-	F float64
+	ContextMoqParam context.Context
+	N               int64
+	S               string
+	MoneyMoqParam   money.Money
+	Purpose         *models.Purpose
 } {
 	var calls []struct {
-		N int64
-		F float64
+		ContextMoqParam context.Context
+		N               int64
+		S               string
+		MoneyMoqParam   money.Money
+		Purpose         *models.Purpose
 	}
 	mock.lockUpdateBalance.RLock()
 	calls = mock.calls.UpdateBalance
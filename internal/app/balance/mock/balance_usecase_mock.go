@@ -6,7 +6,9 @@ package mock
 import (
 	"avito-tech-task/internal/app/balance"
 	"avito-tech-task/internal/app/models"
+	"context"
 	"sync"
+	"time"
 )
 
 // Ensure, that MockService does implement balance.Service.
@@ -15,88 +17,211 @@ var _ balance.Service = &MockService{}
 
 // MockService is a mock implementation of balance.Service.
 //
-// 	func TestSomethingThatUsesService(t *testing.T) {
+//	func TestSomethingThatUsesService(t *testing.T) {
 //
-// 		// make and configure a mocked balance.Service
-// 		mockedService := &MockService{
-// 			GetBalanceFunc: func(n int64, s string) (*models.UserData, error) {
-// 				panic("mock out the GetBalance method")
-// 			},
-// 			MakeTransferFunc: func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
-// 				panic("mock out the MakeTransfer method")
-// 			},
-// 			UpdateBalanceFunc: func(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
-// 				panic("mock out the UpdateBalance method")
-// 			},
-// 		}
+//		// make and configure a mocked balance.Service
+//		mockedService := &MockService{
+//			CreateQuoteFunc: func(contextMoqParam context.Context, exchangeQuoteRequest *models.ExchangeQuoteRequest) (*models.ExchangeQuote, error) {
+//				panic("mock out the CreateQuote method")
+//			},
+//			ExchangeFunc: func(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+//				panic("mock out the Exchange method")
+//			},
+//			GetBalanceFunc: func(contextMoqParam context.Context, n int64, s1 string, s2 string, duration time.Duration) (*models.Balance, error) {
+//				panic("mock out the GetBalance method")
+//			},
+//			MakeTransferFunc: func(contextMoqParam context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+//				panic("mock out the MakeTransfer method")
+//			},
+//			UpdateBalanceFunc: func(contextMoqParam context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+//				panic("mock out the UpdateBalance method")
+//			},
+//		}
 //
-// 		// use mockedService in code that requires balance.Service
-// 		// and then make assertions.
+//		// use mockedService in code that requires balance.Service
+//		// and then make assertions.
 //
-// 	}
+//	}
 type MockService struct {
+	// CreateQuoteFunc mocks the CreateQuote method.
+	CreateQuoteFunc func(contextMoqParam context.Context, exchangeQuoteRequest *models.ExchangeQuoteRequest) (*models.ExchangeQuote, error)
+
+	// ExchangeFunc mocks the Exchange method.
+	ExchangeFunc func(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error)
+
 	// GetBalanceFunc mocks the GetBalance method.
-	GetBalanceFunc func(n int64, s string) (*models.UserData, error)
+	GetBalanceFunc func(contextMoqParam context.Context, n int64, s1 string, s2 string, duration time.Duration) (*models.Balance, error)
 
 	// MakeTransferFunc mocks the MakeTransfer method.
-	MakeTransferFunc func(transferRequest *models.TransferRequest) (*models.TransferUsersData, error)
+	MakeTransferFunc func(contextMoqParam context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error)
 
 	// UpdateBalanceFunc mocks the UpdateBalance method.
-	UpdateBalanceFunc func(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error)
+	UpdateBalanceFunc func(contextMoqParam context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// CreateQuote holds details about calls to the CreateQuote method.
+		CreateQuote []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ExchangeQuoteRequest is the exchangeQuoteRequest argument value.
+			ExchangeQuoteRequest *models.ExchangeQuoteRequest
+		}
+		// Exchange holds details about calls to the Exchange method.
+		Exchange []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ExchangeRequest is the exchangeRequest argument value.
+			ExchangeRequest *models.ExchangeRequest
+		}
 		// GetBalance holds details about calls to the GetBalance method.
 		GetBalance []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
-			// S is the s argument value.
-			S string
+			// S1 is the s1 argument value.
+			S1 string
+			// S2 is the s2 argument value.
+			S2 string
+			// Duration is the duration argument value.
+			Duration time.Duration
 		}
 		// MakeTransfer holds details about calls to the MakeTransfer method.
 		MakeTransfer []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// TransferRequest is the transferRequest argument value.
 			TransferRequest *models.TransferRequest
 		}
 		// UpdateBalance holds details about calls to the UpdateBalance method.
 		UpdateBalance []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// RequestUpdateBalance is the requestUpdateBalance argument value.
 			RequestUpdateBalance *models.RequestUpdateBalance
 		}
 	}
+	lockCreateQuote   sync.RWMutex
+	lockExchange      sync.RWMutex
 	lockGetBalance    sync.RWMutex
 	lockMakeTransfer  sync.RWMutex
 	lockUpdateBalance sync.RWMutex
 }
 
+// CreateQuote calls CreateQuoteFunc.
+func (mock *MockService) CreateQuote(contextMoqParam context.Context, exchangeQuoteRequest *models.ExchangeQuoteRequest) (*models.ExchangeQuote, error) {
+	if mock.CreateQuoteFunc == nil {
+		panic("MockService.CreateQuoteFunc: method is nil but Service.CreateQuote was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam      context.Context
+		ExchangeQuoteRequest *models.ExchangeQuoteRequest
+	}{
+		ContextMoqParam:      contextMoqParam,
+		ExchangeQuoteRequest: exchangeQuoteRequest,
+	}
+	mock.lockCreateQuote.Lock()
+	mock.calls.CreateQuote = append(mock.calls.CreateQuote, callInfo)
+	mock.lockCreateQuote.Unlock()
+	return mock.CreateQuoteFunc(contextMoqParam, exchangeQuoteRequest)
+}
+
+// CreateQuoteCalls gets all the calls that were made to CreateQuote.
+// Check the length with:
+//
+//	len(mockedService.CreateQuoteCalls())
+func (mock *MockService) CreateQuoteCalls() []struct {
+	ContextMoqParam      context.Context
+	ExchangeQuoteRequest *models.ExchangeQuoteRequest
+} {
+	var calls []struct {
+		ContextMoqParam      context.Context
+		ExchangeQuoteRequest *models.ExchangeQuoteRequest
+	}
+	mock.lockCreateQuote.RLock()
+	calls = mock.calls.CreateQuote
+	mock.lockCreateQuote.RUnlock()
+	return calls
+}
+
+// Exchange calls ExchangeFunc.
+func (mock *MockService) Exchange(contextMoqParam context.Context, exchangeRequest *models.ExchangeRequest) (*models.ExchangeResult, error) {
+	if mock.ExchangeFunc == nil {
+		panic("MockService.ExchangeFunc: method is nil but Service.Exchange was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		ExchangeRequest *models.ExchangeRequest
+	}{
+		ContextMoqParam: contextMoqParam,
+		ExchangeRequest: exchangeRequest,
+	}
+	mock.lockExchange.Lock()
+	mock.calls.Exchange = append(mock.calls.Exchange, callInfo)
+	mock.lockExchange.Unlock()
+	return mock.ExchangeFunc(contextMoqParam, exchangeRequest)
+}
+
+// ExchangeCalls gets all the calls that were made to Exchange.
+// Check the length with:
+//
+//	len(mockedService.ExchangeCalls())
+func (mock *MockService) ExchangeCalls() []struct {
+	ContextMoqParam context.Context
+	ExchangeRequest *models.ExchangeRequest
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		ExchangeRequest *models.ExchangeRequest
+	}
+	mock.lockExchange.RLock()
+	calls = mock.calls.Exchange
+	mock.lockExchange.RUnlock()
+	return calls
+}
+
 // GetBalance calls GetBalanceFunc.
-func (mock *MockService) GetBalance(n int64, s string) (*models.UserData, error) {
+func (mock *MockService) GetBalance(contextMoqParam context.Context, n int64, s1 string, s2 string, duration time.Duration) (*models.Balance, error) {
 	if mock.GetBalanceFunc == nil {
 		panic("MockService.GetBalanceFunc: method is nil but Service.GetBalance was just called")
 	}
 	callInfo := struct {
-		N int64
-		S string
+		ContextMoqParam context.Context
+		N               int64
+		S1              string
+		S2              string
+		Duration        time.Duration
 	}{
-		N: n,
-		S: s,
+		ContextMoqParam: contextMoqParam,
+		N:               n,
+		S1:              s1,
+		S2:              s2,
+		Duration:        duration,
 	}
 	mock.lockGetBalance.Lock()
 	mock.calls.GetBalance = append(mock.calls.GetBalance, callInfo)
 	mock.lockGetBalance.Unlock()
-	return mock.GetBalanceFunc(n, s)
+	return mock.GetBalanceFunc(contextMoqParam, n, s1, s2, duration)
 }
 
 // GetBalanceCalls gets all the calls that were made to GetBalance.
 // Check the length with:
-//     len(mockedService.GetBalanceCalls())
+//
+//	len(mockedService.GetBalanceCalls())
 func (mock *MockService) GetBalanceCalls() []struct {
-	N int64
-	S string
+	ContextMoqParam context.Context
+	N               int64
+	S1              string
+	S2              string
+	Duration        time.Duration
 } {
 	var calls []struct {
-		N int64
-		S string
+		ContextMoqParam context.Context
+		N               int64
+		S1              string
+		S2              string
+		Duration        time.Duration
 	}
 	mock.lockGetBalance.RLock()
 	calls = mock.calls.GetBalance
@@ -105,28 +230,33 @@ func (mock *MockService) GetBalanceCalls() []struct {
 }
 
 // MakeTransfer calls MakeTransferFunc.
-func (mock *MockService) MakeTransfer(transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
+func (mock *MockService) MakeTransfer(contextMoqParam context.Context, transferRequest *models.TransferRequest) (*models.TransferUsersData, error) {
 	if mock.MakeTransferFunc == nil {
 		panic("MockService.MakeTransferFunc: method is nil but Service.MakeTransfer was just called")
 	}
 	callInfo := struct {
+		ContextMoqParam context.Context
 		TransferRequest *models.TransferRequest
 	}{
+		ContextMoqParam: contextMoqParam,
 		TransferRequest: transferRequest,
 	}
 	mock.lockMakeTransfer.Lock()
 	mock.calls.MakeTransfer = append(mock.calls.MakeTransfer, callInfo)
 	mock.lockMakeTransfer.Unlock()
-	return mock.MakeTransferFunc(transferRequest)
+	return mock.MakeTransferFunc(contextMoqParam, transferRequest)
 }
 
 // MakeTransferCalls gets all the calls that were made to MakeTransfer.
 // Check the length with:
-//     len(mockedService.MakeTransferCalls())
+//
+//	len(mockedService.MakeTransferCalls())
 func (mock *MockService) MakeTransferCalls() []struct {
+	ContextMoqParam context.Context
 	TransferRequest *models.TransferRequest
 } {
 	var calls []struct {
+		ContextMoqParam context.Context
 		TransferRequest *models.TransferRequest
 	}
 	mock.lockMakeTransfer.RLock()
@@ -136,28 +266,33 @@ func (mock *MockService) MakeTransferCalls() []struct {
 }
 
 // UpdateBalance calls UpdateBalanceFunc.
-func (mock *MockService) UpdateBalance(requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
+func (mock *MockService) UpdateBalance(contextMoqParam context.Context, requestUpdateBalance *models.RequestUpdateBalance) (*models.UserData, error) {
 	if mock.UpdateBalanceFunc == nil {
 		panic("MockService.UpdateBalanceFunc: method is nil but Service.UpdateBalance was just called")
 	}
 	callInfo := struct {
+		ContextMoqParam      context.Context
 		RequestUpdateBalance *models.RequestUpdateBalance
 	}{
+		ContextMoqParam:      contextMoqParam,
 		RequestUpdateBalance: requestUpdateBalance,
 	}
 	mock.lockUpdateBalance.Lock()
 	mock.calls.UpdateBalance = append(mock.calls.UpdateBalance, callInfo)
 	mock.lockUpdateBalance.Unlock()
-	return mock.UpdateBalanceFunc(requestUpdateBalance)
+	return mock.UpdateBalanceFunc(contextMoqParam, requestUpdateBalance)
 }
 
 // UpdateBalanceCalls gets all the calls that were made to UpdateBalance.
 // Check the length with:
-//     len(mockedService.UpdateBalanceCalls())
+//
+//	len(mockedService.UpdateBalanceCalls())
 func (mock *MockService) UpdateBalanceCalls() []struct {
+	ContextMoqParam      context.Context
 	RequestUpdateBalance *models.RequestUpdateBalance
 } {
 	var calls []struct {
+		ContextMoqParam      context.Context
 		RequestUpdateBalance *models.RequestUpdateBalance
 	}
 	mock.lockUpdateBalance.RLock()
@@ -1,12 +1,18 @@
 package balance
 
-import "avito-tech-task/internal/app/models"
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/money"
+)
 
 //go:generate moq -out ./mock/balance_repo_mock.go -pkg mock . Storage:MockStorage
 type Storage interface {
-	UpdateBalance(int64, float64) (float64, error)
-	GetUserData(int64) (*models.UserData, error)
-	CreateAccount(int64) error
-	MakeTransfer(int64, int64, float64) error
-	GetTransferUsersData(int64, int64) (*models.TransferUsersData, error)
+	UpdateBalance(context.Context, int64, string, money.Money, *models.Purpose) (money.Money, error)
+	GetUserData(context.Context, int64, string) (*models.UserData, error)
+	MakeTransfer(context.Context, *models.TransferRequest, money.Money, float64) (*models.TransferUsersData, error)
+	SaveQuote(context.Context, *models.ExchangeQuote) (*models.ExchangeQuote, error)
+	GetQuote(context.Context, string, int64) (*models.ExchangeQuote, error)
+	Exchange(context.Context, *models.ExchangeRequest, money.Money, float64) (*models.ExchangeResult, error)
 }
@@ -2,156 +2,427 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/jackc/pgx/v4"
 
+	repositoryIdempotency "avito-tech-task/internal/app/idempotency/repository"
 	"avito-tech-task/internal/app/models"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/ledger"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
 )
 
 type Storage struct {
-	db utils.PgxIface
+	db      utils.PgxIface
+	journal ledger.Writer
 }
 
 func NewStorage(conn utils.PgxIface) *Storage {
-	return &Storage{conn}
+	return &Storage{db: conn, journal: ledger.Journal{}}
 }
 
 const (
-	queryUpdateBalance   = `UPDATE balance SET balance = balance + $1 WHERE user_id = $2 RETURNING balance`
-	querySaveTransaction = `
-		INSERT INTO transactions(operation_type, sender, receiver, amount)
-		VALUES ($1, $2, NULLIF($3, 0), $4)`
-	queryGetBalance    = `SELECT balance FROM balance WHERE user_id = $1`
-	queryInsertBalance = `INSERT INTO balance (user_id, balance) VALUES($1, 0)`
-	queryGetUser       = `SELECT user_id, balance FROM balance WHERE user_id = $1`
+	// queryUpdateBalance changes balance only if it stays non-negative, so the check and the write are atomic
+	queryUpdateBalance = `
+		UPDATE balance SET balance = balance + $1
+		WHERE user_id = $2 AND currency = $3 AND balance + $1 >= 0
+		RETURNING balance`
+	// queryGetBalance returns zero balance if the user does not have a wallet in the currency,
+	// reservations are made only from the ruble wallet
+	queryGetBalance = `
+		SELECT COALESCE(b.balance, 0),
+			(SELECT COALESCE(SUM(amount), 0) FROM reservations WHERE user_id = $1 AND status = 'reserved' AND $2 = 'RUB')
+		FROM users u LEFT JOIN balance b ON b.user_id = u.id AND b.currency = $2
+		WHERE u.id = $1`
+	queryInsertUser = `INSERT INTO users (id) VALUES($1) ON CONFLICT (id) DO NOTHING`
+	queryUserExists = `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`
+	// queryInsertWallet creates the wallet only if the user exists
+	queryInsertWallet = `
+		INSERT INTO balance (user_id, currency, balance) SELECT id, $2, 0 FROM users WHERE id = $1
+		ON CONFLICT (user_id, currency) DO NOTHING`
+	// queryLockUsers locks wallets in user_id order, so concurrent transfers between the same users can not deadlock
+	queryLockUsers = `
+		SELECT user_id, currency, balance FROM balance
+		WHERE user_id = $1 AND currency = $2 OR user_id = $3 AND currency = $4
+		ORDER BY user_id, currency FOR UPDATE`
+	// queryInsertQuote saves the quote only if the user exists
+	queryInsertQuote = `
+		INSERT INTO exchange_quotes (user_id, currency, receiver_currency, rate, expires)
+		SELECT id, $2, $3, $4, $5 FROM users WHERE id = $1
+		RETURNING id::text`
+	queryDeleteExpiredQuotes = `DELETE FROM exchange_quotes WHERE user_id = $1 AND expires <= now()`
+	queryGetQuote            = `
+		SELECT currency, receiver_currency, rate, expires FROM exchange_quotes
+		WHERE id = $1 AND user_id = $2 AND expires > now()`
+	// queryUseQuote deletes the quote, so it can be used only once
+	queryUseQuote = `DELETE FROM exchange_quotes WHERE id = $1 AND user_id = $2 AND expires > now() RETURNING id::text`
 )
 
-func (s *Storage) CreateAccount(userID int64) error {
-	transaction, err := s.db.Begin(context.Background())
+func (s *Storage) GetUserData(ctx context.Context, userID int64, currency string) (_ *models.UserData, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	_, err = transaction.Exec(context.Background(), queryInsertBalance, userID)
+	var balance, reserved money.Money
+	if err = transaction.QueryRow(ctx, queryGetBalance, userID, currency).Scan(&balance, &reserved); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, createdErrors.ErrUserDoesNotExist
+	}
 
-	return err
+	return &models.UserData{UserID: userID, Balance: balance, Reserved: reserved}, nil
 }
 
-func (s *Storage) GetUserData(userID int64) (*models.UserData, error) {
-	transaction, err := s.db.Begin(context.Background())
+// MakeTransfer locks the sender and receiver wallets, checks that the sender has enough money and moves it
+// in one transaction. The receiver gets received in data.ReceiverCurrency, its wallet is created if needed.
+// If the currencies differ, the money goes through the exchange account and rate is recorded.
+func (s *Storage) MakeTransfer(ctx context.Context, data *models.TransferRequest, received money.Money,
+	rate float64) (_ *models.TransferUsersData, err error) {
+	transaction, err := s.db.Begin(ctx) // start transactions for safe money transfer
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	var balance float64
-	if err = transaction.QueryRow(context.Background(), queryGetBalance, userID).Scan(&balance); err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
+	// the money is moved at most once for the idempotency key of the request
+	if err = repositoryIdempotency.Apply(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	if _, err = transaction.Exec(ctx, queryInsertWallet, data.ReceiverID, data.ReceiverCurrency); err != nil {
+		return nil, err
+	}
+
+	rows, err := transaction.Query(ctx, queryLockUsers, data.SenderID, data.Currency, data.ReceiverID,
+		data.ReceiverCurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transferUsers := &models.TransferUsersData{}
+	for rows.Next() {
+		user := &models.UserData{}
+		if err = rows.Scan(&user.UserID, &user.Currency, &user.Balance); err != nil {
 			return nil, err
 		}
-		return nil, createdErrors.ErrUserDoesNotExist
+		switch {
+		case user.UserID == data.SenderID && user.Currency == data.Currency:
+			transferUsers.Sender = user
+		case user.UserID == data.ReceiverID && user.Currency == data.ReceiverCurrency:
+			transferUsers.Receiver = user
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	switch {
+	case transferUsers.Sender == nil:
+		err = createdErrors.ErrSenderDoesNotExist
+	case transferUsers.Receiver == nil:
+		err = createdErrors.ErrReceiverDoesNotExist
+	case transferUsers.Sender.Balance < data.Amount:
+		err = createdErrors.ErrNotEnoughMoney
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, data.Amount*-1, data.SenderID,
+		data.Currency).Scan(&transferUsers.Sender.Balance); err != nil {
+		return nil, err
+	}
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, received, data.ReceiverID,
+		data.ReceiverCurrency).Scan(&transferUsers.Receiver.Balance); err != nil {
+		return nil, err
+	}
+	entry := &ledger.Entry{
+		OperationType: "transfer",
+		SenderID:      data.SenderID,
+		ReceiverID:    data.ReceiverID,
+		Amount:        data.Amount,
+		Currency:      data.Currency,
+		Postings: []ledger.Posting{
+			ledger.User(data.SenderID, data.Amount*-1, transferUsers.Sender.Balance),
+			ledger.User(data.ReceiverID, received, transferUsers.Receiver.Balance).In(data.ReceiverCurrency),
+		},
+	}
+	if data.ReceiverCurrency != data.Currency {
+		entry.Conversion = &ledger.Conversion{Currency: data.ReceiverCurrency, Amount: received, Rate: rate}
+		entry.Postings = append(entry.Postings, ledger.System(ledger.Exchange, data.Amount),
+			ledger.System(ledger.Exchange, received*-1).In(data.ReceiverCurrency))
+	}
+	if err = describe(entry, &data.Purpose); err != nil {
+		return nil, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, entry); err != nil {
+		return nil, err
 	}
 
-	return &models.UserData{UserID: userID, Balance: balance}, nil
+	return transferUsers, nil
 }
 
-func (s *Storage) GetTransferUsersData(senderID, receiverID int64) (*models.TransferUsersData, error) {
-	transaction, err := s.db.Begin(context.Background())
+// UpdateBalance adds amount to the user wallet in the currency or writes it off if amount is negative.
+// The user and the wallet are created on the first top up, ErrNotEnoughMoney is returned if balance
+// would become negative.
+func (s *Storage) UpdateBalance(ctx context.Context, userID int64, currency string, amount money.Money,
+	purpose *models.Purpose) (_ money.Money, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	transferUsers := &models.TransferUsersData{}
-	transferUsers.Sender = &models.UserData{}
-	transferUsers.Receiver = &models.UserData{}
+	// the money is moved at most once for the idempotency key of the request
+	if err = repositoryIdempotency.Apply(ctx, transaction); err != nil {
+		return 0, err
+	}
 
-	// getting info about sender
-	if err = transaction.QueryRow(context.Background(), queryGetUser, senderID).Scan(&transferUsers.Sender.UserID,
-		&transferUsers.Sender.Balance); err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return nil, err
+	if amount > 0 {
+		if _, err = transaction.Exec(ctx, queryInsertUser, userID); err != nil {
+			return 0, err
+		}
+		if _, err = transaction.Exec(ctx, queryInsertWallet, userID, currency); err != nil {
+			return 0, err
 		}
-		transferUsers.Sender = nil
-		err = nil
 	}
 
-	// getting info about receiver
-	if err = transaction.QueryRow(context.Background(), queryGetUser, receiverID).Scan(&transferUsers.Receiver.UserID,
-		&transferUsers.Receiver.Balance); err != nil {
-		if !errors.Is(err, pgx.ErrNoRows) {
-			return nil, err
+	var balance money.Money
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, amount, userID, currency).Scan(&balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) { // wallet does not exist or does not have enough money
+			err = s.writeOffError(ctx, transaction, userID)
 		}
-		transferUsers.Receiver = nil
-		err = nil
+		return 0, err
 	}
 
-	return transferUsers, nil
+	entry := &ledger.Entry{OperationType: "add", SenderID: userID, Amount: amount, Currency: currency}
+	systemAccount := ledger.TopUps // money comes from outside of the service
+	if amount < 0 {
+		entry.OperationType, entry.Amount = "write_off", amount*-1
+		systemAccount = ledger.WriteOffs // money goes outside of the service
+	}
+	entry.Postings = []ledger.Posting{ledger.User(userID, amount, balance), ledger.System(systemAccount, amount*-1)}
+
+	if err = describe(entry, purpose); err != nil {
+		return 0, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, entry); err != nil {
+		return 0, err
+	}
+
+	return balance, nil
 }
 
-func (s *Storage) MakeTransfer(senderID, receiverID int64, amount float64) error {
-	transaction, err := s.db.Begin(context.Background()) // start transactions for safe money transfer
+// SaveQuote saves the quote of the user and removes the expired quotes of the user.
+func (s *Storage) SaveQuote(ctx context.Context, quote *models.ExchangeQuote) (_ *models.ExchangeQuote, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	if _, err = transaction.Exec(context.Background(), queryUpdateBalance, amount*-1, senderID); err != nil {
-		return err
+	if _, err = transaction.Exec(ctx, queryDeleteExpiredQuotes, quote.UserID); err != nil {
+		return nil, err
 	}
-	if _, err = transaction.Exec(context.Background(), queryUpdateBalance, amount, receiverID); err != nil {
-		return err
+	if err = transaction.QueryRow(ctx, queryInsertQuote, quote.UserID, quote.From, quote.To, quote.Rate,
+		quote.Expires).Scan(&quote.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = createdErrors.ErrUserDoesNotExist
+		}
+		return nil, err
 	}
-	if _, err = transaction.Exec(context.Background(), querySaveTransaction, "transfer", senderID,
-		receiverID, amount); err != nil {
-		return err
+
+	return quote, nil
+}
+
+// GetQuote returns the quote of the user, ErrQuoteDoesNotExist is returned if it was used or has expired.
+func (s *Storage) GetQuote(ctx context.Context, quoteID string, userID int64) (_ *models.ExchangeQuote, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	quote := &models.ExchangeQuote{ID: quoteID, UserID: userID}
+	if err = transaction.QueryRow(ctx, queryGetQuote, quoteID, userID).Scan(&quote.From, &quote.To, &quote.Rate,
+		&quote.Expires); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = createdErrors.ErrQuoteDoesNotExist
+		}
+		return nil, err
 	}
 
-	return nil
+	return quote, nil
 }
 
-func (s *Storage) UpdateBalance(userID int64, amount float64) (float64, error) {
-	transaction, err := s.db.Begin(context.Background())
+// Exchange moves money between two wallets of the user through the exchange account: amount is debited
+// from the wallet in data.From and received is credited to the wallet in data.To, which is created if needed.
+// The quote of data.QuoteID is used up in the same transaction.
+func (s *Storage) Exchange(ctx context.Context, data *models.ExchangeRequest, received money.Money,
+	rate float64) (_ *models.ExchangeResult, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	var balance float64
-	if err = transaction.QueryRow(context.Background(), queryUpdateBalance, amount, userID).Scan(&balance); err != nil {
-		return 0, err
+	// the money is moved at most once for the idempotency key of the request
+	if err = repositoryIdempotency.Apply(ctx, transaction); err != nil {
+		return nil, err
 	}
 
-	var operationType string
-	if amount < 0 {
-		amount *= -1
-		operationType = "write_off"
-	} else {
-		operationType = "add"
+	if data.QuoteID != "" {
+		if err = transaction.QueryRow(ctx, queryUseQuote, data.QuoteID, data.UserID).Scan(&data.QuoteID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) { // the quote has expired or was used by a concurrent request
+				err = createdErrors.ErrQuoteDoesNotExist
+			}
+			return nil, err
+		}
 	}
 
-	if _, err = transaction.Exec(context.Background(), querySaveTransaction, operationType, userID, 0, amount); err != nil {
-		return 0, err
+	if _, err = transaction.Exec(ctx, queryInsertWallet, data.UserID, data.To); err != nil {
+		return nil, err
 	}
 
-	return balance, nil
+	rows, err := transaction.Query(ctx, queryLockUsers, data.UserID, data.From, data.UserID, data.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exchangeResult := &models.ExchangeResult{Amount: data.Amount, Received: received, Rate: rate}
+	for rows.Next() {
+		wallet := &models.UserData{}
+		if err = rows.Scan(&wallet.UserID, &wallet.Currency, &wallet.Balance); err != nil {
+			return nil, err
+		}
+		switch wallet.Currency {
+		case data.From:
+			exchangeResult.From = wallet
+		case data.To:
+			exchangeResult.To = wallet
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	switch {
+	case exchangeResult.To == nil: // the wallet is created for every existing user
+		err = createdErrors.ErrUserDoesNotExist
+	case exchangeResult.From == nil:
+		err = createdErrors.ErrWalletDoesNotExist
+	case exchangeResult.From.Balance < data.Amount:
+		err = createdErrors.ErrNotEnoughMoney
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, data.Amount*-1, data.UserID,
+		data.From).Scan(&exchangeResult.From.Balance); err != nil {
+		return nil, err
+	}
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, received, data.UserID,
+		data.To).Scan(&exchangeResult.To.Balance); err != nil {
+		return nil, err
+	}
+	entry := &ledger.Entry{
+		OperationType: "exchange",
+		SenderID:      data.UserID,
+		Amount:        data.Amount,
+		Currency:      data.From,
+		Conversion:    &ledger.Conversion{Currency: data.To, Amount: received, Rate: rate},
+		Postings: []ledger.Posting{
+			ledger.User(data.UserID, data.Amount*-1, exchangeResult.From.Balance),
+			ledger.User(data.UserID, received, exchangeResult.To.Balance).In(data.To),
+			ledger.System(ledger.Exchange, data.Amount),
+			ledger.System(ledger.Exchange, received*-1).In(data.To),
+		},
+	}
+	if err = describe(entry, &data.Purpose); err != nil {
+		return nil, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, entry); err != nil {
+		return nil, err
+	}
+
+	return exchangeResult, nil
+}
+
+// describe copies the comment, reason and source of the operation to the ledger entry.
+func describe(entry *ledger.Entry, purpose *models.Purpose) error {
+	if purpose == nil {
+		return nil
+	}
+
+	entry.Comment, entry.Reason = purpose.Comment, purpose.Reason
+	if purpose.Source != nil && *purpose.Source != (models.Source{}) {
+		source, err := json.Marshal(purpose.Source)
+		if err != nil {
+			return err
+		}
+		entry.Source = source
+	}
+
+	return nil
+}
+
+// writeOffError tells a write off from a user that does not exist from a write off without enough money,
+// a missing wallet of an existing user has zero balance.
+func (s *Storage) writeOffError(ctx context.Context, transaction pgx.Tx, userID int64) error {
+	var exists bool
+	if err := transaction.QueryRow(ctx, queryUserExists, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return createdErrors.ErrUserDoesNotExist
+	}
+
+	return createdErrors.ErrNotEnoughMoney
 }
@@ -2,19 +2,25 @@ package repository
 
 import (
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"context"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/pashagolub/pgxmock"
 	"github.com/stretchr/testify/assert"
 
+	"avito-tech-task/internal/app/idempotency"
 	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/ledger"
+	ledgerMock "avito-tech-task/internal/pkg/ledger/mock"
+	"avito-tech-task/internal/pkg/money"
 )
 
 func TestStorage_GetUserData(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
@@ -34,18 +40,20 @@ func TestStorage_GetUserData(t *testing.T) {
 			userID: 1,
 			mock: func() {
 				var (
-					balance float64 = 1000
-					userID  int64   = 1
+					balance  money.Money = 1000
+					reserved money.Money = 500
+					userID   int64       = 1
 				)
-				rows := pgxmock.NewRows([]string{"balance"})
-				rows.AddRow(balance)
+				rows := pgxmock.NewRows([]string{"balance", "reserved"})
+				rows.AddRow(balance, reserved)
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID).WillReturnRows(rows)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID, "RUB").WillReturnRows(rows)
 				mock.ExpectCommit()
 			},
 			expected: &models.UserData{
-				UserID:  1,
-				Balance: 1000,
+				UserID:   1,
+				Balance:  1000,
+				Reserved: 500,
 			},
 		},
 		{
@@ -54,7 +62,7 @@ func TestStorage_GetUserData(t *testing.T) {
 			mock: func() {
 				var userID int64 = 1
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID).WillReturnError(pgx.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID, "RUB").WillReturnError(pgx.ErrNoRows)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
@@ -66,12 +74,21 @@ func TestStorage_GetUserData(t *testing.T) {
 			mock: func() {
 				var userID int64 = 1
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID).WillReturnError(dbErr)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetBalance)).WithArgs(userID, "RUB").WillReturnError(dbErr)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
 			err:         dbErr,
 		},
+		{
+			name:   "Could not begin transaction",
+			userID: 1,
+			mock: func() {
+				mock.ExpectBegin().WillReturnError(dbErr)
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
 	}
 
 	var got *models.UserData
@@ -79,7 +96,7 @@ func TestStorage_GetUserData(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			test.mock()
-			got, err = storage.GetUserData(test.userID)
+			got, err = storage.GetUserData(context.Background(), test.userID, "RUB")
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -92,8 +109,8 @@ func TestStorage_GetUserData(t *testing.T) {
 	}
 }
 
-func TestStorage_CreateAccount(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+func TestStorage_UpdateBalance(t *testing.T) {
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
@@ -103,137 +120,543 @@ func TestStorage_CreateAccount(t *testing.T) {
 	tests := []struct {
 		name        string
 		userID      int64
+		amount      money.Money
+		purpose     *models.Purpose
 		mock        func()
+		entry       *ledger.Entry
+		ledgerErr   error
+		expected    money.Money
 		expectedErr bool
 		err         error
 	}{
 		{
-			name:   "Successfully created new account",
+			name:   "Successfully updated balance",
 			userID: 1,
+			amount: 1000,
 			mock: func() {
-				var userID int64 = 1
+				var (
+					userID         int64       = 1
+					amount         money.Money = 1000
+					updatedBalance money.Money = 2000
+				)
+				rows := pgxmock.NewRows([]string{"balance"})
+				rows.AddRow(updatedBalance)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertUser)).WithArgs(userID).
+					WillReturnResult(pgxmock.NewResult("INSERT", 0))
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(userID, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 0))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Amount:        1000,
+				Currency:      "RUB",
+				Postings:      []ledger.Posting{ledger.User(1, 1000, 2000), ledger.System(ledger.TopUps, -1000)},
+			},
+			expected: 2000,
+		},
+		{
+			name:   "Top up with comment, reason and source",
+			userID: 1,
+			amount: 1000,
+			purpose: &models.Purpose{
+				Comment: "Welcome bonus",
+				Reason:  "promo",
+				Source:  &models.Source{PromoCode: "WELCOME100"},
+			},
+			mock: func() {
+				var (
+					userID         int64       = 1
+					amount         money.Money = 1000
+					updatedBalance money.Money = 1000
+				)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryInsertBalance)).WithArgs(userID).
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertUser)).WithArgs(userID).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(userID, "RUB").
 					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(updatedBalance))
 				mock.ExpectCommit()
 			},
+			entry: &ledger.Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Amount:        1000,
+				Currency:      "RUB",
+				Postings:      []ledger.Posting{ledger.User(1, 1000, 1000), ledger.System(ledger.TopUps, -1000)},
+				Comment:       "Welcome bonus",
+				Reason:        "promo",
+				Source:        []byte(`{"promo_code":"WELCOME100"}`),
+			},
+			expected: 1000,
 		},
 		{
-			name:   "Error in database",
+			name:   "Successfully wrote off money",
 			userID: 1,
+			amount: -1000,
+			mock: func() {
+				var (
+					userID         int64       = 1
+					amount         money.Money = -1000
+					updatedBalance money.Money = 500
+				)
+				rows := pgxmock.NewRows([]string{"balance"})
+				rows.AddRow(updatedBalance)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: "write_off",
+				SenderID:      1,
+				Amount:        1000,
+				Currency:      "RUB",
+				Postings:      []ledger.Posting{ledger.User(1, -1000, 500), ledger.System(ledger.WriteOffs, 1000)},
+			},
+			expected: 500,
+		},
+		{
+			name:   "Error in database during commit",
+			userID: 1,
+			amount: -1000,
+			mock: func() {
+				var (
+					userID         int64       = 1
+					amount         money.Money = -1000
+					updatedBalance money.Money = 500
+				)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(updatedBalance))
+				// e.g. the deferred check of balanced postings fails
+				mock.ExpectCommit().WillReturnError(dbErr)
+			},
+			entry: &ledger.Entry{
+				OperationType: "write_off",
+				SenderID:      1,
+				Amount:        1000,
+				Currency:      "RUB",
+				Postings:      []ledger.Posting{ledger.User(1, -1000, 500), ledger.System(ledger.WriteOffs, 1000)},
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+		{
+			name:   "Not enough money to write off",
+			userID: 1,
+			amount: -1000,
+			mock: func() {
+				var (
+					userID int64       = 1
+					amount money.Money = -1000
+				)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotEnoughMoney,
+		},
+		{
+			name:   "Write off from user that does not exist",
+			userID: 1,
+			amount: -1000,
+			mock: func() {
+				var (
+					userID int64       = 1
+					amount money.Money = -1000
+				)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrUserDoesNotExist,
+		},
+		{
+			name:   "Error in database during creating account",
+			userID: 1,
+			amount: 1000,
 			mock: func() {
 				var userID int64 = 1
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryInsertBalance)).WithArgs(userID).WillReturnError(dbErr)
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertUser)).WithArgs(userID).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+		{
+			name:   "Error in database during updating balance",
+			userID: 1,
+			amount: 1000,
+			mock: func() {
+				var (
+					userID int64       = 1
+					amount money.Money = 1000
+				)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertUser)).WithArgs(userID).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(userID, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+		{
+			name:   "Error in database during saving transaction info",
+			userID: 1,
+			amount: -1000,
+			mock: func() {
+				var (
+					userID         int64       = 1
+					amount         money.Money = -1000
+					updatedBalance money.Money
+				)
+				rows := pgxmock.NewRows([]string{"balance"})
+				rows.AddRow(updatedBalance)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID, "RUB").
+					WillReturnRows(rows)
 				mock.ExpectRollback()
 			},
+			entry: &ledger.Entry{
+				OperationType: "write_off",
+				SenderID:      1,
+				Amount:        1000,
+				Currency:      "RUB",
+				Postings:      []ledger.Posting{ledger.User(1, -1000, 0), ledger.System(ledger.WriteOffs, 1000)},
+			},
+			ledgerErr:   dbErr,
 			expectedErr: true,
 			err:         dbErr,
 		},
 	}
 
+	var got money.Money
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
+			var written *ledger.Entry
+			storage.journal = &ledgerMock.MockWriter{
+				WriteFunc: func(_ context.Context, _ pgx.Tx, entry *ledger.Entry) (int64, error) {
+					written = entry
+					return 1, test.ledgerErr
+				},
+			}
 			test.mock()
-			err = storage.CreateAccount(test.userID)
+			got, err = storage.UpdateBalance(context.Background(), test.userID, "RUB", test.amount, test.purpose)
 
 			if test.expectedErr {
 				assert.Error(t, err)
 				assert.Equal(t, test.err, err)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
 			}
+			assert.Equal(t, test.entry, written)
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
-func TestStorage_UpdateBalance(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+func TestStorage_UpdateBalanceWithIdempotencyKey(t *testing.T) {
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
 	storage := NewStorage(mock)
-	dbErr := errors.New("Error in database")
+	storage.journal = &ledgerMock.MockWriter{
+		WriteFunc: func(_ context.Context, _ pgx.Tx, _ *ledger.Entry) (int64, error) {
+			return 1, nil
+		},
+	}
+	ctx := idempotency.WithLock(context.Background(), idempotency.Lock{Key: "key", Token: "token"})
+	queryApplyKey := regexp.QuoteMeta("UPDATE idempotency_keys SET applied = true")
 
 	tests := []struct {
 		name        string
-		userID      int64
-		amount      float64
 		mock        func()
-		expected    float64
 		expectedErr bool
 		err         error
 	}{
 		{
-			name:   "Successfully updated balance",
-			userID: 1,
-			amount: 1000,
+			name: "Key of the request is applied with the balance update",
 			mock: func() {
-				var (
-					userID         int64   = 1
-					amount         float64 = 1000
-					updatedBalance float64 = 2000
-					operationType          = "add"
-				)
-				rows := pgxmock.NewRows([]string{"balance"})
-				rows.AddRow(updatedBalance)
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID).
-					WillReturnRows(rows)
-				mock.ExpectExec(regexp.QuoteMeta(querySaveTransaction)).WithArgs(operationType, userID, 0, amount).
-					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(queryApplyKey).WithArgs("key", "token").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(money.Money(-1000), int64(1), "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1000)))
 				mock.ExpectCommit()
 			},
-			expected: 2000,
 		},
 		{
-			name:   "Error in database during updating balance",
-			userID: 1,
-			amount: 1000,
+			name: "Key was taken over by a retry",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(queryApplyKey).WithArgs("key", "token").WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrIdempotencyKeyTakenOver,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+			_, err = storage.UpdateBalance(ctx, 1, "RUB", -1000, nil)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorage_MakeTransfer(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	dbErr := errors.New("Error in database")
+
+	var (
+		senderID      int64       = 1
+		receiverID    int64       = 2
+		amount        money.Money = 1000
+		operationType             = "transfer"
+	)
+	request := func(receiverCurrency string) *models.TransferRequest {
+		return &models.TransferRequest{SenderID: senderID, ReceiverID: receiverID, Amount: amount, Currency: "RUB",
+			ReceiverCurrency: receiverCurrency}
+	}
+	lockedRows := func(receiverCurrency string, balances map[int64]money.Money) *pgxmock.Rows {
+		rows := pgxmock.NewRows([]string{"user_id", "currency", "balance"})
+		for userID, currency := range map[int64]string{senderID: "RUB", receiverID: receiverCurrency} {
+			if balance, ok := balances[userID]; ok {
+				rows.AddRow(userID, currency, balance)
+			}
+		}
+		return rows
+	}
+	expectLock := func(receiverCurrency string, balances map[int64]money.Money) {
+		mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(receiverID, receiverCurrency).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mock.ExpectQuery(regexp.QuoteMeta(queryLockUsers)).WithArgs(senderID, "RUB", receiverID, receiverCurrency).
+			WillReturnRows(lockedRows(receiverCurrency, balances))
+	}
+
+	tests := []struct {
+		name             string
+		receiverCurrency string
+		received         money.Money
+		rate             float64
+		purpose          *models.Purpose
+		mock             func()
+		entry            *ledger.Entry
+		ledgerErr        error
+		expected         *models.TransferUsersData
+		expectedErr      bool
+		err              error
+	}{
+		{
+			name:    "Successfully transferred money",
+			purpose: &models.Purpose{Comment: "Dinner"},
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock("RUB", map[int64]money.Money{senderID: 1500, receiverID: 500})
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: operationType,
+				SenderID:      senderID,
+				ReceiverID:    receiverID,
+				Amount:        amount,
+				Currency:      "RUB",
+				Postings: []ledger.Posting{
+					ledger.User(senderID, amount*-1, 500),
+					ledger.User(receiverID, amount, 1500).In("RUB"),
+				},
+				Comment: "Dinner",
+			},
+			expected: &models.TransferUsersData{
+				Sender: &models.UserData{
+					UserID:   1,
+					Balance:  500,
+					Currency: "RUB",
+				},
+				Receiver: &models.UserData{
+					UserID:   2,
+					Balance:  1500,
+					Currency: "RUB",
+				},
+			},
+		},
+		{
+			name:             "Transfer with conversion goes through the exchange account",
+			receiverCurrency: "USD",
+			received:         13,
+			rate:             0.0131,
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock("USD", map[int64]money.Money{senderID: 1500, receiverID: 0})
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(money.Money(13), receiverID, "USD").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(13)))
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: operationType,
+				SenderID:      senderID,
+				ReceiverID:    receiverID,
+				Amount:        amount,
+				Currency:      "RUB",
+				Conversion:    &ledger.Conversion{Currency: "USD", Amount: 13, Rate: 0.0131},
+				Postings: []ledger.Posting{
+					ledger.User(senderID, amount*-1, 500),
+					ledger.User(receiverID, 13, 13).In("USD"),
+					ledger.System(ledger.Exchange, amount),
+					ledger.System(ledger.Exchange, -13).In("USD"),
+				},
+			},
+			expected: &models.TransferUsersData{
+				Sender:   &models.UserData{UserID: 1, Balance: 500, Currency: "RUB"},
+				Receiver: &models.UserData{UserID: 2, Balance: 13, Currency: "USD"},
+			},
+		},
+		{
+			name: "Sender not found",
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock("RUB", map[int64]money.Money{receiverID: 500})
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrSenderDoesNotExist,
+		},
+		{
+			name: "Receiver not found",
 			mock: func() {
-				var (
-					userID int64   = 1
-					amount float64 = 1000
-				)
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID).WillReturnError(dbErr)
+				expectLock("RUB", map[int64]money.Money{senderID: 1500})
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrReceiverDoesNotExist,
+		},
+		{
+			name: "Not enough money",
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock("RUB", map[int64]money.Money{senderID: 999, receiverID: 500})
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotEnoughMoney,
+		},
+		{
+			name: "Error in database during locking users",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(receiverID, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 0))
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockUsers)).WithArgs(senderID, "RUB", receiverID, "RUB").
+					WillReturnError(dbErr)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
 			err:         dbErr,
 		},
 		{
-			name:   "Error in database during saving transaction info",
-			userID: 1,
-			amount: -1000,
+			name: "Error in database during adding money to receiver",
 			mock: func() {
-				var (
-					userID         int64   = 1
-					amount         float64 = -1000
-					updatedBalance float64
-					operationType  = "write_off"
-				)
-				rows := pgxmock.NewRows([]string{"balance"})
-				rows.AddRow(updatedBalance)
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID).
-					WillReturnRows(rows)
-				mock.ExpectExec(regexp.QuoteMeta(querySaveTransaction)).WithArgs(operationType, userID, 0, amount*-1).
+				expectLock("RUB", map[int64]money.Money{senderID: 1500, receiverID: 500})
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID, "RUB").
 					WillReturnError(dbErr)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
 			err:         dbErr,
 		},
+		{
+			name: "Error in database during saving transaction",
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock("RUB", map[int64]money.Money{senderID: 1500, receiverID: 500})
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectRollback()
+			},
+			entry: &ledger.Entry{
+				OperationType: operationType,
+				SenderID:      senderID,
+				ReceiverID:    receiverID,
+				Amount:        amount,
+				Currency:      "RUB",
+				Postings: []ledger.Posting{
+					ledger.User(senderID, amount*-1, 500),
+					ledger.User(receiverID, amount, 1500).In("RUB"),
+				},
+			},
+			ledgerErr:   dbErr,
+			expectedErr: true,
+			err:         dbErr,
+		},
 	}
 
-	var got float64
+	var got *models.TransferUsersData
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
+			var written *ledger.Entry
+			storage.journal = &ledgerMock.MockWriter{
+				WriteFunc: func(_ context.Context, _ pgx.Tx, entry *ledger.Entry) (int64, error) {
+					written = entry
+					return 1, test.ledgerErr
+				},
+			}
 			test.mock()
-			got, err = storage.UpdateBalance(test.userID, test.amount)
+			receiverCurrency, received, rate := test.receiverCurrency, test.received, test.rate
+			if receiverCurrency == "" {
+				receiverCurrency, received, rate = "RUB", amount, 1
+			}
+			data := request(receiverCurrency)
+			if test.purpose != nil {
+				data.Purpose = *test.purpose
+			}
+			got, err = storage.MakeTransfer(context.Background(), data, received, rate)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -242,113 +665,121 @@ func TestStorage_UpdateBalance(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expected, got)
 			}
+			assert.Equal(t, test.entry, written)
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
-func TestStorage_MakeTransfer(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+func TestStorage_SaveQuote(t *testing.T) {
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
 	storage := NewStorage(mock)
 	dbErr := errors.New("Error in database")
+	expires := time.Date(2022, 1, 19, 12, 1, 0, 0, time.UTC)
+	quoteID := "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
 
 	tests := []struct {
 		name        string
-		senderID    int64
-		receiverID  int64
-		amount      float64
 		mock        func()
+		expected    *models.ExchangeQuote
 		expectedErr bool
 		err         error
 	}{
 		{
-			name:       "Successfully transferred money",
-			senderID:   1,
-			receiverID: 2,
-			amount:     1000,
+			name: "Successfully saved quote",
 			mock: func() {
-				var (
-					senderID      int64   = 1
-					receiverID    int64   = 2
-					amount        float64 = 1000
-					operationType         = "transfer"
-				)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID).
-					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID).
-					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(regexp.QuoteMeta(querySaveTransaction)).
-					WithArgs(operationType, senderID, receiverID, amount).
-					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredQuotes)).WithArgs(int64(1)).
+					WillReturnResult(pgxmock.NewResult("DELETE", 2))
+				mock.ExpectQuery(regexp.QuoteMeta(queryInsertQuote)).WithArgs(int64(1), "RUB", "USD", 0.013, expires).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(quoteID))
 				mock.ExpectCommit()
 			},
+			expected: &models.ExchangeQuote{ID: quoteID, UserID: 1, From: "RUB", To: "USD", Rate: 0.013, Expires: expires},
 		},
 		{
-			name:       "Error in database during writing off money from sender",
-			senderID:   1,
-			receiverID: 2,
-			amount:     1000,
+			name: "User not found",
 			mock: func() {
-				var (
-					senderID int64   = 1
-					amount   float64 = 1000
-				)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID).
-					WillReturnError(dbErr)
+				mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredQuotes)).WithArgs(int64(1)).
+					WillReturnResult(pgxmock.NewResult("DELETE", 0))
+				mock.ExpectQuery(regexp.QuoteMeta(queryInsertQuote)).WithArgs(int64(1), "RUB", "USD", 0.013, expires).
+					WillReturnError(pgx.ErrNoRows)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
-			err:         dbErr,
+			err:         createdErrors.ErrUserDoesNotExist,
 		},
 		{
-			name:       "Error in database during adding money to receiver",
-			senderID:   1,
-			receiverID: 2,
-			amount:     1000,
+			name: "Error in database during deleting expired quotes",
 			mock: func() {
-				var (
-					senderID   int64   = 1
-					receiverID int64   = 2
-					amount     float64 = 1000
-				)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID).
-					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID).
-					WillReturnError(dbErr)
+				mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredQuotes)).WithArgs(int64(1)).WillReturnError(dbErr)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
 			err:         dbErr,
 		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+			got, err := storage.SaveQuote(context.Background(),
+				&models.ExchangeQuote{UserID: 1, From: "RUB", To: "USD", Rate: 0.013, Expires: expires})
+
+			if test.expectedErr {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorage_GetQuote(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	expires := time.Date(2022, 1, 19, 12, 1, 0, 0, time.UTC)
+	quoteID := "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
+
+	tests := []struct {
+		name        string
+		mock        func()
+		expected    *models.ExchangeQuote
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Successfully got quote",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetQuote)).WithArgs(quoteID, int64(1)).
+					WillReturnRows(pgxmock.NewRows([]string{"currency", "receiver_currency", "rate", "expires"}).
+						AddRow("RUB", "USD", 0.013, expires))
+				mock.ExpectCommit()
+			},
+			expected: &models.ExchangeQuote{ID: quoteID, UserID: 1, From: "RUB", To: "USD", Rate: 0.013, Expires: expires},
+		},
 		{
-			name:       "Error in database during saving sender transaction",
-			senderID:   1,
-			receiverID: 2,
-			amount:     1000,
+			name: "Quote was used, has expired or belongs to another user",
 			mock: func() {
-				var (
-					senderID      int64   = 1
-					receiverID    int64   = 2
-					amount        float64 = 1000
-					operationType         = "transfer"
-				)
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, senderID).
-					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, receiverID).
-					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
-				mock.ExpectExec(regexp.QuoteMeta(querySaveTransaction)).
-					WithArgs(operationType, senderID, receiverID, amount).
-					WillReturnError(dbErr)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetQuote)).WithArgs(quoteID, int64(1)).
+					WillReturnError(pgx.ErrNoRows)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
-			err:         dbErr,
+			err:         createdErrors.ErrQuoteDoesNotExist,
 		},
 	}
 
@@ -356,165 +787,186 @@ func TestStorage_MakeTransfer(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			test.mock()
-			err = storage.MakeTransfer(test.senderID, test.receiverID, test.amount)
+			got, err := storage.GetQuote(context.Background(), quoteID, 1)
 
 			if test.expectedErr {
-				assert.Error(t, err)
-				assert.Equal(t, test.err, err)
+				assert.ErrorIs(t, err, test.err)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
-func TestStorage_GetTransferUsersData(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+func TestStorage_Exchange(t *testing.T) {
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
 	storage := NewStorage(mock)
-	dbError := errors.New("Error in database")
+	dbErr := errors.New("Error in database")
+
+	var (
+		userID   int64       = 1
+		amount   money.Money = 100000
+		received money.Money = 1303
+		rate                 = 0.013035
+		quoteID              = "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
+	)
+	expectLock := func(balances map[string]money.Money) {
+		mock.ExpectExec(regexp.QuoteMeta(queryInsertWallet)).WithArgs(userID, "USD").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		rows := pgxmock.NewRows([]string{"user_id", "currency", "balance"})
+		for _, currency := range []string{"RUB", "USD"} {
+			if balance, ok := balances[currency]; ok {
+				rows.AddRow(userID, currency, balance)
+			}
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(queryLockUsers)).WithArgs(userID, "RUB", userID, "USD").WillReturnRows(rows)
+	}
+	expectUpdate := func() {
+		mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, userID, "RUB").
+			WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(50000)))
+		mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(received, userID, "USD").
+			WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(received))
+	}
+	exchangeEntry := &ledger.Entry{
+		OperationType: "exchange",
+		SenderID:      userID,
+		Amount:        amount,
+		Currency:      "RUB",
+		Conversion:    &ledger.Conversion{Currency: "USD", Amount: received, Rate: rate},
+		Postings: []ledger.Posting{
+			ledger.User(userID, amount*-1, 50000),
+			ledger.User(userID, received, received).In("USD"),
+			ledger.System(ledger.Exchange, amount),
+			ledger.System(ledger.Exchange, received*-1).In("USD"),
+		},
+	}
+	exchanged := &models.ExchangeResult{
+		Amount:   amount,
+		Received: received,
+		Rate:     rate,
+		From:     &models.UserData{UserID: userID, Balance: 50000, Currency: "RUB"},
+		To:       &models.UserData{UserID: userID, Balance: received, Currency: "USD"},
+	}
 
 	tests := []struct {
 		name        string
-		senderID    int64
-		receiverID  int64
+		quoteID     string
 		mock        func()
-		expected    *models.TransferUsersData
+		entry       *ledger.Entry
+		expected    *models.ExchangeResult
 		expectedErr bool
 		err         error
 	}{
 		{
-			name:       "Successfully get data about users in transfer",
-			senderID:   1,
-			receiverID: 2,
+			name: "Successfully exchanged money at the current rate",
 			mock: func() {
-				var (
-					senderID        int64   = 1
-					senderBalance   float64 = 1000
-					receiverID      int64   = 2
-					receiverBalance float64 = 1000
-				)
 				mock.ExpectBegin()
-				rows := pgxmock.NewRows([]string{"user_id", "balance"})
-				rows.AddRow(senderID, senderBalance)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(senderID).WillReturnRows(rows)
-				rows = pgxmock.NewRows([]string{"user_id", "balance"})
-				rows.AddRow(receiverID, receiverBalance)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(receiverID).WillReturnRows(rows)
+				expectLock(map[string]money.Money{"RUB": 150000, "USD": 0})
+				expectUpdate()
 				mock.ExpectCommit()
 			},
-			expected: &models.TransferUsersData{
-				Sender: &models.UserData{
-					UserID:  1,
-					Balance: 1000,
-				},
-				Receiver: &models.UserData{
-					UserID:  2,
-					Balance: 1000,
-				},
-			},
+			entry:    exchangeEntry,
+			expected: exchanged,
 		},
 		{
-			name:       "Sender not found",
-			senderID:   1,
-			receiverID: 2,
+			name:    "Quote is used up",
+			quoteID: quoteID,
 			mock: func() {
-				var (
-					senderID        int64   = 1
-					receiverID      int64   = 2
-					receiverBalance float64 = 1000
-				)
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(senderID).WillReturnError(pgx.ErrNoRows)
-				rows := pgxmock.NewRows([]string{"user_id", "balance"})
-				rows.AddRow(receiverID, receiverBalance)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(receiverID).WillReturnRows(rows)
+				mock.ExpectQuery(regexp.QuoteMeta(queryUseQuote)).WithArgs(quoteID, userID).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(quoteID))
+				expectLock(map[string]money.Money{"RUB": 150000, "USD": 0})
+				expectUpdate()
 				mock.ExpectCommit()
 			},
-			expected: &models.TransferUsersData{
-				Sender: nil,
-				Receiver: &models.UserData{
-					UserID:  2,
-					Balance: 1000,
-				},
+			entry:    exchangeEntry,
+			expected: exchanged,
+		},
+		{
+			name:    "Quote was used by a concurrent request",
+			quoteID: quoteID,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUseQuote)).WithArgs(quoteID, userID).
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectRollback()
 			},
+			expectedErr: true,
+			err:         createdErrors.ErrQuoteDoesNotExist,
 		},
 		{
-			name:       "Receiver not found",
-			senderID:   1,
-			receiverID: 2,
+			name: "User not found",
 			mock: func() {
-				var (
-					senderID      int64   = 1
-					senderBalance float64 = 1000
-					receiverID    int64   = 2
-				)
 				mock.ExpectBegin()
-				rows := pgxmock.NewRows([]string{"user_id", "balance"})
-				rows.AddRow(senderID, senderBalance)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(senderID).WillReturnRows(rows)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(receiverID).WillReturnError(pgx.ErrNoRows)
-				mock.ExpectCommit()
+				expectLock(map[string]money.Money{})
+				mock.ExpectRollback()
 			},
-			expected: &models.TransferUsersData{
-				Sender: &models.UserData{
-					UserID:  1,
-					Balance: 1000,
-				},
-				Receiver: nil,
+			expectedErr: true,
+			err:         createdErrors.ErrUserDoesNotExist,
+		},
+		{
+			name: "User does not have a wallet in the debited currency",
+			mock: func() {
+				mock.ExpectBegin()
+				expectLock(map[string]money.Money{"USD": 0})
+				mock.ExpectRollback()
 			},
+			expectedErr: true,
+			err:         createdErrors.ErrWalletDoesNotExist,
 		},
 		{
-			name:       "Error in database occurred during getting data about sender",
-			senderID:   1,
-			receiverID: 2,
+			name: "Not enough money",
 			mock: func() {
-				var senderID int64 = 1
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(senderID).WillReturnError(dbError)
+				expectLock(map[string]money.Money{"RUB": 99999, "USD": 0})
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
-			err:         dbError,
+			err:         createdErrors.ErrNotEnoughMoney,
 		},
 		{
-			name:       "Error in database occurred during getting data about receiver",
-			senderID:   1,
-			receiverID: 2,
+			name: "Error in database during crediting money",
 			mock: func() {
-				var (
-					senderID      int64   = 1
-					senderBalance float64 = 1000
-					receiverID    int64   = 2
-				)
 				mock.ExpectBegin()
-				rows := pgxmock.NewRows([]string{"user_id", "balance"})
-				rows.AddRow(senderID, senderBalance)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(senderID).WillReturnRows(rows)
-				mock.ExpectQuery(regexp.QuoteMeta(queryGetUser)).WithArgs(receiverID).WillReturnError(dbError)
+				expectLock(map[string]money.Money{"RUB": 150000, "USD": 0})
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, userID, "RUB").
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(50000)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(received, userID, "USD").
+					WillReturnError(dbErr)
 				mock.ExpectRollback()
 			},
 			expectedErr: true,
-			err:         dbError,
+			err:         dbErr,
 		},
 	}
 
-	var got *models.TransferUsersData
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
+			var written *ledger.Entry
+			storage.journal = &ledgerMock.MockWriter{
+				WriteFunc: func(_ context.Context, _ pgx.Tx, entry *ledger.Entry) (int64, error) {
+					written = entry
+					return 1, nil
+				},
+			}
 			test.mock()
-			got, err = storage.GetTransferUsersData(test.senderID, test.receiverID)
+			data := &models.ExchangeRequest{UserID: userID, From: "RUB", To: "USD", Amount: amount, QuoteID: test.quoteID}
+			got, err := storage.Exchange(context.Background(), data, received, rate)
 
 			if test.expectedErr {
-				assert.Error(t, err)
-				assert.Equal(t, test.err, err)
+				assert.ErrorIs(t, err, test.err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expected, got)
 			}
+			assert.Equal(t, test.entry, written)
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
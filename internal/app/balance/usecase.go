@@ -1,10 +1,26 @@
 package balance
 
-import "avito-tech-task/internal/app/models"
+import (
+	"context"
+	"time"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/money"
+)
 
 //go:generate moq -out ./mock/balance_usecase_mock.go -pkg mock . Service:MockService
 type Service interface {
-	GetBalance(int64, string) (*models.UserData, error)
-	MakeTransfer(*models.TransferRequest) (*models.TransferUsersData, error)
-	UpdateBalance(*models.RequestUpdateBalance) (*models.UserData, error)
+	GetBalance(context.Context, int64, string, string, time.Duration) (*models.Balance, error)
+	MakeTransfer(context.Context, *models.TransferRequest) (*models.TransferUsersData, error)
+	UpdateBalance(context.Context, *models.RequestUpdateBalance) (*models.UserData, error)
+	CreateQuote(context.Context, *models.ExchangeQuoteRequest) (*models.ExchangeQuote, error)
+	Exchange(context.Context, *models.ExchangeRequest) (*models.ExchangeResult, error)
+}
+
+//go:generate moq -out ./mock/balance_metrics_mock.go -pkg mock . Metrics:MockMetrics
+
+// Metrics records money movements, operation types are the ones of the ledger, e.g. transfer.
+type Metrics interface {
+	TransactionCommitted(operationType, currency string, amount money.Money)
+	InsufficientFunds(operationType string)
 }
@@ -1,52 +1,100 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
 	"avito-tech-task/internal/app/balance"
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/pkg/constants"
 	"avito-tech-task/internal/pkg/currency"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
-	"errors"
 )
 
+const dateLayout = "2006-01-02"
+
 type Service struct {
 	validator *utils.Validation
 	storage   balance.Storage
 	converter currency.ConverterIface
+	metrics   balance.Metrics
+	// spread is the share of the rate kept on exchange between wallets of the user
+	spread   float64
+	quoteTTL time.Duration
 }
 
-func NewService(storage balance.Storage, validator *utils.Validation, converter currency.ConverterIface) *Service {
+func NewService(storage balance.Storage, validator *utils.Validation, converter currency.ConverterIface,
+	metrics balance.Metrics, spread float64, quoteTTL time.Duration) *Service {
+	if quoteTTL <= 0 {
+		quoteTTL = constants.DefaultExchangeQuoteTTL
+	}
+
 	return &Service{
 		storage:   storage,
 		validator: validator,
 		converter: converter,
+		metrics:   metrics,
+		spread:    spread,
+		quoteTTL:  quoteTTL,
 	}
 }
 
-func (s *Service) GetBalance(id int64, currency string) (*models.UserData, error) {
-	if len(currency) == 0 {
-		currency = "RUB"
+// GetBalance returns the balance of the user wallet converted to the currency at the actual rate. The wallet
+// is RUB by default and the balance is not converted if the currency is not set. Codes are case insensitive.
+// The balance is not converted with rates fetched more than maxRateAge ago, any rates are used if it is not set.
+func (s *Service) GetBalance(ctx context.Context, id int64, wallet, currencyCode string,
+	maxRateAge time.Duration) (*models.Balance, error) {
+	if maxRateAge < 0 {
+		return nil, createdErrors.ErrNegativeMaxRateAge
+	}
+	wallet = strings.ToUpper(strings.TrimSpace(wallet))
+	if len(wallet) == 0 {
+		wallet = constants.BaseCurrency
+	}
+	currencyCode = strings.ToUpper(strings.TrimSpace(currencyCode))
+	if len(currencyCode) == 0 {
+		currencyCode = wallet
 	}
 
-	userData, err := s.storage.GetUserData(id)
+	rate, err := s.actualRate(wallet, currencyCode)
 	if err != nil {
 		return nil, err
 	}
-	if userData == nil {
-		return nil, createdErrors.ErrUserDoesNotExist
+	if wallet != currencyCode {
+		if s.converter.Snapshot().Stale(time.Now(), maxRateAge) {
+			return nil, createdErrors.ErrStaleRates
+		}
+		utils.Log(ctx).WithFields(logrus.Fields{"from": wallet, "to": currencyCode, "rate": rate.Value,
+			"rate_date": rate.Date.Format(dateLayout)}).Debug("Converting balance")
 	}
 
-	convertingCoeff, err := s.converter.Get(currency)
+	userData, err := s.storage.GetUserData(ctx, id, wallet)
 	if err != nil {
 		return nil, err
 	}
-	userData.Balance *= convertingCoeff
+	if userData == nil {
+		return nil, createdErrors.ErrUserDoesNotExist
+	}
 
-	return userData, nil
+	digits := currency.Digits(currencyCode)
+	return &models.Balance{
+		UserID:   userData.UserID,
+		Wallet:   wallet,
+		Balance:  userData.Balance.Convert(rate.Value, digits),
+		Reserved: userData.Reserved.Convert(rate.Value, digits),
+		Currency: currencyCode,
+		Rate:     rate.Value,
+		RateDate: rate.Date.Format(dateLayout),
+	}, nil
 }
 
-func (s *Service) MakeTransfer(data *models.TransferRequest) (*models.TransferUsersData, error) {
+func (s *Service) MakeTransfer(ctx context.Context, data *models.TransferRequest) (*models.TransferUsersData, error) {
 	errors := s.validator.Validate(data) // validation
 	for _, err := range errors {
 		switch err.Field() {
@@ -56,36 +104,50 @@ func (s *Service) MakeTransfer(data *models.TransferRequest) (*models.TransferUs
 			return nil, createdErrors.ErrReceiverIDisRequired
 		case "Amount":
 			return nil, createdErrors.ErrAmountFiledIsRequired
+		case "Currency", "ReceiverCurrency":
+			return nil, createdErrors.ErrNotSupportedCurrency
+		case "Comment", "Reason", "OrderID", "ServiceID", "PromoCode":
+			return nil, createdErrors.ErrInvalidPurpose
 		}
 	}
+	if data.SenderID == data.ReceiverID {
+		return nil, createdErrors.ErrTransferToSelf
+	}
+	if len(data.Currency) == 0 {
+		data.Currency = constants.BaseCurrency
+	}
+	if len(data.ReceiverCurrency) == 0 {
+		data.ReceiverCurrency = data.Currency
+	}
 
-	transferUsersData, err := s.storage.GetTransferUsersData(data.SenderID, data.ReceiverID)
+	// the receiver gets the amount converted at the current rate
+	rate, err := s.exchangeRate(data.Currency, data.ReceiverCurrency)
 	if err != nil {
 		return nil, err
 	}
-	if transferUsersData.Sender == nil { // check if sender exists
-		return nil, createdErrors.ErrSenderDoesNotExist
+	if err := checkWallets(data.Currency, data.ReceiverCurrency); err != nil {
+		return nil, err
 	}
-	if transferUsersData.Receiver == nil { // check if receiver exists
-		return nil, createdErrors.ErrReceiverDoesNotExist
+	if err := s.checkFreshRates(data.Currency, data.ReceiverCurrency); err != nil {
+		return nil, err
 	}
-
-	if transferUsersData.Sender.Balance < data.Amount {
-		return nil, createdErrors.ErrNotEnoughMoney
+	received := convert(data.Amount, rate)
+	if received <= 0 {
+		return nil, createdErrors.ErrAmountTooSmallToConvert
 	}
-
-	if err = s.storage.MakeTransfer(data.SenderID, data.ReceiverID, data.Amount); err != nil {
-		return nil, err
+	if data.Currency != data.ReceiverCurrency {
+		utils.Log(ctx).WithFields(logrus.Fields{"from": data.Currency, "to": data.ReceiverCurrency, "rate": rate,
+			"received": received.String()}).Debug("Converting transfer")
 	}
 
-	transferUsersData.Sender.Balance -= data.Amount
-	transferUsersData.Receiver.Balance += data.Amount
+	// existence of users and sufficiency of money are checked by storage under row locks
+	result, err := s.storage.MakeTransfer(ctx, data, received, rate)
+	s.record("transfer", data.Currency, data.Amount, err)
 
-	return transferUsersData, nil
+	return result, err
 }
 
-//nolint:cyclop
-func (s *Service) UpdateBalance(data *models.RequestUpdateBalance) (*models.UserData, error) {
+func (s *Service) UpdateBalance(ctx context.Context, data *models.RequestUpdateBalance) (*models.UserData, error) {
 	errs := s.validator.Validate(data) // validation
 	for _, err := range errs {
 		switch err.Field() {
@@ -95,35 +157,204 @@ func (s *Service) UpdateBalance(data *models.RequestUpdateBalance) (*models.User
 			return nil, createdErrors.ErrNotSupportedOperationType
 		case "Amount":
 			return nil, createdErrors.ErrAmountFiledIsRequired
+		case "Currency":
+			return nil, createdErrors.ErrNotSupportedCurrency
+		case "Comment", "Reason", "OrderID", "ServiceID", "PromoCode":
+			return nil, createdErrors.ErrInvalidPurpose
+		}
+	}
+	currency := data.Currency
+	if len(currency) == 0 {
+		currency = constants.BaseCurrency
+	}
+	// wallets are kept only in currencies with known rates, so that money can be exchanged
+	if _, err := s.exchangeRate(constants.BaseCurrency, currency); err != nil {
+		return nil, err
+	}
+	if err := checkWallets(currency); err != nil {
+		return nil, err
+	}
+
+	amount, operationType := data.Amount, "add"
+	if data.OperationType == constants.REDUCE {
+		amount, operationType = amount*-1, "write_off"
+	}
+
+	// storage creates wallet on the first top up and atomically checks that balance stays non-negative
+	newBalance, err := s.storage.UpdateBalance(ctx, data.UserID, currency, amount, &data.Purpose)
+	s.record(operationType, currency, data.Amount, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserData{UserID: data.UserID, Balance: newBalance, Currency: currency}, nil
+}
+
+// CreateQuote locks the current exchange rate with the spread for the user until the quote expires.
+func (s *Service) CreateQuote(ctx context.Context, data *models.ExchangeQuoteRequest) (*models.ExchangeQuote, error) {
+	errs := s.validator.Validate(data) // validation
+	for _, err := range errs {
+		switch err.Field() {
+		case "UserID":
+			return nil, createdErrors.ErrNegativeUserID
+		case "From", "To":
+			return nil, createdErrors.ErrNotSupportedCurrency
 		}
 	}
+	if data.From == data.To {
+		return nil, createdErrors.ErrExchangeSameCurrency
+	}
+	if err := checkWallets(data.From, data.To); err != nil {
+		return nil, err
+	}
 
-	userData, err := s.storage.GetUserData(data.UserID) // check if user exists
+	rate, err := s.exchangeRate(data.From, data.To)
 	if err != nil {
-		switch errors.Is(err, createdErrors.ErrUserDoesNotExist) {
-		case true:
-			if err = s.storage.CreateAccount(data.UserID); err != nil { // create account if user does not exist
-				return nil, err
-			}
-			if data.OperationType == constants.REDUCE { // trying to write off money from created account (balance = 0)
-				return nil, createdErrors.ErrNotEnoughMoney
-			}
-		case false:
+		return nil, err
+	}
+	if err = s.checkFreshRates(data.From, data.To); err != nil {
+		return nil, err
+	}
+
+	return s.storage.SaveQuote(ctx, &models.ExchangeQuote{
+		UserID:  data.UserID,
+		From:    data.From,
+		To:      data.To,
+		Rate:    rate * (1 - s.spread),
+		Expires: time.Now().Add(s.quoteTTL),
+	})
+}
+
+// Exchange converts money between wallets of the user at the rate of the quote, or at the current rate
+// with the spread if the quote is not set.
+func (s *Service) Exchange(ctx context.Context, data *models.ExchangeRequest) (*models.ExchangeResult, error) {
+	errs := s.validator.Validate(data) // validation
+	for _, err := range errs {
+		switch err.Field() {
+		case "UserID":
+			return nil, createdErrors.ErrNegativeUserID
+		case "From", "To":
+			return nil, createdErrors.ErrNotSupportedCurrency
+		case "Amount":
+			return nil, createdErrors.ErrAmountFiledIsRequired
+		case "QuoteID":
+			return nil, createdErrors.ErrQuoteDoesNotExist
+		case "Comment", "Reason", "OrderID", "ServiceID", "PromoCode":
+			return nil, createdErrors.ErrInvalidPurpose
+		}
+	}
+	if data.From == data.To {
+		return nil, createdErrors.ErrExchangeSameCurrency
+	}
+	if err := checkWallets(data.From, data.To); err != nil {
+		return nil, err
+	}
+
+	var rate float64
+	if data.QuoteID != "" {
+		quote, err := s.storage.GetQuote(ctx, data.QuoteID, data.UserID)
+		if err != nil {
 			return nil, err
 		}
+		if quote.From != data.From || quote.To != data.To {
+			return nil, createdErrors.ErrQuoteMismatch
+		}
+		rate = quote.Rate // the rates were checked when the quote was created
+	} else {
+		marketRate, err := s.exchangeRate(data.From, data.To)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.checkFreshRates(data.From, data.To); err != nil {
+			return nil, err
+		}
+		rate = marketRate * (1 - s.spread)
 	}
 
-	if data.OperationType == constants.REDUCE {
-		if userData.Balance < data.Amount {
-			return nil, createdErrors.ErrNotEnoughMoney
+	received := convert(data.Amount, rate)
+	if received <= 0 {
+		return nil, createdErrors.ErrAmountTooSmallToConvert
+	}
+	utils.Log(ctx).WithFields(logrus.Fields{"from": data.From, "to": data.To, "rate": rate, "quote_id": data.QuoteID,
+		"received": received.String()}).Debug("Exchanging money")
+
+	// the quote is used up and sufficiency of money is checked by storage under row locks
+	result, err := s.storage.Exchange(ctx, data, received, rate)
+	s.record("exchange", data.From, data.Amount, err)
+
+	return result, err
+}
+
+// record reports the committed transaction or the lack of money for it to metrics.
+func (s *Service) record(operationType, currencyCode string, amount money.Money, err error) {
+	switch {
+	case err == nil:
+		s.metrics.TransactionCommitted(operationType, currencyCode, amount)
+	case errors.Is(err, createdErrors.ErrNotEnoughMoney):
+		s.metrics.InsufficientFunds(operationType)
+	}
+}
+
+// checkWallets checks that money in the currencies can be kept in wallets. Amounts are stored in hundredths,
+// so currencies with other minor units, e.g. JPY or KWD, are only used to show converted balances.
+func checkWallets(codes ...string) error {
+	for _, code := range codes {
+		if currency.Digits(code) != money.Precision {
+			return createdErrors.ErrNotSupportedWallet
 		}
-		data.Amount *= -1
 	}
 
-	newBalance, err := s.storage.UpdateBalance(data.UserID, data.Amount)
+	return nil
+}
+
+// checkFreshRates refuses to move money between the currencies at rates that missed their updates, e.g. the
+// static or cached ones, the rates are not needed for the same currency.
+func (s *Service) checkFreshRates(from, to string) error {
+	if from != to && s.converter.Snapshot().Stale(time.Now(), constants.DefaultMaxRateAge) {
+		return createdErrors.ErrStaleRates
+	}
+
+	return nil
+}
+
+// convert returns the amount converted at the rate to a currency of a wallet, it is rounded half to even to
+// the minor units like converted balances.
+func convert(amount money.Money, rate float64) money.Money {
+	return money.FromMinor(amount.Convert(rate, money.Precision).Minor)
+}
+
+// exchangeRate returns the current amount of the currency to for one unit of the currency from.
+func (s *Service) exchangeRate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, err := s.converter.Get(from)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	toRate, err := s.converter.Get(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return toRate / fromRate, nil
+}
+
+// actualRate returns the actual amount of the currency to for one unit of the currency from with the date
+// of the rates. An unknown currency is reported with the list of supported ones.
+func (s *Service) actualRate(from, to string) (*currency.Rate, error) {
+	rates := make([]*currency.Rate, 0, 2)
+	for _, code := range []string{from, to} {
+		rate, err := s.converter.GetRate(code)
+		if errors.Is(err, createdErrors.ErrNotSupportedCurrency) {
+			return nil, &createdErrors.NotSupportedCurrencyError{Currency: code, Supported: s.converter.Supported()}
+		}
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
 	}
 
-	return &models.UserData{UserID: data.UserID, Balance: newBalance}, nil
+	return &currency.Rate{Value: rates[1].Value / rates[0].Value, Date: rates[1].Date}, nil
 }
@@ -1,55 +1,232 @@
 package usecase
 
 import (
-	converterMock "avito-tech-task/internal/pkg/currency/mock"
+	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	storageMock "avito-tech-task/internal/app/balance/mock"
 	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	"avito-tech-task/internal/pkg/currency"
+	converterMock "avito-tech-task/internal/pkg/currency/mock"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
 )
 
+// rates of currencies for one ruble
+var rates = map[string]float64{"RUB": 1, "USD": 0.5, "EUR": 0.25, "JPY": 1.5}
+
+func getRate(currency string) (float64, error) {
+	rate, ok := rates[currency]
+	if !ok {
+		return 0, createdErrors.ErrNotSupportedCurrency
+	}
+	return rate, nil
+}
+
+// newMetricsMock accepts all records of metrics, they are checked by the calls of the mock.
+func newMetricsMock() *storageMock.MockMetrics {
+	return &storageMock.MockMetrics{
+		TransactionCommittedFunc: func(operationType string, currency string, amount money.Money) {},
+		InsufficientFundsFunc:    func(operationType string) {},
+	}
+}
+
+// freshRates returns rates fetched an hour ago, they can be used to move money.
+func freshRates() *currency.Snapshot {
+	return &currency.Snapshot{Source: currency.CBRJSON, Fetched: time.Now().Add(-time.Hour)}
+}
+
+// staleRates returns static rates, they are never fetched and are always stale.
+func staleRates() *currency.Snapshot {
+	return &currency.Snapshot{Source: currency.Static}
+}
+
+func getActualRate(code string) (*currency.Rate, error) {
+	rate, err := getRate(code)
+	if err != nil {
+		return nil, err
+	}
+	return &currency.Rate{Value: rate, Date: time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC)}, nil
+}
+
 func TestService_GetBalance(t *testing.T) {
 	storageError := errors.New("Storage error")
-	converterError := errors.New("Unsupported currency")
+	converter := &converterMock.MockConverterIface{
+		GetRateFunc: getActualRate,
+		SupportedFunc: func() []string {
+			return []string{"EUR", "JPY", "RUB", "USD"}
+		},
+		SnapshotFunc: func() *currency.Snapshot {
+			return &currency.Snapshot{Fetched: time.Now().Add(-time.Hour)}
+		},
+	}
+	userData := &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
+		return &models.UserData{UserID: 1, Balance: 1000}, nil
+	}}
 
 	tests := []struct {
-		name          string
-		userID        int64
-		currency      string
-		storageMock   *storageMock.MockStorage
-		converterMock *converterMock.MockConverterIface
-		expected      *models.UserData
-		expectedErr   bool
-		err           error
+		name        string
+		userID      int64
+		wallet      string
+		currency    string
+		maxRateAge  time.Duration
+		storageMock *storageMock.MockStorage
+		expected    *models.Balance
+		expectedErr bool
+		err         error
 	}{
 		{
 			name:     "Successfully got user balance",
 			userID:   1,
 			currency: "USD",
-			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(n int64) (*models.UserData, error) {
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
+				if s != "RUB" {
+					return nil, storageError
+				}
+				return &models.UserData{
+					UserID:   1,
+					Balance:  1000,
+					Reserved: 200,
+				}, nil
+			}},
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "RUB",
+				Balance:  money.Amount{Minor: 500, Digits: 2},
+				Reserved: money.Amount{Minor: 100, Digits: 2},
+				Currency: "USD",
+				Rate:     0.5,
+				RateDate: "2022-01-19",
+			},
+		},
+		{
+			name:   "Balance of the wallet is not converted by default",
+			userID: 1,
+			wallet: "USD",
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
+				if s != "USD" {
+					return nil, storageError
+				}
 				return &models.UserData{
 					UserID:  1,
 					Balance: 1000,
 				}, nil
 			}},
-			converterMock: &converterMock.MockConverterIface{GetFunc: func(s string) (float64, error) {
-				return 0.5, nil
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "USD",
+				Balance:  money.Amount{Minor: 1000, Digits: 2},
+				Reserved: money.Amount{Digits: 2},
+				Currency: "USD",
+				Rate:     1,
+				RateDate: "2022-01-19",
+			},
+		},
+		{
+			name:     "Lowercase codes are accepted",
+			userID:   1,
+			wallet:   "usd",
+			currency: "eur",
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
+				if s != "USD" {
+					return nil, storageError
+				}
+				return &models.UserData{
+					UserID:  1,
+					Balance: 1000,
+				}, nil
 			}},
-			expected: &models.UserData{
-				UserID:  1,
-				Balance: 500,
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "USD",
+				Balance:  money.Amount{Minor: 500, Digits: 2},
+				Reserved: money.Amount{Digits: 2},
+				Currency: "EUR",
+				Rate:     0.5,
+				RateDate: "2022-01-19",
+			},
+		},
+		{
+			name:     "Balance is rounded half to even to minor units of the currency",
+			userID:   1,
+			currency: "JPY",
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
+				return &models.UserData{
+					UserID:   1,
+					Balance:  300,
+					Reserved: 100,
+				}, nil
+			}},
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "RUB",
+				Balance:  money.Amount{Minor: 4},
+				Reserved: money.Amount{Minor: 2},
+				Currency: "JPY",
+				Rate:     1.5,
+				RateDate: "2022-01-19",
 			},
 		},
+		{
+			name:        "Rates are fresh enough",
+			userID:      1,
+			currency:    "USD",
+			maxRateAge:  2 * time.Hour,
+			storageMock: userData,
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "RUB",
+				Balance:  money.Amount{Minor: 500, Digits: 2},
+				Reserved: money.Amount{Digits: 2},
+				Currency: "USD",
+				Rate:     0.5,
+				RateDate: "2022-01-19",
+			},
+		},
+		{
+			name:        "Rates are older than the max rate age",
+			userID:      1,
+			currency:    "USD",
+			maxRateAge:  30 * time.Minute,
+			storageMock: userData,
+			expectedErr: true,
+			err:         createdErrors.ErrStaleRates,
+		},
+		{
+			name:        "Balance which is not converted does not depend on age of rates",
+			userID:      1,
+			maxRateAge:  30 * time.Minute,
+			storageMock: userData,
+			expected: &models.Balance{
+				UserID:   1,
+				Wallet:   "RUB",
+				Balance:  money.Amount{Minor: 1000, Digits: 2},
+				Reserved: money.Amount{Digits: 2},
+				Currency: "RUB",
+				Rate:     1,
+				RateDate: "2022-01-19",
+			},
+		},
+		{
+			name:        "Negative max rate age",
+			userID:      1,
+			maxRateAge:  -time.Minute,
+			storageMock: userData,
+			expectedErr: true,
+			err:         createdErrors.ErrNegativeMaxRateAge,
+		},
 		{
 			name:     "Error occurred in storage",
 			userID:   1,
 			currency: "USD",
-			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(n int64) (*models.UserData, error) {
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
 				return nil, storageError
 			}},
 			expectedErr: true,
@@ -59,26 +236,33 @@ func TestService_GetBalance(t *testing.T) {
 			name:     "No user data returned from storage",
 			userID:   1,
 			currency: "USD",
-			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(n int64) (*models.UserData, error) {
+			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(ctx context.Context, n int64, s string) (*models.UserData, error) {
 				return nil, createdErrors.ErrUserDoesNotExist
 			}},
 			expectedErr: true,
 			err:         createdErrors.ErrUserDoesNotExist,
 		},
 		{
-			name:   "Unsupported currency",
-			userID: 1,
-			storageMock: &storageMock.MockStorage{GetUserDataFunc: func(n int64) (*models.UserData, error) {
-				return &models.UserData{
-					UserID:  1,
-					Balance: 1000,
-				}, nil
-			}},
-			converterMock: &converterMock.MockConverterIface{GetFunc: func(s string) (float64, error) {
-				return 0, converterError
-			}},
+			name:        "Unsupported currency",
+			userID:      1,
+			currency:    "gbp",
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err: &createdErrors.NotSupportedCurrencyError{
+				Currency:  "GBP",
+				Supported: []string{"EUR", "JPY", "RUB", "USD"},
+			},
+		},
+		{
+			name:        "Unsupported wallet",
+			userID:      1,
+			wallet:      "ABC",
+			storageMock: &storageMock.MockStorage{},
 			expectedErr: true,
-			err:         converterError,
+			err: &createdErrors.NotSupportedCurrencyError{
+				Currency:  "ABC",
+				Supported: []string{"EUR", "JPY", "RUB", "USD"},
+			},
 		},
 	}
 
@@ -86,9 +270,9 @@ func TestService_GetBalance(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			validator := utils.NewValidator()
-			service := NewService(test.storageMock, validator, test.converterMock)
+			service := NewService(test.storageMock, validator, converter, newMetricsMock(), 0, 0)
 
-			got, err := service.GetBalance(test.userID, test.currency)
+			got, err := service.GetBalance(context.Background(), test.userID, test.wallet, test.currency, test.maxRateAge)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -105,12 +289,14 @@ func TestService_UpdateBalance(t *testing.T) {
 	storageError := errors.New("Error in storage")
 
 	tests := []struct {
-		name        string
-		data        *models.RequestUpdateBalance
-		storageMock *storageMock.MockStorage
-		expected    *models.UserData
-		expectedErr bool
-		err         error
+		name           string
+		data           *models.RequestUpdateBalance
+		storageMock    *storageMock.MockStorage
+		converterMock  *converterMock.MockConverterIface
+		expected       *models.UserData
+		expectedAmount money.Money
+		expectedErr    bool
+		err            error
 	}{
 		{
 			name: "Successfully updated balance",
@@ -120,92 +306,113 @@ func TestService_UpdateBalance(t *testing.T) {
 				Amount:        1000,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return &models.UserData{
-						UserID:  1,
-						Balance: 1000,
-					}, nil
-				},
-				UpdateBalanceFunc: func(n int64, f float64) (float64, error) {
+				UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
 					return 2000, nil
 				},
 			},
 			expected: &models.UserData{
-				UserID:  1,
-				Balance: 2000,
+				UserID:   1,
+				Balance:  2000,
+				Currency: "RUB",
 			},
+			expectedAmount: 1000,
 		},
 		{
-			name: "Error in storage, GetUserData",
+			name: "Successfully wrote off money",
 			data: &models.RequestUpdateBalance{
 				UserID:        1,
-				OperationType: 1,
+				OperationType: 2,
 				Amount:        1000,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return nil, storageError
+				UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
+					return 500, nil
 				},
 			},
-			expectedErr: true,
-			err:         storageError,
+			expected: &models.UserData{
+				UserID:   1,
+				Balance:  500,
+				Currency: "RUB",
+			},
+			expectedAmount: -1000,
 		},
 		{
-			name: "Error in storage, CreateAccount",
+			name: "Successfully topped up the wallet in another currency",
 			data: &models.RequestUpdateBalance{
 				UserID:        1,
 				OperationType: 1,
 				Amount:        1000,
+				Currency:      "USD",
 			},
 			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return nil, createdErrors.ErrUserDoesNotExist
-				},
-				CreateAccountFunc: func(n int64) error {
-					return storageError
+				UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
+					if s != "USD" {
+						return 0, storageError
+					}
+					return 1000, nil
 				},
 			},
-			expectedErr: true,
-			err:         storageError,
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expected: &models.UserData{
+				UserID:   1,
+				Balance:  1000,
+				Currency: "USD",
+			},
+			expectedAmount: 1000,
 		},
 		{
-			name: "Error in storage, UpdateBalance",
+			name: "Currency without rate",
 			data: &models.RequestUpdateBalance{
 				UserID:        1,
-				OperationType: 2,
+				OperationType: 1,
 				Amount:        1000,
+				Currency:      "GBP",
 			},
-			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return &models.UserData{
-						UserID:  1,
-						Balance: 1500,
-					}, nil
-				},
-				UpdateBalanceFunc: func(n int64, f float64) (float64, error) {
-					return 0, storageError
-				},
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrNotSupportedCurrency,
+		},
+		{
+			name: "Wallet in currency without two minor unit digits",
+			data: &models.RequestUpdateBalance{
+				UserID:        1,
+				OperationType: 1,
+				Amount:        1000,
+				Currency:      "JPY",
+			},
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrNotSupportedWallet,
+		},
+		{
+			name: "Invalid currency code",
+			data: &models.RequestUpdateBalance{
+				UserID:        1,
+				OperationType: 1,
+				Amount:        1000,
+				Currency:      "RUBLES",
 			},
+			storageMock: &storageMock.MockStorage{},
 			expectedErr: true,
-			err:         storageError,
+			err:         createdErrors.ErrNotSupportedCurrency,
 		},
 		{
-			name: "Write off money from created account with zero balance",
+			name: "Error in storage, UpdateBalance",
 			data: &models.RequestUpdateBalance{
 				UserID:        1,
 				OperationType: 2,
 				Amount:        1000,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return nil, createdErrors.ErrUserDoesNotExist
-				},
-				CreateAccountFunc: func(n int64) error {
-					return nil
+				UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
+					return 0, storageError
 				},
 			},
-			expectedErr: true,
-			err:         createdErrors.ErrNotEnoughMoney,
+			expectedAmount: -1000,
+			expectedErr:    true,
+			err:            storageError,
 		},
 		{
 			name: "Not enough money to write off",
@@ -215,15 +422,13 @@ func TestService_UpdateBalance(t *testing.T) {
 				Amount:        1000,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetUserDataFunc: func(n int64) (*models.UserData, error) {
-					return &models.UserData{
-						UserID:  1,
-						Balance: 500,
-					}, nil
+				UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
+					return 0, createdErrors.ErrNotEnoughMoney
 				},
 			},
-			expectedErr: true,
-			err:         createdErrors.ErrNotEnoughMoney,
+			expectedAmount: -1000,
+			expectedErr:    true,
+			err:            createdErrors.ErrNotEnoughMoney,
 		},
 		{
 			name: "Negative user ID",
@@ -232,6 +437,7 @@ func TestService_UpdateBalance(t *testing.T) {
 				OperationType: 1,
 				Amount:        1000,
 			},
+			storageMock: &storageMock.MockStorage{},
 			expectedErr: true,
 			err:         createdErrors.ErrNegativeUserID,
 		},
@@ -242,6 +448,7 @@ func TestService_UpdateBalance(t *testing.T) {
 				OperationType: 10,
 				Amount:        1000,
 			},
+			storageMock: &storageMock.MockStorage{},
 			expectedErr: true,
 			err:         createdErrors.ErrNotSupportedOperationType,
 		},
@@ -251,18 +458,54 @@ func TestService_UpdateBalance(t *testing.T) {
 				UserID:        1,
 				OperationType: 2,
 			},
+			storageMock: &storageMock.MockStorage{},
 			expectedErr: true,
 			err:         createdErrors.ErrAmountFiledIsRequired,
 		},
+		{
+			name: "Negative amount of write off",
+			data: &models.RequestUpdateBalance{
+				UserID:        1,
+				OperationType: 2,
+				Amount:        -1000,
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
+		{
+			name: "Too long reason",
+			data: &models.RequestUpdateBalance{
+				UserID:        1,
+				OperationType: 1,
+				Amount:        1000,
+				Purpose:       models.Purpose{Reason: strings.Repeat("a", 65)},
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidPurpose,
+		},
+		{
+			name: "Negative order ID in source",
+			data: &models.RequestUpdateBalance{
+				UserID:        1,
+				OperationType: 2,
+				Amount:        1000,
+				Purpose:       models.Purpose{Source: &models.Source{OrderID: -1}},
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidPurpose,
+		},
 	}
 
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			validator := utils.NewValidator()
-			service := NewService(test.storageMock, validator, nil)
+			service := NewService(test.storageMock, validator, test.converterMock, newMetricsMock(), 0, 0)
 
-			got, err := service.UpdateBalance(test.data)
+			got, err := service.UpdateBalance(context.Background(), test.data)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -271,6 +514,9 @@ func TestService_UpdateBalance(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expected, got)
 			}
+			if test.expectedAmount != 0 {
+				assert.Equal(t, test.expectedAmount, test.storageMock.UpdateBalanceCalls()[0].MoneyMoqParam)
+			}
 		})
 	}
 }
@@ -279,12 +525,13 @@ func TestService_MakeTransfer(t *testing.T) {
 	storageError := errors.New("Error in storage")
 
 	tests := []struct {
-		name        string
-		data        *models.TransferRequest
-		storageMock *storageMock.MockStorage
-		expected    *models.TransferUsersData
-		expectedErr bool
-		err         error
+		name          string
+		data          *models.TransferRequest
+		storageMock   *storageMock.MockStorage
+		converterMock *converterMock.MockConverterIface
+		expected      *models.TransferUsersData
+		expectedErr   bool
+		err           error
 	}{
 		{
 			name: "Successfully transferred money",
@@ -294,21 +541,18 @@ func TestService_MakeTransfer(t *testing.T) {
 				Amount:     500,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
 					return &models.TransferUsersData{
 						Sender: &models.UserData{
 							UserID:  1,
-							Balance: 1000,
+							Balance: 500,
 						},
 						Receiver: &models.UserData{
 							UserID:  2,
-							Balance: 1000,
+							Balance: 1500,
 						},
 					}, nil
 				},
-				MakeTransferFunc: func(n1 int64, n2 int64, f float64) error {
-					return nil
-				},
 			},
 			expected: &models.TransferUsersData{
 				Sender: &models.UserData{
@@ -322,88 +566,118 @@ func TestService_MakeTransfer(t *testing.T) {
 			},
 		},
 		{
-			name: "Error in storage, GetTransferUsersData",
+			name: "Transfer to the wallet in another currency is converted",
 			data: &models.TransferRequest{
-				SenderID:   1,
-				ReceiverID: 2,
-				Amount:     500,
+				SenderID:         1,
+				ReceiverID:       2,
+				Amount:           500,
+				ReceiverCurrency: "USD",
 			},
 			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-					return nil, storageError
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
+					if transferRequest.Currency != "RUB" || m != 250 || f != 0.5 {
+						return nil, storageError
+					}
+					return &models.TransferUsersData{
+						Sender:   &models.UserData{UserID: 1, Balance: 500, Currency: "RUB"},
+						Receiver: &models.UserData{UserID: 2, Balance: 250, Currency: "USD"},
+					}, nil
 				},
 			},
-			expectedErr: true,
-			err:         storageError,
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expected: &models.TransferUsersData{
+				Sender:   &models.UserData{UserID: 1, Balance: 500, Currency: "RUB"},
+				Receiver: &models.UserData{UserID: 2, Balance: 250, Currency: "USD"},
+			},
 		},
 		{
-			name: "Error in storage, MakeTransfer",
+			name: "Transfer to the wallet in another currency is refused at stale rates",
+			data: &models.TransferRequest{
+				SenderID:         1,
+				ReceiverID:       2,
+				Amount:           500,
+				ReceiverCurrency: "USD",
+			},
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: staleRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrStaleRates,
+		},
+		{
+			name: "Transfer in the same currency does not depend on age of rates",
 			data: &models.TransferRequest{
 				SenderID:   1,
 				ReceiverID: 2,
 				Amount:     500,
+				Currency:   "USD",
 			},
 			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
 					return &models.TransferUsersData{
-						Sender: &models.UserData{
-							UserID:  1,
-							Balance: 1000,
-						},
-						Receiver: &models.UserData{
-							UserID:  2,
-							Balance: 1000,
-						},
+						Sender:   &models.UserData{UserID: 1, Balance: 500, Currency: "USD"},
+						Receiver: &models.UserData{UserID: 2, Balance: 500, Currency: "USD"},
 					}, nil
 				},
-				MakeTransferFunc: func(n1 int64, n2 int64, f float64) error {
-					return storageError
-				},
 			},
-			expectedErr: true,
-			err:         storageError,
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: staleRates},
+			expected: &models.TransferUsersData{
+				Sender:   &models.UserData{UserID: 1, Balance: 500, Currency: "USD"},
+				Receiver: &models.UserData{UserID: 2, Balance: 500, Currency: "USD"},
+			},
 		},
 		{
-			name: "Sender does not exist",
+			name: "Amount is too small to convert",
 			data: &models.TransferRequest{
-				SenderID:   1,
-				ReceiverID: 2,
-				Amount:     500,
+				SenderID:         1,
+				ReceiverID:       2,
+				Amount:           1,
+				ReceiverCurrency: "EUR",
 			},
-			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-					return &models.TransferUsersData{
-						Sender: nil,
-						Receiver: &models.UserData{
-							UserID:  2,
-							Balance: 1000,
-						},
-					}, nil
-				},
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrAmountTooSmallToConvert,
+		},
+		{
+			name: "Receiver currency without rate",
+			data: &models.TransferRequest{
+				SenderID:         1,
+				ReceiverID:       2,
+				Amount:           500,
+				ReceiverCurrency: "GBP",
 			},
-			expectedErr: true,
-			err:         createdErrors.ErrSenderDoesNotExist,
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrNotSupportedCurrency,
 		},
 		{
-			name: "Receiver does not exist",
+			name: "Receiver currency without two minor unit digits",
+			data: &models.TransferRequest{
+				SenderID:         1,
+				ReceiverID:       2,
+				Amount:           500,
+				ReceiverCurrency: "JPY",
+			},
+			storageMock:   &storageMock.MockStorage{},
+			converterMock: &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates},
+			expectedErr:   true,
+			err:           createdErrors.ErrNotSupportedWallet,
+		},
+		{
+			name: "Error in storage, MakeTransfer",
 			data: &models.TransferRequest{
 				SenderID:   1,
 				ReceiverID: 2,
 				Amount:     500,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-					return &models.TransferUsersData{
-						Sender: &models.UserData{
-							UserID:  1,
-							Balance: 1000,
-						},
-						Receiver: nil,
-					}, nil
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
+					return nil, storageError
 				},
 			},
 			expectedErr: true,
-			err:         createdErrors.ErrReceiverDoesNotExist,
+			err:         storageError,
 		},
 		{
 			name: "Not enough money",
@@ -413,22 +687,24 @@ func TestService_MakeTransfer(t *testing.T) {
 				Amount:     2000,
 			},
 			storageMock: &storageMock.MockStorage{
-				GetTransferUsersDataFunc: func(n1 int64, n2 int64) (*models.TransferUsersData, error) {
-					return &models.TransferUsersData{
-						Sender: &models.UserData{
-							UserID:  1,
-							Balance: 1000,
-						},
-						Receiver: &models.UserData{
-							UserID:  2,
-							Balance: 1000,
-						},
-					}, nil
+				MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
+					return nil, createdErrors.ErrNotEnoughMoney
 				},
 			},
 			expectedErr: true,
 			err:         createdErrors.ErrNotEnoughMoney,
 		},
+		{
+			name: "Transfer to the same user",
+			data: &models.TransferRequest{
+				SenderID:   1,
+				ReceiverID: 1,
+				Amount:     2000,
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrTransferToSelf,
+		},
 		{
 			name: "Sender ID field is required",
 			data: &models.TransferRequest{
@@ -459,15 +735,26 @@ func TestService_MakeTransfer(t *testing.T) {
 			expectedErr: true,
 			err:         createdErrors.ErrAmountFiledIsRequired,
 		},
+		{
+			name: "Negative amount",
+			data: &models.TransferRequest{
+				SenderID:   1,
+				ReceiverID: 2,
+				Amount:     -1000,
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
 	}
 
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			validator := utils.NewValidator()
-			service := NewService(test.storageMock, validator, nil)
+			service := NewService(test.storageMock, validator, test.converterMock, newMetricsMock(), 0, 0)
 
-			got, err := service.MakeTransfer(test.data)
+			got, err := service.MakeTransfer(context.Background(), test.data)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -479,3 +766,320 @@ func TestService_MakeTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateQuote(t *testing.T) {
+	storageError := errors.New("Error in storage")
+
+	tests := []struct {
+		name        string
+		data        *models.ExchangeQuoteRequest
+		storageMock *storageMock.MockStorage
+		// snapshot returns the rates of the converter, they are fresh by default
+		snapshot    func() *currency.Snapshot
+		expected    *models.ExchangeQuote
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Quote at the current rate with the spread",
+			data: &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "USD"},
+			storageMock: &storageMock.MockStorage{
+				SaveQuoteFunc: func(ctx context.Context, quote *models.ExchangeQuote) (*models.ExchangeQuote, error) {
+					if time.Until(quote.Expires) <= 0 || time.Until(quote.Expires) > time.Minute {
+						return nil, storageError
+					}
+					quote.ID = "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
+					quote.Expires = time.Time{}
+					return quote, nil
+				},
+			},
+			expected: &models.ExchangeQuote{
+				ID:     "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e",
+				UserID: 1,
+				From:   "RUB",
+				To:     "USD",
+				Rate:   0.45,
+			},
+		},
+		{
+			name:        "Exchange to the same currency",
+			data:        &models.ExchangeQuoteRequest{UserID: 1, From: "USD", To: "USD"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrExchangeSameCurrency,
+		},
+		{
+			name:        "Currency without rate",
+			data:        &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "GBP"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedCurrency,
+		},
+		{
+			name:        "Currency without two minor unit digits",
+			data:        &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "JPY"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedWallet,
+		},
+		{
+			name:        "Invalid currency code",
+			data:        &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "ABC"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedCurrency,
+		},
+		{
+			name:        "Stale rates",
+			data:        &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "USD"},
+			storageMock: &storageMock.MockStorage{},
+			snapshot:    staleRates,
+			expectedErr: true,
+			err:         createdErrors.ErrStaleRates,
+		},
+		{
+			name:        "Negative user ID",
+			data:        &models.ExchangeQuoteRequest{UserID: -1, From: "RUB", To: "USD"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrNegativeUserID,
+		},
+		{
+			name: "Error in storage, SaveQuote",
+			data: &models.ExchangeQuoteRequest{UserID: 1, From: "RUB", To: "USD"},
+			storageMock: &storageMock.MockStorage{
+				SaveQuoteFunc: func(ctx context.Context, quote *models.ExchangeQuote) (*models.ExchangeQuote, error) {
+					return nil, storageError
+				},
+			},
+			expectedErr: true,
+			err:         storageError,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			validator := utils.NewValidator()
+			converter := &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates}
+			if test.snapshot != nil {
+				converter.SnapshotFunc = test.snapshot
+			}
+			service := NewService(test.storageMock, validator, converter, newMetricsMock(), 0.1, 0)
+
+			got, err := service.CreateQuote(context.Background(), test.data)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_Exchange(t *testing.T) {
+	storageError := errors.New("Error in storage")
+	quoteID := "0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"
+	exchange := func(ctx context.Context, data *models.ExchangeRequest, received money.Money,
+		rate float64) (*models.ExchangeResult, error) {
+		return &models.ExchangeResult{Amount: data.Amount, Received: received, Rate: rate}, nil
+	}
+	getQuote := func(ctx context.Context, id string, userID int64) (*models.ExchangeQuote, error) {
+		return &models.ExchangeQuote{ID: id, UserID: userID, From: "RUB", To: "USD", Rate: 0.48}, nil
+	}
+
+	tests := []struct {
+		name        string
+		data        *models.ExchangeRequest
+		storageMock *storageMock.MockStorage
+		// snapshot returns the rates of the converter, they are fresh by default
+		snapshot    func() *currency.Snapshot
+		expected    *models.ExchangeResult
+		expectedErr bool
+		err         error
+	}{
+		{
+			name:        "Exchange at the current rate with the spread",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000},
+			storageMock: &storageMock.MockStorage{ExchangeFunc: exchange},
+			expected:    &models.ExchangeResult{Amount: 1000, Received: 450, Rate: 0.45},
+		},
+		{
+			name: "Exchange at the rate of the quote",
+			data: &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000, QuoteID: quoteID},
+			storageMock: &storageMock.MockStorage{
+				GetQuoteFunc: getQuote,
+				ExchangeFunc: exchange,
+			},
+			expected: &models.ExchangeResult{Amount: 1000, Received: 480, Rate: 0.48},
+		},
+		{
+			name:        "Exchange at the current rate is refused at stale rates",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000},
+			storageMock: &storageMock.MockStorage{},
+			snapshot:    staleRates,
+			expectedErr: true,
+			err:         createdErrors.ErrStaleRates,
+		},
+		{
+			name: "Exchange at the rate of the quote does not depend on age of rates",
+			data: &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000, QuoteID: quoteID},
+			storageMock: &storageMock.MockStorage{
+				GetQuoteFunc: getQuote,
+				ExchangeFunc: exchange,
+			},
+			snapshot: staleRates,
+			expected: &models.ExchangeResult{Amount: 1000, Received: 480, Rate: 0.48},
+		},
+		{
+			name:        "Received amount is rounded half to even",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "EUR", Amount: 20},
+			storageMock: &storageMock.MockStorage{ExchangeFunc: exchange},
+			expected:    &models.ExchangeResult{Amount: 20, Received: 4, Rate: 0.225},
+		},
+		{
+			name:        "Currency without two minor unit digits",
+			data:        &models.ExchangeRequest{UserID: 1, From: "JPY", To: "RUB", Amount: 1000},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedWallet,
+		},
+		{
+			name:        "Quote for other currencies",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "EUR", Amount: 1000, QuoteID: quoteID},
+			storageMock: &storageMock.MockStorage{GetQuoteFunc: getQuote},
+			expectedErr: true,
+			err:         createdErrors.ErrQuoteMismatch,
+		},
+		{
+			name: "Quote has expired",
+			data: &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000, QuoteID: quoteID},
+			storageMock: &storageMock.MockStorage{
+				GetQuoteFunc: func(ctx context.Context, id string, userID int64) (*models.ExchangeQuote, error) {
+					return nil, createdErrors.ErrQuoteDoesNotExist
+				},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrQuoteDoesNotExist,
+		},
+		{
+			name:        "Invalid quote ID",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000, QuoteID: "quote"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrQuoteDoesNotExist,
+		},
+		{
+			name:        "Exchange to the same currency",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "RUB", Amount: 1000},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrExchangeSameCurrency,
+		},
+		{
+			name:        "Amount is too small to convert",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "EUR", Amount: 1},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountTooSmallToConvert,
+		},
+		{
+			name:        "Amount field is required",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD"},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
+		{
+			name:        "Negative amount",
+			data:        &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: -1000},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
+		{
+			name: "Error in storage, Exchange",
+			data: &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000},
+			storageMock: &storageMock.MockStorage{
+				ExchangeFunc: func(ctx context.Context, data *models.ExchangeRequest, received money.Money,
+					rate float64) (*models.ExchangeResult, error) {
+					return nil, storageError
+				},
+			},
+			expectedErr: true,
+			err:         storageError,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			validator := utils.NewValidator()
+			converter := &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates}
+			if test.snapshot != nil {
+				converter.SnapshotFunc = test.snapshot
+			}
+			service := NewService(test.storageMock, validator, converter, newMetricsMock(), 0.1, 0)
+
+			got, err := service.Exchange(context.Background(), test.data)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_Metrics(t *testing.T) {
+	converter := &converterMock.MockConverterIface{GetFunc: getRate, SnapshotFunc: freshRates}
+	storage := &storageMock.MockStorage{
+		UpdateBalanceFunc: func(ctx context.Context, n int64, s string, m money.Money, purpose *models.Purpose) (money.Money, error) {
+			if m < 0 {
+				return 0, createdErrors.ErrNotEnoughMoney
+			}
+			return m, nil
+		},
+		MakeTransferFunc: func(ctx context.Context, transferRequest *models.TransferRequest, m money.Money, f float64) (*models.TransferUsersData, error) {
+			return &models.TransferUsersData{}, nil
+		},
+		ExchangeFunc: func(ctx context.Context, data *models.ExchangeRequest, received money.Money, rate float64) (*models.ExchangeResult, error) {
+			return nil, createdErrors.ErrNotEnoughMoney
+		},
+	}
+	metrics := newMetricsMock()
+	service := NewService(storage, utils.NewValidator(), converter, metrics, 0, 0)
+	ctx := context.Background()
+
+	_, err := service.UpdateBalance(ctx, &models.RequestUpdateBalance{UserID: 1, OperationType: constants.ADD,
+		Amount: 1000, Currency: "USD"})
+	assert.NoError(t, err)
+	_, err = service.UpdateBalance(ctx, &models.RequestUpdateBalance{UserID: 1, OperationType: constants.REDUCE,
+		Amount: 300})
+	assert.ErrorIs(t, err, createdErrors.ErrNotEnoughMoney)
+	_, err = service.MakeTransfer(ctx, &models.TransferRequest{SenderID: 1, ReceiverID: 2, Amount: 500})
+	assert.NoError(t, err)
+	_, err = service.Exchange(ctx, &models.ExchangeRequest{UserID: 1, From: "RUB", To: "USD", Amount: 1000})
+	assert.ErrorIs(t, err, createdErrors.ErrNotEnoughMoney)
+
+	committed := metrics.TransactionCommittedCalls()
+	if assert.Len(t, committed, 2) {
+		assert.Equal(t, "add", committed[0].OperationType)
+		assert.Equal(t, "USD", committed[0].Currency)
+		assert.Equal(t, money.Money(1000), committed[0].Amount)
+		assert.Equal(t, "transfer", committed[1].OperationType)
+		assert.Equal(t, "RUB", committed[1].Currency)
+		assert.Equal(t, money.Money(500), committed[1].Amount)
+	}
+	insufficient := metrics.InsufficientFundsCalls()
+	if assert.Len(t, insufficient, 2) {
+		assert.Equal(t, "write_off", insufficient[0].OperationType)
+		assert.Equal(t, "exchange", insufficient[1].OperationType)
+	}
+}
@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"avito-tech-task/internal/app/currencies"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Handlers struct {
+	service currencies.Service
+}
+
+func NewHandlers(service currencies.Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+// InitHandlers registers currency routes, admin middleware guards the refresh of rates.
+func (h *Handlers) InitHandlers(server *echo.Echo, admin echo.MiddlewareFunc) {
+	server.GET("/api/v1/currencies", h.GetCurrencies)
+	server.POST("/api/v1/currencies/refresh", h.RefreshCurrencies, admin)
+}
+
+// GetCurrencies
+// @Summary 	Get supported currencies with actual rates
+// @Produce 	json
+// @Param 		max_age query string false "Rates fetched earlier are reported as stale, e.g. 1h30m, 48h by default"
+// @Success 	200 {object} models.CurrencyRates
+// @Failure		400 {object} models.Problem "Invalid query params"
+// @Failure		422 {object} models.Problem "Negative max age"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/currencies [GET]
+func (h *Handlers) GetCurrencies(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	var maxAge time.Duration
+	if param := ctx.QueryParam("max_age"); param != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(param); err != nil {
+			log.WithError(err).Warn("Could not parse max age")
+			return fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, err)
+		}
+	}
+	log.WithField("max_age", maxAge.String()).Debug("Request data")
+
+	rates, err := h.service.GetRates(ctx.Request().Context(), maxAge)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("currencies", len(rates.Rates)).Debug("Request was processed")
+	return ctx.JSON(http.StatusOK, rates)
+}
+
+// RefreshCurrencies
+// @Summary 	Get actual rates from providers right away
+// @Produce 	json
+// @Param 		Authorization header string true "Bearer admin token"
+// @Success 	200 {object} models.CurrencyRates
+// @Failure		401 {object} models.Problem "Invalid admin token"
+// @Failure		403 {object} models.Problem "Admin endpoints are disabled"
+// @Failure		502 {object} models.Problem "Rates are unavailable from all providers, the current rates are kept"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/currencies/refresh [POST]
+func (h *Handlers) RefreshCurrencies(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	rates, err := h.service.Refresh(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(logrus.Fields{"source": rates.Source, "currencies": len(rates.Rates)}).Info("Rates were refreshed")
+	return ctx.JSON(http.StatusOK, rates)
+}
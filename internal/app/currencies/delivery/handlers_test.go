@@ -0,0 +1,227 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/app/currencies/mock"
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+// requestID is the correlation ID of internal errors in responses of tests
+const requestID = "test-request-id"
+
+func TestHandlers_GetCurrencies(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	rates := &models.CurrencyRates{
+		Base:  "RUB",
+		Date:  "2022-01-19",
+		Stale: true,
+		Rates: []models.CurrencyRate{{Currency: "RUB", Rate: 1, Digits: 2}, {Currency: "USD", Rate: 0.0131, Digits: 2}},
+	}
+	internalServerErr := errors.New("Internal server error")
+	tests := []struct {
+		name           string
+		serviceMock    *mock.MockService
+		query          string
+		expectedStatus int
+		expected       interface{}
+	}{
+		{
+			name: "Successfully got rates",
+			serviceMock: &mock.MockService{
+				GetRatesFunc: func(ctx context.Context, maxAge time.Duration) (*models.CurrencyRates, error) {
+					if maxAge != time.Hour {
+						return nil, internalServerErr
+					}
+					return rates, nil
+				},
+			},
+			query:          "?max_age=1h",
+			expectedStatus: http.StatusOK,
+			expected:       rates,
+		},
+		{
+			name:           "Invalid max age",
+			query:          "?max_age=day",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `time: invalid duration "day"`), ""),
+		},
+		{
+			name: "Negative max age",
+			serviceMock: &mock.MockService{
+				GetRatesFunc: func(ctx context.Context, maxAge time.Duration) (*models.CurrencyRates, error) {
+					return nil, createdErrors.ErrNegativeMaxRateAge
+				},
+			},
+			query:          "?max_age=-1h",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrNegativeMaxRateAge, ""),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+			req := httptest.NewRequest(echo.GET, "/"+test.query, nil)
+			rec := httptest.NewRecorder()
+			ctx := server.NewContext(req, rec)
+			ctx.SetPath("/api/v1/currencies")
+
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.GetCurrencies)(ctx)) {
+				assert.Equal(t, test.expectedStatus, rec.Code)
+
+				expectedString, _ := json.Marshal(test.expected)
+				assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_RefreshCurrencies(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	rates := &models.CurrencyRates{
+		Base:  "RUB",
+		Date:  "2022-01-19",
+		Rates: []models.CurrencyRate{{Currency: "RUB", Rate: 1, Digits: 2}},
+	}
+	unavailableErr := fmt.Errorf("%w: cbr_json: timeout", createdErrors.ErrRatesUnavailable)
+	tests := []struct {
+		name           string
+		adminToken     string
+		authorization  string
+		serviceMock    *mock.MockService
+		expectedStatus int
+		expected       interface{}
+	}{
+		{
+			name:          "Successfully refreshed rates",
+			adminToken:    "secret",
+			authorization: "Bearer secret",
+			serviceMock: &mock.MockService{
+				RefreshFunc: func(ctx context.Context) (*models.CurrencyRates, error) {
+					return rates, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expected:       rates,
+		},
+		{
+			name:          "All providers failed",
+			adminToken:    "secret",
+			authorization: "Bearer secret",
+			serviceMock: &mock.MockService{
+				RefreshFunc: func(ctx context.Context) (*models.CurrencyRates, error) {
+					return nil, unavailableErr
+				},
+			},
+			expectedStatus: http.StatusBadGateway,
+			expected:       utils.NewProblem(unavailableErr, requestID),
+		},
+		{
+			name:           "Invalid admin token",
+			adminToken:     "secret",
+			authorization:  "Bearer guess",
+			serviceMock:    &mock.MockService{},
+			expectedStatus: http.StatusUnauthorized,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAdminToken, ""),
+		},
+		{
+			name:           "Token without bearer scheme",
+			adminToken:     "secret",
+			authorization:  "secret",
+			serviceMock:    &mock.MockService{},
+			expectedStatus: http.StatusUnauthorized,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAdminToken, ""),
+		},
+		{
+			name:           "Admin token is not configured",
+			authorization:  "Bearer ",
+			serviceMock:    &mock.MockService{},
+			expectedStatus: http.StatusForbidden,
+			expected:       utils.NewProblem(createdErrors.ErrAdminDisabled, ""),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+			server.Use(utils.RequestLogger(logger))
+			handlers := NewHandlers(test.serviceMock)
+			handlers.InitHandlers(server, utils.AdminOnly(test.adminToken))
+
+			req := httptest.NewRequest(echo.POST, "/api/v1/currencies/refresh", nil)
+			req.Header.Set(echo.HeaderAuthorization, test.authorization)
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			expectedString, _ := json.Marshal(test.expected)
+			assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/currencies"
+	"avito-tech-task/internal/app/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// Ensure, that MockService does implement currencies.Service.
+// If this is not the case, regenerate this file with moq.
+var _ currencies.Service = &MockService{}
+
+// MockService is a mock implementation of currencies.Service.
+//
+//	func TestSomethingThatUsesService(t *testing.T) {
+//
+//		// make and configure a mocked currencies.Service
+//		mockedService := &MockService{
+//			GetRatesFunc: func(contextMoqParam context.Context, duration time.Duration) (*models.CurrencyRates, error) {
+//				panic("mock out the GetRates method")
+//			},
+//			RefreshFunc: func(contextMoqParam context.Context) (*models.CurrencyRates, error) {
+//				panic("mock out the Refresh method")
+//			},
+//		}
+//
+//		// use mockedService in code that requires currencies.Service
+//		// and then make assertions.
+//
+//	}
+type MockService struct {
+	// GetRatesFunc mocks the GetRates method.
+	GetRatesFunc func(contextMoqParam context.Context, duration time.Duration) (*models.CurrencyRates, error)
+
+	// RefreshFunc mocks the Refresh method.
+	RefreshFunc func(contextMoqParam context.Context) (*models.CurrencyRates, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetRates holds details about calls to the GetRates method.
+		GetRates []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Duration is the duration argument value.
+			Duration time.Duration
+		}
+		// Refresh holds details about calls to the Refresh method.
+		Refresh []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
+	}
+	lockGetRates sync.RWMutex
+	lockRefresh  sync.RWMutex
+}
+
+// GetRates calls GetRatesFunc.
+func (mock *MockService) GetRates(contextMoqParam context.Context, duration time.Duration) (*models.CurrencyRates, error) {
+	if mock.GetRatesFunc == nil {
+		panic("MockService.GetRatesFunc: method is nil but Service.GetRates was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Duration        time.Duration
+	}{
+		ContextMoqParam: contextMoqParam,
+		Duration:        duration,
+	}
+	mock.lockGetRates.Lock()
+	mock.calls.GetRates = append(mock.calls.GetRates, callInfo)
+	mock.lockGetRates.Unlock()
+	return mock.GetRatesFunc(contextMoqParam, duration)
+}
+
+// GetRatesCalls gets all the calls that were made to GetRates.
+// Check the length with:
+//
+//	len(mockedService.GetRatesCalls())
+func (mock *MockService) GetRatesCalls() []struct {
+	ContextMoqParam context.Context
+	Duration        time.Duration
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Duration        time.Duration
+	}
+	mock.lockGetRates.RLock()
+	calls = mock.calls.GetRates
+	mock.lockGetRates.RUnlock()
+	return calls
+}
+
+// Refresh calls RefreshFunc.
+func (mock *MockService) Refresh(contextMoqParam context.Context) (*models.CurrencyRates, error) {
+	if mock.RefreshFunc == nil {
+		panic("MockService.RefreshFunc: method is nil but Service.Refresh was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockRefresh.Lock()
+	mock.calls.Refresh = append(mock.calls.Refresh, callInfo)
+	mock.lockRefresh.Unlock()
+	return mock.RefreshFunc(contextMoqParam)
+}
+
+// RefreshCalls gets all the calls that were made to Refresh.
+// Check the length with:
+//
+//	len(mockedService.RefreshCalls())
+func (mock *MockService) RefreshCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockRefresh.RLock()
+	calls = mock.calls.Refresh
+	mock.lockRefresh.RUnlock()
+	return calls
+}
@@ -0,0 +1,14 @@
+package currencies
+
+import (
+	"context"
+	"time"
+
+	"avito-tech-task/internal/app/models"
+)
+
+//go:generate moq -out ./mock/currencies_usecase_mock.go -pkg mock . Service:MockService
+type Service interface {
+	GetRates(context.Context, time.Duration) (*models.CurrencyRates, error)
+	Refresh(context.Context) (*models.CurrencyRates, error)
+}
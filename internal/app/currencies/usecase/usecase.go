@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	"avito-tech-task/internal/pkg/currency"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+const dateLayout = "2006-01-02"
+
+type Service struct {
+	converter currency.ConverterIface
+}
+
+func NewService(converter currency.ConverterIface) *Service {
+	return &Service{
+		converter: converter,
+	}
+}
+
+// GetRates returns the actual rates, they are stale if they are older than maxAge or than the default max age
+// if maxAge is not set.
+func (s *Service) GetRates(ctx context.Context, maxAge time.Duration) (*models.CurrencyRates, error) {
+	if maxAge < 0 {
+		return nil, createdErrors.ErrNegativeMaxRateAge
+	}
+	if maxAge == 0 {
+		maxAge = constants.DefaultMaxRateAge
+	}
+
+	snapshot := s.converter.Snapshot()
+	now := time.Now()
+	rates := &models.CurrencyRates{
+		Base:   snapshot.Base,
+		Date:   snapshot.Date.Format(dateLayout),
+		Source: snapshot.Source,
+		Stale:  snapshot.Stale(now, maxAge),
+		Rates:  make([]models.CurrencyRate, 0, len(snapshot.Rates)),
+	}
+	if !snapshot.Fetched.IsZero() {
+		ageSeconds := int64(snapshot.Age(now) / time.Second)
+		rates.Fetched, rates.AgeSeconds = &snapshot.Fetched, &ageSeconds
+	}
+	for code, value := range snapshot.Rates {
+		rates.Rates = append(rates.Rates, models.CurrencyRate{Currency: code, Rate: value, Digits: currency.Digits(code)})
+	}
+	sort.Slice(rates.Rates, func(i, j int) bool {
+		return rates.Rates[i].Currency < rates.Rates[j].Currency
+	})
+
+	return rates, nil
+}
+
+// Refresh gets the actual rates from the providers right away, the current rates are kept if all of them fail.
+func (s *Service) Refresh(ctx context.Context) (*models.CurrencyRates, error) {
+	if err := s.converter.Update(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetRates(ctx, 0)
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/currency"
+	converterMock "avito-tech-task/internal/pkg/currency/mock"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestService_GetRates(t *testing.T) {
+	fetched := time.Now().Add(-time.Hour)
+	snapshot := func() *currency.Snapshot {
+		return &currency.Snapshot{
+			Base:    "RUB",
+			Rates:   map[string]float64{"USD": 0.0131, "RUB": 1, "JPY": 1.5155},
+			Date:    time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC),
+			Source:  currency.CBRJSON,
+			Fetched: fetched,
+		}
+	}
+	rates := []models.CurrencyRate{
+		{Currency: "JPY", Rate: 1.5155, Digits: 0},
+		{Currency: "RUB", Rate: 1, Digits: 2},
+		{Currency: "USD", Rate: 0.0131, Digits: 2},
+	}
+	ageSeconds := int64(3600)
+
+	tests := []struct {
+		name          string
+		maxAge        time.Duration
+		converterMock *converterMock.MockConverterIface
+		expected      *models.CurrencyRates
+		expectedErr   bool
+		err           error
+	}{
+		{
+			name:          "Rates are fresh by default",
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot},
+			expected: &models.CurrencyRates{
+				Base:       "RUB",
+				Date:       "2022-01-19",
+				Source:     currency.CBRJSON,
+				Fetched:    &fetched,
+				AgeSeconds: &ageSeconds,
+				Rates:      rates,
+			},
+		},
+		{
+			name:          "Rates are older than the max age",
+			maxAge:        30 * time.Minute,
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot},
+			expected: &models.CurrencyRates{
+				Base:       "RUB",
+				Date:       "2022-01-19",
+				Source:     currency.CBRJSON,
+				Fetched:    &fetched,
+				AgeSeconds: &ageSeconds,
+				Stale:      true,
+				Rates:      rates,
+			},
+		},
+		{
+			name: "Rates were never fetched",
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: func() *currency.Snapshot {
+				return &currency.Snapshot{
+					Base:  "RUB",
+					Rates: map[string]float64{"RUB": 1},
+					Date:  time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC),
+				}
+			}},
+			expected: &models.CurrencyRates{
+				Base:  "RUB",
+				Date:  "2022-01-19",
+				Stale: true,
+				Rates: []models.CurrencyRate{{Currency: "RUB", Rate: 1, Digits: 2}},
+			},
+		},
+		{
+			name:          "Negative max age",
+			maxAge:        -time.Minute,
+			converterMock: &converterMock.MockConverterIface{},
+			expectedErr:   true,
+			err:           createdErrors.ErrNegativeMaxRateAge,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			service := NewService(test.converterMock)
+
+			got, err := service.GetRates(context.Background(), test.maxAge)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_Refresh(t *testing.T) {
+	updateErr := errors.New("Error in provider")
+	converter := &converterMock.MockConverterIface{
+		UpdateFunc: func(ctx context.Context) error {
+			return updateErr
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := NewService(converter).Refresh(ctx)
+	assert.Equal(t, updateErr, err)
+	assert.Len(t, converter.SnapshotCalls(), 0)
+	// the refresh is cancelled with the request
+	assert.Equal(t, ctx, converter.UpdateCalls()[0].ContextMoqParam)
+
+	converter.UpdateFunc = func(ctx context.Context) error {
+		return nil
+	}
+	converter.SnapshotFunc = func() *currency.Snapshot {
+		return &currency.Snapshot{Base: "RUB", Rates: map[string]float64{"RUB": 1}, Fetched: time.Now()}
+	}
+	got, err := NewService(converter).Refresh(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, got.Stale)
+		assert.Equal(t, []models.CurrencyRate{{Currency: "RUB", Rate: 1, Digits: 2}}, got.Rates)
+	}
+}
@@ -0,0 +1,44 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"avito-tech-task/internal/app/health"
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+)
+
+type Handlers struct {
+	service health.Service
+}
+
+func NewHandlers(service health.Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+// InitHandlers registers health routes, they are outside of the API for orchestrators and load balancers.
+func (h *Handlers) InitHandlers(server *echo.Echo) {
+	server.GET("/healthz", h.Live)
+	server.GET("/readyz", h.Ready)
+}
+
+// Live reports that the process handles requests. Dependencies are not checked, so the service is not
+// restarted while they are unavailable.
+func (h *Handlers) Live(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, &models.Health{Status: constants.HealthStatusOK})
+}
+
+// Ready reports whether the service can handle requests with the result of every check,
+// 503 is returned if any of them fails.
+func (h *Handlers) Ready(ctx echo.Context) error {
+	result := h.service.Ready(ctx.Request().Context())
+	if result.Status != constants.HealthStatusOK {
+		return ctx.JSON(http.StatusServiceUnavailable, result)
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
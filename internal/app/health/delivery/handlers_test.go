@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/health/mock"
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+)
+
+func TestHandlers(t *testing.T) {
+	ready := &models.Health{Status: constants.HealthStatusOK, Checks: map[string]*models.HealthCheck{
+		"database": {Status: constants.HealthStatusOK, LatencyMs: 0.5},
+		"rates":    {Status: constants.HealthStatusOK},
+	}}
+	notReady := &models.Health{Status: constants.HealthStatusFail, Checks: map[string]*models.HealthCheck{
+		"database": {Status: constants.HealthStatusFail, LatencyMs: 2000, Error: "database is unavailable"},
+		"rates":    {Status: constants.HealthStatusOK},
+	}}
+
+	tests := []struct {
+		name           string
+		path           string
+		serviceMock    *mock.MockService
+		expectedStatus int
+		expected       *models.Health
+	}{
+		{
+			name:           "Service is alive without checks",
+			path:           "/healthz",
+			serviceMock:    &mock.MockService{},
+			expectedStatus: http.StatusOK,
+			expected:       &models.Health{Status: constants.HealthStatusOK},
+		},
+		{
+			name: "Service is ready",
+			path: "/readyz",
+			serviceMock: &mock.MockService{
+				ReadyFunc: func(ctx context.Context) *models.Health {
+					return ready
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expected:       ready,
+		},
+		{
+			name: "Service is not ready",
+			path: "/readyz",
+			serviceMock: &mock.MockService{
+				ReadyFunc: func(ctx context.Context) *models.Health {
+					return notReady
+				},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expected:       notReady,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			NewHandlers(test.serviceMock).InitHandlers(server)
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(echo.GET, test.path, nil))
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			expectedString, _ := json.Marshal(test.expected)
+			assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+		})
+	}
+}
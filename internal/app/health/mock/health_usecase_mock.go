@@ -0,0 +1,77 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/health"
+	"avito-tech-task/internal/app/models"
+	"context"
+	"sync"
+)
+
+// Ensure, that MockService does implement health.Service.
+// If this is not the case, regenerate this file with moq.
+var _ health.Service = &MockService{}
+
+// MockService is a mock implementation of health.Service.
+//
+//	func TestSomethingThatUsesService(t *testing.T) {
+//
+//		// make and configure a mocked health.Service
+//		mockedService := &MockService{
+//			ReadyFunc: func(contextMoqParam context.Context) *models.Health {
+//				panic("mock out the Ready method")
+//			},
+//		}
+//
+//		// use mockedService in code that requires health.Service
+//		// and then make assertions.
+//
+//	}
+type MockService struct {
+	// ReadyFunc mocks the Ready method.
+	ReadyFunc func(contextMoqParam context.Context) *models.Health
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Ready holds details about calls to the Ready method.
+		Ready []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
+	}
+	lockReady sync.RWMutex
+}
+
+// Ready calls ReadyFunc.
+func (mock *MockService) Ready(contextMoqParam context.Context) *models.Health {
+	if mock.ReadyFunc == nil {
+		panic("MockService.ReadyFunc: method is nil but Service.Ready was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockReady.Lock()
+	mock.calls.Ready = append(mock.calls.Ready, callInfo)
+	mock.lockReady.Unlock()
+	return mock.ReadyFunc(contextMoqParam)
+}
+
+// ReadyCalls gets all the calls that were made to Ready.
+// Check the length with:
+//
+//	len(mockedService.ReadyCalls())
+func (mock *MockService) ReadyCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockReady.RLock()
+	calls = mock.calls.Ready
+	mock.lockReady.RUnlock()
+	return calls
+}
@@ -0,0 +1,12 @@
+package health
+
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+)
+
+//go:generate moq -out ./mock/health_usecase_mock.go -pkg mock . Service:MockService
+type Service interface {
+	Ready(context.Context) *models.Health
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	"avito-tech-task/internal/pkg/currency"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Service struct {
+	db        utils.PgxIface
+	converter currency.ConverterIface
+}
+
+func NewService(db utils.PgxIface, converter currency.ConverterIface) *Service {
+	return &Service{
+		db:        db,
+		converter: converter,
+	}
+}
+
+// Ready checks that the database answers and the exchange rates are not stale, the service is ready
+// if all checks pass.
+func (s *Service) Ready(ctx context.Context) *models.Health {
+	health := &models.Health{
+		Status: constants.HealthStatusOK,
+		Checks: map[string]*models.HealthCheck{
+			"database": check(ctx, "database", s.pingDatabase),
+			"rates":    check(ctx, "rates", s.checkRates),
+		},
+	}
+	for _, result := range health.Checks {
+		if result.Status != constants.HealthStatusOK {
+			health.Status = constants.HealthStatusFail
+		}
+	}
+
+	return health
+}
+
+func (s *Service) pingDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, constants.HealthCheckTimeout)
+	defer cancel()
+
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %s", createdErrors.ErrDatabaseUnavailable, err)
+	}
+
+	return nil
+}
+
+func (s *Service) checkRates(context.Context) error {
+	snapshot := s.converter.Snapshot()
+	if snapshot.Stale(time.Now(), constants.DefaultMaxRateAge) {
+		return fmt.Errorf("%w: rates of %s were not updated for %s", createdErrors.ErrStaleRates,
+			snapshot.Source, constants.DefaultMaxRateAge)
+	}
+
+	return nil
+}
+
+// check runs the check and measures its latency. Only messages of domain errors are shown,
+// the details are logged.
+func check(ctx context.Context, name string, run func(context.Context) error) *models.HealthCheck {
+	start := time.Now()
+	err := run(ctx)
+	result := &models.HealthCheck{
+		Status:    constants.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err == nil {
+		return result
+	}
+
+	utils.Log(ctx).WithError(err).WithField("check", name).Warn("Readiness check failed")
+	result.Status, result.Error = constants.HealthStatusFail, "check failed"
+	var domainErr *createdErrors.Error
+	if errors.As(err, &domainErr) {
+		result.Error = domainErr.Error()
+	}
+
+	return result
+}
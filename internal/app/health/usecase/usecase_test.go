@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/pkg/constants"
+	"avito-tech-task/internal/pkg/currency"
+	converterMock "avito-tech-task/internal/pkg/currency/mock"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestService_Ready(t *testing.T) {
+	snapshot := func(fetched time.Time) func() *currency.Snapshot {
+		return func() *currency.Snapshot {
+			return &currency.Snapshot{Base: "RUB", Source: currency.CBRJSON, Fetched: fetched}
+		}
+	}
+
+	tests := []struct {
+		name          string
+		pingErr       error
+		converterMock *converterMock.MockConverterIface
+		expected      string
+		// expectedErrors are the errors of failed checks
+		expectedErrors map[string]string
+	}{
+		{
+			name:          "All checks passed",
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot(time.Now().Add(-time.Hour))},
+			expected:      constants.HealthStatusOK,
+		},
+		{
+			name:          "Database is unavailable",
+			pingErr:       errors.New("dial tcp 10.0.0.2:5432: connect: connection refused"),
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot(time.Now().Add(-time.Hour))},
+			expected:      constants.HealthStatusFail,
+			expectedErrors: map[string]string{
+				"database": createdErrors.ErrDatabaseUnavailable.Error(),
+			},
+		},
+		{
+			name:          "Rates are stale",
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot(time.Now().Add(-72 * time.Hour))},
+			expected:      constants.HealthStatusFail,
+			expectedErrors: map[string]string{
+				"rates": createdErrors.ErrStaleRates.Error(),
+			},
+		},
+		{
+			name:          "Rates were never received",
+			converterMock: &converterMock.MockConverterIface{SnapshotFunc: snapshot(time.Time{})},
+			expected:      constants.HealthStatusFail,
+			expectedErrors: map[string]string{
+				"rates": createdErrors.ErrStaleRates.Error(),
+			},
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool(pgxmock.MonitorPingsOption(true))
+			if err != nil {
+				t.Errorf("Could not mock database connection: %s", err)
+			}
+			mock.ExpectPing().WillReturnError(test.pingErr)
+
+			got := NewService(mock, test.converterMock).Ready(context.Background())
+
+			assert.Equal(t, test.expected, got.Status)
+			for _, name := range []string{"database", "rates"} {
+				if assert.Contains(t, got.Checks, name) {
+					assert.Equal(t, test.expectedErrors[name], got.Checks[name].Error)
+					assert.Equal(t, test.expectedErrors[name] == "", got.Checks[name].Status == constants.HealthStatusOK)
+					assert.GreaterOrEqual(t, got.Checks[name].LatencyMs, 0.0)
+				}
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
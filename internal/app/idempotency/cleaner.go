@@ -0,0 +1,27 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"avito-tech-task/internal/pkg/constants"
+)
+
+// CleanExpired periodically removes expired idempotency keys until ctx is cancelled.
+func CleanExpired(ctx context.Context, service Service, logger *logrus.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.IdempotencyCleanupPeriod):
+			deleted, err := service.DeleteExpired(ctx)
+			if err != nil {
+				logger.Errorf("Could not delete expired idempotency keys: %s", err)
+				continue
+			}
+			logger.Infof("Deleted %d expired idempotency keys", deleted)
+		}
+	}
+}
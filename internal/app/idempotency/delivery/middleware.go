@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Middleware struct {
+	service idempotency.Service
+}
+
+func NewMiddleware(service idempotency.Service) *Middleware {
+	return &Middleware{
+		service: service,
+	}
+}
+
+// Handle makes the request idempotent if it has the Idempotency-Key header.
+// The first response for the key is stored and replayed for retries of the same request.
+func (m *Middleware) Handle(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		key := ctx.Request().Header.Get(constants.IdempotencyKeyHeader)
+		if key == "" {
+			return next(ctx)
+		}
+
+		log := utils.Log(ctx.Request().Context()).WithField("idempotency_key", key)
+		body, err := io.ReadAll(ctx.Request().Body)
+		if err != nil {
+			log.WithError(err).Warn("Could not read request body")
+			return createdErrors.ErrInvalidBody
+		}
+		ctx.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := m.service.Start(ctx.Request().Context(), key, fingerprint(ctx.Request(), body))
+		switch {
+		case err != nil:
+			return err
+		case record.StatusCode != 0:
+			log.Info("Replaying stored response")
+			ctx.Response().Header().Set(constants.IdempotentReplayedHeader, "true")
+			contentType := record.ContentType
+			if contentType == "" { // the response was stored before content types were kept
+				contentType = echo.MIMEApplicationJSONCharsetUTF8
+			}
+			return ctx.Blob(record.StatusCode, contentType, record.Response)
+		}
+
+		// the handler marks the key in the transaction moving money, so a retry does not move it again
+		lock := idempotency.Lock{Key: key, Token: record.Token}
+		ctx.SetRequest(ctx.Request().WithContext(idempotency.WithLock(ctx.Request().Context(), lock)))
+
+		// the outcome is saved even if the client has already gone, otherwise the key stays locked until it expires
+		saveCtx := context.Background()
+		recorder := &responseRecorder{ResponseWriter: ctx.Response().Writer}
+		ctx.Response().Writer = recorder
+
+		if err = next(ctx); err != nil {
+			// the error is rendered here, so its response is stored like any other
+			ctx.Error(err)
+		}
+		if ctx.Response().Status >= http.StatusInternalServerError {
+			if releaseErr := m.service.Release(saveCtx, lock); releaseErr != nil {
+				log.WithError(releaseErr).Error("Could not release idempotency key")
+			}
+			return nil
+		}
+
+		contentType := ctx.Response().Header().Get(echo.HeaderContentType)
+		err = m.service.Complete(saveCtx, lock, ctx.Response().Status, contentType, recorder.body.Bytes())
+		switch {
+		case errors.Is(err, createdErrors.ErrIdempotencyKeyTakenOver):
+			log.Warn("Idempotency key was taken over by a retry, response is not saved")
+		case err != nil:
+			log.WithError(err).Error("Could not save response for idempotency key")
+		}
+
+		return nil
+	}
+}
+
+// fingerprint identifies the request by its method, URI and body.
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder copies the response body written by the handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,262 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/idempotency/mock"
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+func TestMiddleware_Handle(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	lock := idempotency.Lock{Key: "key", Token: "token"}
+
+	tests := []struct {
+		name             string
+		key              string
+		serviceMock      *mock.MockService
+		handlerStatus    int
+		handlerErr       error
+		expectedStatus   int
+		expectedType     string
+		expectedBody     string
+		expectedCalls    int
+		expectedReplayed bool
+		expectedComplete bool
+		expectedRelease  bool
+	}{
+		{
+			name:           "Request without key is passed through",
+			serviceMock:    &mock.MockService{},
+			handlerStatus:  http.StatusOK,
+			expectedStatus: http.StatusOK,
+			expectedType:   echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:   `{"message":"handled"}` + "\n",
+			expectedCalls:  1,
+		},
+		{
+			name: "First request with key is processed and stored",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Token: "token"}, nil
+				},
+				CompleteFunc: func(ctx context.Context, lock idempotency.Lock, statusCode int, contentType string, response []byte) error {
+					return nil
+				},
+			},
+			handlerStatus:    http.StatusOK,
+			expectedStatus:   http.StatusOK,
+			expectedType:     echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:     `{"message":"handled"}` + "\n",
+			expectedCalls:    1,
+			expectedComplete: true,
+		},
+		{
+			name: "Retried request is replayed without calling handler",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{StatusCode: http.StatusOK, ContentType: echo.MIMEApplicationJSONCharsetUTF8,
+						Response: []byte(`{"message":"stored"}`)}, nil
+				},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedType:     echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:     `{"message":"stored"}`,
+			expectedReplayed: true,
+		},
+		{
+			name: "Stored error is replayed as problem details",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{StatusCode: http.StatusUnprocessableEntity,
+						ContentType: constants.ProblemContentType, Response: []byte(problemBody(createdErrors.ErrNotEnoughMoney))}, nil
+				},
+			},
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedType:     constants.ProblemContentType,
+			expectedBody:     problemBody(createdErrors.ErrNotEnoughMoney),
+			expectedReplayed: true,
+		},
+		{
+			name: "Response stored without content type is replayed as JSON",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{StatusCode: http.StatusOK, Response: []byte(`{"message":"stored"}`)}, nil
+				},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedType:     echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:     `{"message":"stored"}`,
+			expectedReplayed: true,
+		},
+		{
+			name: "Key reused with a different body",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return nil, createdErrors.ErrIdempotencyKeyReused
+				},
+			},
+			expectedStatus: http.StatusConflict,
+			expectedType:   constants.ProblemContentType,
+			expectedBody:   problemBody(createdErrors.ErrIdempotencyKeyReused),
+		},
+		{
+			name: "Error of handler is rendered and stored",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Token: "token"}, nil
+				},
+				CompleteFunc: func(ctx context.Context, lock idempotency.Lock, statusCode int, contentType string, response []byte) error {
+					return nil
+				},
+			},
+			handlerErr:       createdErrors.ErrNotEnoughMoney,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedType:     constants.ProblemContentType,
+			expectedBody:     problemBody(createdErrors.ErrNotEnoughMoney),
+			expectedCalls:    1,
+			expectedComplete: true,
+		},
+		{
+			name: "Response of request taken over by a retry is not stored",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Token: "token"}, nil
+				},
+				CompleteFunc: func(ctx context.Context, lock idempotency.Lock, statusCode int, contentType string, response []byte) error {
+					return createdErrors.ErrIdempotencyKeyTakenOver
+				},
+			},
+			handlerStatus:    http.StatusOK,
+			expectedStatus:   http.StatusOK,
+			expectedType:     echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:     `{"message":"handled"}` + "\n",
+			expectedCalls:    1,
+			expectedComplete: true,
+		},
+		{
+			name: "Key is released when handler fails",
+			key:  "key",
+			serviceMock: &mock.MockService{
+				StartFunc: func(ctx context.Context, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Token: "token"}, nil
+				},
+				ReleaseFunc: func(ctx context.Context, lock idempotency.Lock) error {
+					return nil
+				},
+			},
+			handlerStatus:   http.StatusInternalServerError,
+			expectedStatus:  http.StatusInternalServerError,
+			expectedType:    echo.MIMEApplicationJSONCharsetUTF8,
+			expectedBody:    `{"message":"handled"}` + "\n",
+			expectedCalls:   1,
+			expectedRelease: true,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+
+			calls := 0
+			var handlerLock idempotency.Lock
+			handler := func(ctx echo.Context) error {
+				calls++
+				handlerLock, _ = idempotency.LockFrom(ctx.Request().Context())
+				if test.handlerErr != nil {
+					return test.handlerErr
+				}
+				return ctx.JSON(test.handlerStatus, map[string]string{"message": "handled"})
+			}
+
+			req := httptest.NewRequest(echo.POST, "/api/v1/transfer", strings.NewReader(`{"amount": 10}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if test.key != "" {
+				req.Header.Set(constants.IdempotencyKeyHeader, test.key)
+			}
+			rec := httptest.NewRecorder()
+			ctx := server.NewContext(req, rec)
+
+			middleware := NewMiddleware(test.serviceMock)
+			if assert.NoError(t, utils.RequestLogger(logger)(middleware.Handle(handler))(ctx)) {
+				assert.Equal(t, test.expectedStatus, rec.Code)
+				assert.Equal(t, test.expectedType, rec.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, test.expectedBody, rec.Body.String())
+				assert.Equal(t, test.expectedCalls, calls)
+				assert.Equal(t, test.expectedReplayed, rec.Header().Get(constants.IdempotentReplayedHeader) == "true")
+				assert.Equal(t, test.expectedComplete, len(test.serviceMock.CompleteCalls()) == 1)
+				assert.Equal(t, test.expectedRelease, len(test.serviceMock.ReleaseCalls()) == 1)
+				if test.expectedComplete || test.expectedRelease {
+					assert.Equal(t, lock, handlerLock)
+				}
+				if test.expectedRelease {
+					assert.Equal(t, lock, test.serviceMock.ReleaseCalls()[0].Lock)
+				}
+				if test.expectedComplete {
+					assert.Equal(t, lock, test.serviceMock.CompleteCalls()[0].Lock)
+					assert.Equal(t, test.expectedType, test.serviceMock.CompleteCalls()[0].S)
+					assert.Equal(t, test.expectedBody, string(test.serviceMock.CompleteCalls()[0].Bytes))
+				}
+			}
+		})
+	}
+}
+
+func problemBody(err error) string {
+	body, _ := json.Marshal(utils.NewProblem(err, ""))
+	return string(body) + "\n"
+}
+
+func TestFingerprint(t *testing.T) {
+	first := httptest.NewRequest(echo.POST, "/api/v1/balance/1", nil)
+	second := httptest.NewRequest(echo.POST, "/api/v1/balance/2", nil)
+
+	assert.Equal(t, fingerprint(first, []byte(`{"amount": 10}`)), fingerprint(first, []byte(`{"amount": 10}`)))
+	assert.NotEqual(t, fingerprint(first, []byte(`{"amount": 10}`)), fingerprint(first, []byte(`{"amount": 20}`)))
+	assert.NotEqual(t, fingerprint(first, []byte(`{"amount": 10}`)), fingerprint(second, []byte(`{"amount": 10}`)))
+}
@@ -0,0 +1,22 @@
+package idempotency
+
+import "context"
+
+type lockKey struct{}
+
+// Lock identifies the request holding the idempotency key, the token is changed when the key is taken over.
+type Lock struct {
+	Key   string
+	Token string
+}
+
+// WithLock returns the context of the request holding the lock.
+func WithLock(ctx context.Context, lock Lock) context.Context {
+	return context.WithValue(ctx, lockKey{}, lock)
+}
+
+// LockFrom returns the lock of the request, false is returned for requests without an idempotency key.
+func LockFrom(ctx context.Context) (Lock, bool) {
+	lock, ok := ctx.Value(lockKey{}).(Lock)
+	return lock, ok
+}
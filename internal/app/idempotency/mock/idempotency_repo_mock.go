@@ -0,0 +1,314 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// Ensure, that MockStorage does implement idempotency.Storage.
+// If this is not the case, regenerate this file with moq.
+var _ idempotency.Storage = &MockStorage{}
+
+// MockStorage is a mock implementation of idempotency.Storage.
+//
+//	func TestSomethingThatUsesStorage(t *testing.T) {
+//
+//		// make and configure a mocked idempotency.Storage
+//		mockedStorage := &MockStorage{
+//			DeleteFunc: func(contextMoqParam context.Context, lock idempotency.Lock) error {
+//				panic("mock out the Delete method")
+//			},
+//			DeleteExpiredFunc: func(contextMoqParam context.Context) (int64, error) {
+//				panic("mock out the DeleteExpired method")
+//			},
+//			GetFunc: func(contextMoqParam context.Context, s string) (*models.IdempotencyRecord, error) {
+//				panic("mock out the Get method")
+//			},
+//			LockFunc: func(contextMoqParam context.Context, s1 string, s2 string, timeMoqParam1 time.Time, timeMoqParam2 time.Time) (string, error) {
+//				panic("mock out the Lock method")
+//			},
+//			SaveResponseFunc: func(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error {
+//				panic("mock out the SaveResponse method")
+//			},
+//		}
+//
+//		// use mockedStorage in code that requires idempotency.Storage
+//		// and then make assertions.
+//
+//	}
+type MockStorage struct {
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(contextMoqParam context.Context, lock idempotency.Lock) error
+
+	// DeleteExpiredFunc mocks the DeleteExpired method.
+	DeleteExpiredFunc func(contextMoqParam context.Context) (int64, error)
+
+	// GetFunc mocks the Get method.
+	GetFunc func(contextMoqParam context.Context, s string) (*models.IdempotencyRecord, error)
+
+	// LockFunc mocks the Lock method.
+	LockFunc func(contextMoqParam context.Context, s1 string, s2 string, timeMoqParam1 time.Time, timeMoqParam2 time.Time) (string, error)
+
+	// SaveResponseFunc mocks the SaveResponse method.
+	SaveResponseFunc func(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Lock is the lock argument value.
+			Lock idempotency.Lock
+		}
+		// DeleteExpired holds details about calls to the DeleteExpired method.
+		DeleteExpired []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// S is the s argument value.
+			S string
+		}
+		// Lock holds details about calls to the Lock method.
+		Lock []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// S1 is the s1 argument value.
+			S1 string
+			// S2 is the s2 argument value.
+			S2 string
+			// TimeMoqParam1 is the timeMoqParam1 argument value.
+			TimeMoqParam1 time.Time
+			// TimeMoqParam2 is the timeMoqParam2 argument value.
+			TimeMoqParam2 time.Time
+		}
+		// SaveResponse holds details about calls to the SaveResponse method.
+		SaveResponse []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Lock is the lock argument value.
+			Lock idempotency.Lock
+			// N is the n argument value.
+			N int
+			// S is the s argument value.
+			S string
+			// Bytes is the bytes argument value.
+			Bytes []byte
+		}
+	}
+	lockDelete        sync.RWMutex
+	lockDeleteExpired sync.RWMutex
+	lockGet           sync.RWMutex
+	lockLock          sync.RWMutex
+	lockSaveResponse  sync.RWMutex
+}
+
+// Delete calls DeleteFunc.
+func (mock *MockStorage) Delete(contextMoqParam context.Context, lock idempotency.Lock) error {
+	if mock.DeleteFunc == nil {
+		panic("MockStorage.DeleteFunc: method is nil but Storage.Delete was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+	}{
+		ContextMoqParam: contextMoqParam,
+		Lock:            lock,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(contextMoqParam, lock)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedStorage.DeleteCalls())
+func (mock *MockStorage) DeleteCalls() []struct {
+	ContextMoqParam context.Context
+	Lock            idempotency.Lock
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
+// DeleteExpired calls DeleteExpiredFunc.
+func (mock *MockStorage) DeleteExpired(contextMoqParam context.Context) (int64, error) {
+	if mock.DeleteExpiredFunc == nil {
+		panic("MockStorage.DeleteExpiredFunc: method is nil but Storage.DeleteExpired was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockDeleteExpired.Lock()
+	mock.calls.DeleteExpired = append(mock.calls.DeleteExpired, callInfo)
+	mock.lockDeleteExpired.Unlock()
+	return mock.DeleteExpiredFunc(contextMoqParam)
+}
+
+// DeleteExpiredCalls gets all the calls that were made to DeleteExpired.
+// Check the length with:
+//
+//	len(mockedStorage.DeleteExpiredCalls())
+func (mock *MockStorage) DeleteExpiredCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockDeleteExpired.RLock()
+	calls = mock.calls.DeleteExpired
+	mock.lockDeleteExpired.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *MockStorage) Get(contextMoqParam context.Context, s string) (*models.IdempotencyRecord, error) {
+	if mock.GetFunc == nil {
+		panic("MockStorage.GetFunc: method is nil but Storage.Get was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		S               string
+	}{
+		ContextMoqParam: contextMoqParam,
+		S:               s,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(contextMoqParam, s)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedStorage.GetCalls())
+func (mock *MockStorage) GetCalls() []struct {
+	ContextMoqParam context.Context
+	S               string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		S               string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// Lock calls LockFunc.
+func (mock *MockStorage) Lock(contextMoqParam context.Context, s1 string, s2 string, timeMoqParam1 time.Time, timeMoqParam2 time.Time) (string, error) {
+	if mock.LockFunc == nil {
+		panic("MockStorage.LockFunc: method is nil but Storage.Lock was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		S1              string
+		S2              string
+		TimeMoqParam1   time.Time
+		TimeMoqParam2   time.Time
+	}{
+		ContextMoqParam: contextMoqParam,
+		S1:              s1,
+		S2:              s2,
+		TimeMoqParam1:   timeMoqParam1,
+		TimeMoqParam2:   timeMoqParam2,
+	}
+	mock.lockLock.Lock()
+	mock.calls.Lock = append(mock.calls.Lock, callInfo)
+	mock.lockLock.Unlock()
+	return mock.LockFunc(contextMoqParam, s1, s2, timeMoqParam1, timeMoqParam2)
+}
+
+// LockCalls gets all the calls that were made to Lock.
+// Check the length with:
+//
+//	len(mockedStorage.LockCalls())
+func (mock *MockStorage) LockCalls() []struct {
+	ContextMoqParam context.Context
+	S1              string
+	S2              string
+	TimeMoqParam1   time.Time
+	TimeMoqParam2   time.Time
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		S1              string
+		S2              string
+		TimeMoqParam1   time.Time
+		TimeMoqParam2   time.Time
+	}
+	mock.lockLock.RLock()
+	calls = mock.calls.Lock
+	mock.lockLock.RUnlock()
+	return calls
+}
+
+// SaveResponse calls SaveResponseFunc.
+func (mock *MockStorage) SaveResponse(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error {
+	if mock.SaveResponseFunc == nil {
+		panic("MockStorage.SaveResponseFunc: method is nil but Storage.SaveResponse was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+		N               int
+		S               string
+		Bytes           []byte
+	}{
+		ContextMoqParam: contextMoqParam,
+		Lock:            lock,
+		N:               n,
+		S:               s,
+		Bytes:           bytes,
+	}
+	mock.lockSaveResponse.Lock()
+	mock.calls.SaveResponse = append(mock.calls.SaveResponse, callInfo)
+	mock.lockSaveResponse.Unlock()
+	return mock.SaveResponseFunc(contextMoqParam, lock, n, s, bytes)
+}
+
+// SaveResponseCalls gets all the calls that were made to SaveResponse.
+// Check the length with:
+//
+//	len(mockedStorage.SaveResponseCalls())
+func (mock *MockStorage) SaveResponseCalls() []struct {
+	ContextMoqParam context.Context
+	Lock            idempotency.Lock
+	N               int
+	S               string
+	Bytes           []byte
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+		N               int
+		S               string
+		Bytes           []byte
+	}
+	mock.lockSaveResponse.RLock()
+	calls = mock.calls.SaveResponse
+	mock.lockSaveResponse.RUnlock()
+	return calls
+}
@@ -0,0 +1,251 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/models"
+	"context"
+	"sync"
+)
+
+// Ensure, that MockService does implement idempotency.Service.
+// If this is not the case, regenerate this file with moq.
+var _ idempotency.Service = &MockService{}
+
+// MockService is a mock implementation of idempotency.Service.
+//
+//	func TestSomethingThatUsesService(t *testing.T) {
+//
+//		// make and configure a mocked idempotency.Service
+//		mockedService := &MockService{
+//			CompleteFunc: func(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error {
+//				panic("mock out the Complete method")
+//			},
+//			DeleteExpiredFunc: func(contextMoqParam context.Context) (int64, error) {
+//				panic("mock out the DeleteExpired method")
+//			},
+//			ReleaseFunc: func(contextMoqParam context.Context, lock idempotency.Lock) error {
+//				panic("mock out the Release method")
+//			},
+//			StartFunc: func(contextMoqParam context.Context, s1 string, s2 string) (*models.IdempotencyRecord, error) {
+//				panic("mock out the Start method")
+//			},
+//		}
+//
+//		// use mockedService in code that requires idempotency.Service
+//		// and then make assertions.
+//
+//	}
+type MockService struct {
+	// CompleteFunc mocks the Complete method.
+	CompleteFunc func(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error
+
+	// DeleteExpiredFunc mocks the DeleteExpired method.
+	DeleteExpiredFunc func(contextMoqParam context.Context) (int64, error)
+
+	// ReleaseFunc mocks the Release method.
+	ReleaseFunc func(contextMoqParam context.Context, lock idempotency.Lock) error
+
+	// StartFunc mocks the Start method.
+	StartFunc func(contextMoqParam context.Context, s1 string, s2 string) (*models.IdempotencyRecord, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Complete holds details about calls to the Complete method.
+		Complete []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Lock is the lock argument value.
+			Lock idempotency.Lock
+			// N is the n argument value.
+			N int
+			// S is the s argument value.
+			S string
+			// Bytes is the bytes argument value.
+			Bytes []byte
+		}
+		// DeleteExpired holds details about calls to the DeleteExpired method.
+		DeleteExpired []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
+		// Release holds details about calls to the Release method.
+		Release []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Lock is the lock argument value.
+			Lock idempotency.Lock
+		}
+		// Start holds details about calls to the Start method.
+		Start []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// S1 is the s1 argument value.
+			S1 string
+			// S2 is the s2 argument value.
+			S2 string
+		}
+	}
+	lockComplete      sync.RWMutex
+	lockDeleteExpired sync.RWMutex
+	lockRelease       sync.RWMutex
+	lockStart         sync.RWMutex
+}
+
+// Complete calls CompleteFunc.
+func (mock *MockService) Complete(contextMoqParam context.Context, lock idempotency.Lock, n int, s string, bytes []byte) error {
+	if mock.CompleteFunc == nil {
+		panic("MockService.CompleteFunc: method is nil but Service.Complete was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+		N               int
+		S               string
+		Bytes           []byte
+	}{
+		ContextMoqParam: contextMoqParam,
+		Lock:            lock,
+		N:               n,
+		S:               s,
+		Bytes:           bytes,
+	}
+	mock.lockComplete.Lock()
+	mock.calls.Complete = append(mock.calls.Complete, callInfo)
+	mock.lockComplete.Unlock()
+	return mock.CompleteFunc(contextMoqParam, lock, n, s, bytes)
+}
+
+// CompleteCalls gets all the calls that were made to Complete.
+// Check the length with:
+//
+//	len(mockedService.CompleteCalls())
+func (mock *MockService) CompleteCalls() []struct {
+	ContextMoqParam context.Context
+	Lock            idempotency.Lock
+	N               int
+	S               string
+	Bytes           []byte
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+		N               int
+		S               string
+		Bytes           []byte
+	}
+	mock.lockComplete.RLock()
+	calls = mock.calls.Complete
+	mock.lockComplete.RUnlock()
+	return calls
+}
+
+// DeleteExpired calls DeleteExpiredFunc.
+func (mock *MockService) DeleteExpired(contextMoqParam context.Context) (int64, error) {
+	if mock.DeleteExpiredFunc == nil {
+		panic("MockService.DeleteExpiredFunc: method is nil but Service.DeleteExpired was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockDeleteExpired.Lock()
+	mock.calls.DeleteExpired = append(mock.calls.DeleteExpired, callInfo)
+	mock.lockDeleteExpired.Unlock()
+	return mock.DeleteExpiredFunc(contextMoqParam)
+}
+
+// DeleteExpiredCalls gets all the calls that were made to DeleteExpired.
+// Check the length with:
+//
+//	len(mockedService.DeleteExpiredCalls())
+func (mock *MockService) DeleteExpiredCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockDeleteExpired.RLock()
+	calls = mock.calls.DeleteExpired
+	mock.lockDeleteExpired.RUnlock()
+	return calls
+}
+
+// Release calls ReleaseFunc.
+func (mock *MockService) Release(contextMoqParam context.Context, lock idempotency.Lock) error {
+	if mock.ReleaseFunc == nil {
+		panic("MockService.ReleaseFunc: method is nil but Service.Release was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+	}{
+		ContextMoqParam: contextMoqParam,
+		Lock:            lock,
+	}
+	mock.lockRelease.Lock()
+	mock.calls.Release = append(mock.calls.Release, callInfo)
+	mock.lockRelease.Unlock()
+	return mock.ReleaseFunc(contextMoqParam, lock)
+}
+
+// ReleaseCalls gets all the calls that were made to Release.
+// Check the length with:
+//
+//	len(mockedService.ReleaseCalls())
+func (mock *MockService) ReleaseCalls() []struct {
+	ContextMoqParam context.Context
+	Lock            idempotency.Lock
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Lock            idempotency.Lock
+	}
+	mock.lockRelease.RLock()
+	calls = mock.calls.Release
+	mock.lockRelease.RUnlock()
+	return calls
+}
+
+// Start calls StartFunc.
+func (mock *MockService) Start(contextMoqParam context.Context, s1 string, s2 string) (*models.IdempotencyRecord, error) {
+	if mock.StartFunc == nil {
+		panic("MockService.StartFunc: method is nil but Service.Start was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		S1              string
+		S2              string
+	}{
+		ContextMoqParam: contextMoqParam,
+		S1:              s1,
+		S2:              s2,
+	}
+	mock.lockStart.Lock()
+	mock.calls.Start = append(mock.calls.Start, callInfo)
+	mock.lockStart.Unlock()
+	return mock.StartFunc(contextMoqParam, s1, s2)
+}
+
+// StartCalls gets all the calls that were made to Start.
+// Check the length with:
+//
+//	len(mockedService.StartCalls())
+func (mock *MockService) StartCalls() []struct {
+	ContextMoqParam context.Context
+	S1              string
+	S2              string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		S1              string
+		S2              string
+	}
+	mock.lockStart.RLock()
+	calls = mock.calls.Start
+	mock.lockStart.RUnlock()
+	return calls
+}
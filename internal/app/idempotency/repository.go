@@ -0,0 +1,18 @@
+package idempotency
+
+import (
+	"context"
+
+	"time"
+
+	"avito-tech-task/internal/app/models"
+)
+
+//go:generate moq -out ./mock/idempotency_repo_mock.go -pkg mock . Storage:MockStorage
+type Storage interface {
+	Lock(context.Context, string, string, time.Time, time.Time) (string, error)
+	Get(context.Context, string) (*models.IdempotencyRecord, error)
+	SaveResponse(context.Context, Lock, int, string, []byte) error
+	Delete(context.Context, Lock) error
+	DeleteExpired(context.Context) (int64, error)
+}
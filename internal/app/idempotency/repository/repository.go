@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Storage struct {
+	db utils.PgxIface
+}
+
+func NewStorage(conn utils.PgxIface) *Storage {
+	return &Storage{conn}
+}
+
+const (
+	// queryLockKey inserts a new key or takes over an expired one or the one whose request is still not
+	// completed after the lock lease and has not moved money, no rows are returned if the key is active.
+	// Every lock gets a new token, so the request that was taken over can not change the key
+	queryLockKey = `
+		INSERT INTO idempotency_keys (key, fingerprint, locked_until, expires, lock_token)
+		VALUES ($1, $2, $3, $4, gen_random_uuid())
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, response = NULL,
+			created = now(), locked_until = EXCLUDED.locked_until, expires = EXCLUDED.expires,
+			lock_token = EXCLUDED.lock_token, applied = false
+		WHERE idempotency_keys.expires < now()
+			OR (idempotency_keys.status_code IS NULL AND NOT idempotency_keys.applied
+				AND idempotency_keys.locked_until < now())
+		RETURNING lock_token::text`
+	queryGetKey = `
+		SELECT key, fingerprint, status_code, content_type, response, expires
+		FROM idempotency_keys WHERE key = $1 AND expires >= now()`
+	querySaveResponse = `
+		UPDATE idempotency_keys SET status_code = $1, content_type = $2, response = $3
+		WHERE key = $4 AND lock_token = $5`
+	// queryDeleteKey keeps the key once money was moved for it, so a retry can not move it again
+	queryDeleteKey = `DELETE FROM idempotency_keys WHERE key = $1 AND lock_token = $2 AND NOT applied`
+	// queryApplyKey marks the key in the transaction moving money, the row stays locked until it commits,
+	// so the key can not be taken over meanwhile
+	queryApplyKey          = `UPDATE idempotency_keys SET applied = true WHERE key = $1 AND lock_token = $2`
+	queryDeleteExpiredKeys = `DELETE FROM idempotency_keys WHERE expires < now()`
+)
+
+// Lock stores the key with the request fingerprint and returns the lock token, an empty token is returned
+// if the key is already in use. The request must be completed before lockedUntil, otherwise the key can be
+// locked by another one.
+func (s *Storage) Lock(ctx context.Context, key, fingerprint string, lockedUntil, expires time.Time) (_ string, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	var token string
+	if err = transaction.QueryRow(ctx, queryLockKey, key, fingerprint, lockedUntil, expires).Scan(&token); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+		err = nil
+		return "", nil
+	}
+
+	return token, nil
+}
+
+// Get returns the active record for the key or nil if there is no such record.
+func (s *Storage) Get(ctx context.Context, key string) (_ *models.IdempotencyRecord, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	record := &models.IdempotencyRecord{}
+	var statusCode sql.NullInt32
+	var contentType sql.NullString
+	if err = transaction.QueryRow(ctx, queryGetKey, key).Scan(&record.Key, &record.Fingerprint,
+		&statusCode, &contentType, &record.Response, &record.Expires); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		err = nil
+		return nil, nil
+	}
+	record.StatusCode = int(statusCode.Int32)
+	record.ContentType = contentType.String
+
+	return record, nil
+}
+
+// SaveResponse stores the response if the request still holds the lock, ErrIdempotencyKeyTakenOver is returned
+// otherwise.
+func (s *Storage) SaveResponse(ctx context.Context, lock idempotency.Lock, statusCode int, contentType string,
+	response []byte) error {
+	saved, err := s.exec(ctx, querySaveResponse, statusCode, contentType, response, lock.Key, lock.Token)
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return createdErrors.ErrIdempotencyKeyTakenOver
+	}
+
+	return nil
+}
+
+// Delete removes the key if the request still holds the lock and has not moved money.
+func (s *Storage) Delete(ctx context.Context, lock idempotency.Lock) error {
+	_, err := s.exec(ctx, queryDeleteKey, lock.Key, lock.Token)
+	return err
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	result, err := transaction.Exec(ctx, queryDeleteExpiredKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (s *Storage) exec(ctx context.Context, query string, args ...interface{}) (_ int64, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	result, err := transaction.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// Apply marks the idempotency key of the request as the one that moved money, it must be called in the
+// transaction moving it. ErrIdempotencyKeyTakenOver is returned if the key was taken over by a retry,
+// so the transaction is rolled back. Requests without an idempotency key are not checked.
+func Apply(ctx context.Context, transaction pgx.Tx) error {
+	lock, ok := idempotency.LockFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	result, err := transaction.Exec(ctx, queryApplyKey, lock.Key, lock.Token)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return createdErrors.ErrIdempotencyKeyTakenOver
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestStorage_Lock(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	dbErr := errors.New("Error in database")
+	lockedUntil := time.Now().Add(time.Minute)
+	expires := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		expected    string
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Key was locked",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockKey)).WithArgs("key", "fingerprint", lockedUntil, expires).
+					WillReturnRows(pgxmock.NewRows([]string{"lock_token"}).AddRow("token"))
+				mock.ExpectCommit()
+			},
+			expected: "token",
+		},
+		{
+			name: "Key is already in use",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockKey)).WithArgs("key", "fingerprint", lockedUntil, expires).
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectCommit()
+			},
+			expected: "",
+		},
+		{
+			name: "Error in database",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockKey)).WithArgs("key", "fingerprint", lockedUntil, expires).
+					WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+	}
+
+	var got string
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+			got, err = storage.Lock(context.Background(), "key", "fingerprint", lockedUntil, expires)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorage_Get(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	expires := time.Now().Add(time.Hour)
+	columns := []string{"key", "fingerprint", "status_code", "content_type", "response", "expires"}
+
+	tests := []struct {
+		name     string
+		mock     func()
+		expected *models.IdempotencyRecord
+	}{
+		{
+			name: "Completed request",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetKey)).WithArgs("key").WillReturnRows(
+					pgxmock.NewRows(columns).AddRow("key", "fingerprint", int32(200), "application/json", []byte(`{}`), expires))
+				mock.ExpectCommit()
+			},
+			expected: &models.IdempotencyRecord{
+				Key:         "key",
+				Fingerprint: "fingerprint",
+				StatusCode:  200,
+				ContentType: "application/json",
+				Response:    []byte(`{}`),
+				Expires:     expires,
+			},
+		},
+		{
+			name: "Request in progress",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetKey)).WithArgs("key").WillReturnRows(
+					pgxmock.NewRows(columns).AddRow("key", "fingerprint", nil, nil, nil, expires))
+				mock.ExpectCommit()
+			},
+			expected: &models.IdempotencyRecord{
+				Key:         "key",
+				Fingerprint: "fingerprint",
+				Expires:     expires,
+			},
+		},
+		{
+			name: "Key not found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetKey)).WithArgs("key").WillReturnError(pgx.ErrNoRows)
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+			got, err := storage.Get(context.Background(), "key")
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorage_SaveResponseAndDelete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	lock := idempotency.Lock{Key: "key", Token: "token"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(querySaveResponse)).WithArgs(200, "application/json", []byte(`{}`), "key", "token").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	assert.NoError(t, storage.SaveResponse(context.Background(), lock, 200, "application/json", []byte(`{}`)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(querySaveResponse)).WithArgs(200, "application/json", []byte(`{}`), "key", "token").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectCommit()
+	assert.Equal(t, createdErrors.ErrIdempotencyKeyTakenOver,
+		storage.SaveResponse(context.Background(), lock, 200, "application/json", []byte(`{}`)))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(queryDeleteKey)).WithArgs("key", "token").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+	assert.NoError(t, storage.Delete(context.Background(), lock))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredKeys)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	mock.ExpectCommit()
+	deleted, err := storage.DeleteExpired(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApply(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	dbErr := errors.New("Error in database")
+	locked := idempotency.WithLock(context.Background(), idempotency.Lock{Key: "key", Token: "token"})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		mock func()
+		err  error
+	}{
+		{
+			name: "Request without idempotency key",
+			ctx:  context.Background(),
+			mock: func() {},
+		},
+		{
+			name: "Request holds the key",
+			ctx:  locked,
+			mock: func() {
+				mock.ExpectExec(regexp.QuoteMeta(queryApplyKey)).WithArgs("key", "token").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+		},
+		{
+			name: "Key was taken over by a retry",
+			ctx:  locked,
+			mock: func() {
+				mock.ExpectExec(regexp.QuoteMeta(queryApplyKey)).WithArgs("key", "token").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+			err: createdErrors.ErrIdempotencyKeyTakenOver,
+		},
+		{
+			name: "Error in database",
+			ctx:  locked,
+			mock: func() {
+				mock.ExpectExec(regexp.QuoteMeta(queryApplyKey)).WithArgs("key", "token").WillReturnError(dbErr)
+			},
+			err: dbErr,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			test.mock()
+			transaction, err := mock.Begin(context.Background())
+			if err != nil {
+				t.Fatalf("Could not begin transaction: %s", err)
+			}
+
+			assert.Equal(t, test.err, Apply(test.ctx, transaction))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
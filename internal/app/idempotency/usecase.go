@@ -0,0 +1,15 @@
+package idempotency
+
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+)
+
+//go:generate moq -out ./mock/idempotency_usecase_mock.go -pkg mock . Service:MockService
+type Service interface {
+	Start(context.Context, string, string) (*models.IdempotencyRecord, error)
+	Complete(context.Context, Lock, int, string, []byte) error
+	Release(context.Context, Lock) error
+	DeleteExpired(context.Context) (int64, error)
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"avito-tech-task/internal/app/idempotency"
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+type Service struct {
+	storage idempotency.Storage
+	ttl     time.Duration
+	// lockLease is the time the request has to complete, then the key can be taken over by a retry
+	lockLease time.Duration
+}
+
+func NewService(storage idempotency.Storage, ttl, lockLease time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = constants.DefaultIdempotencyKeyTTL
+	}
+	if lockLease <= 0 {
+		lockLease = constants.DefaultIdempotencyLockLease
+	}
+
+	return &Service{
+		storage:   storage,
+		ttl:       ttl,
+		lockLease: lockLease,
+	}
+}
+
+// Start locks the key for the request with the given fingerprint.
+// If the key was already used for the same request, the stored record is returned to replay the response.
+// A record without a response holds the lock token of the request that must be processed.
+func (s *Service) Start(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if len(key) == 0 || len(key) > constants.IdempotencyKeyMaxLength {
+		return nil, createdErrors.ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now()
+	token, err := s.storage.Lock(ctx, key, fingerprint, now.Add(s.lockLease), now.Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		return &models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, Token: token}, nil
+	}
+
+	record, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil { // the key was released by a failed request in the meantime
+		return nil, createdErrors.ErrIdempotencyKeyInProgress
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, createdErrors.ErrIdempotencyKeyReused
+	}
+	if record.StatusCode == 0 {
+		return nil, createdErrors.ErrIdempotencyKeyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete stores the response of the processed request with its content type for replaying.
+// ErrIdempotencyKeyTakenOver is returned if the request lost the lock.
+func (s *Service) Complete(ctx context.Context, lock idempotency.Lock, statusCode int, contentType string,
+	response []byte) error {
+	return s.storage.SaveResponse(ctx, lock, statusCode, contentType, response)
+}
+
+// Release removes the key of the failed request, so that the client can retry it. The key is kept if the request
+// lost the lock or has already moved money.
+func (s *Service) Release(ctx context.Context, lock idempotency.Lock) error {
+	return s.storage.Delete(ctx, lock)
+}
+
+func (s *Service) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.storage.DeleteExpired(ctx)
+}
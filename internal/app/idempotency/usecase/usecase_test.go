@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	storageMock "avito-tech-task/internal/app/idempotency/mock"
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestService_Start(t *testing.T) {
+	storageError := errors.New("Error in storage")
+	record := &models.IdempotencyRecord{
+		Key:         "key",
+		Fingerprint: "fingerprint",
+		StatusCode:  200,
+		Response:    []byte(`{"user_id":1,"balance":10.00}`),
+	}
+
+	tests := []struct {
+		name        string
+		key         string
+		fingerprint string
+		storageMock *storageMock.MockStorage
+		expected    *models.IdempotencyRecord
+		expectedErr bool
+		err         error
+	}{
+		{
+			name:        "New key is locked for processing",
+			key:         "key",
+			fingerprint: "fingerprint",
+			storageMock: &storageMock.MockStorage{
+				LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+					return "token", nil
+				},
+			},
+			expected: &models.IdempotencyRecord{Key: "key", Fingerprint: "fingerprint", Token: "token"},
+		},
+		{
+			name:        "Stored response is replayed",
+			key:         "key",
+			fingerprint: "fingerprint",
+			storageMock: &storageMock.MockStorage{
+				LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+					return "", nil
+				},
+				GetFunc: func(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+					return record, nil
+				},
+			},
+			expected: record,
+		},
+		{
+			name:        "Key reused with a different request",
+			key:         "key",
+			fingerprint: "other fingerprint",
+			storageMock: &storageMock.MockStorage{
+				LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+					return "", nil
+				},
+				GetFunc: func(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+					return record, nil
+				},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "Request with the key is in progress",
+			key:         "key",
+			fingerprint: "fingerprint",
+			storageMock: &storageMock.MockStorage{
+				LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+					return "", nil
+				},
+				GetFunc: func(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+					return &models.IdempotencyRecord{Key: "key", Fingerprint: "fingerprint"}, nil
+				},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrIdempotencyKeyInProgress,
+		},
+		{
+			name:        "Error in storage",
+			key:         "key",
+			fingerprint: "fingerprint",
+			storageMock: &storageMock.MockStorage{
+				LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+					return "", storageError
+				},
+			},
+			expectedErr: true,
+			err:         storageError,
+		},
+		{
+			name:        "Too long key",
+			key:         strings.Repeat("k", 256),
+			fingerprint: "fingerprint",
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidIdempotencyKey,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			service := NewService(test.storageMock, time.Hour, time.Minute)
+
+			got, err := service.Start(context.Background(), test.key, test.fingerprint)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_StartUsesTTLAndLockLease(t *testing.T) {
+	storage := &storageMock.MockStorage{
+		LockFunc: func(ctx context.Context, key string, fingerprint string, lockedUntil time.Time, expires time.Time) (string, error) {
+			return "token", nil
+		},
+	}
+	service := NewService(storage, time.Hour, time.Minute)
+
+	_, err := service.Start(context.Background(), "key", "fingerprint")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), storage.LockCalls()[0].TimeMoqParam1, time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), storage.LockCalls()[0].TimeMoqParam2, time.Second)
+}
@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Handlers struct {
+	logger *logrus.Logger
+}
+
+func NewHandlers(logger *logrus.Logger) *Handlers {
+	return &Handlers{
+		logger: logger,
+	}
+}
+
+// InitHandlers registers logging routes, all of them are guarded by admin middleware.
+func (h *Handlers) InitHandlers(server *echo.Echo, admin echo.MiddlewareFunc) {
+	server.GET("/api/v1/logging/level", h.GetLogLevel, admin)
+	server.PUT("/api/v1/logging/level", h.SetLogLevel, admin)
+}
+
+// GetLogLevel
+// @Summary 	Get the current log level
+// @Produce 	json
+// @Param 		Authorization header string true "Bearer admin token"
+// @Success 	200 {object} models.LogLevel
+// @Failure		401 {object} models.Problem "Invalid admin token"
+// @Failure		403 {object} models.Problem "Admin endpoints are disabled"
+// @Router 		/logging/level [GET]
+func (h *Handlers) GetLogLevel(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, models.LogLevel{Level: h.logger.GetLevel().String()})
+}
+
+// SetLogLevel
+// @Summary 	Change the log level until restart, the level of the config file is set again on SIGHUP
+// @Produce 	json
+// @Param 		Authorization header string true "Bearer admin token"
+// @Param 		data body models.LogLevel true "New log level"
+// @Success 	200 {object} models.LogLevel
+// @Failure		400 {object} models.Problem "Invalid request body"
+// @Failure		401 {object} models.Problem "Invalid admin token"
+// @Failure		403 {object} models.Problem "Admin endpoints are disabled"
+// @Failure		422 {object} models.Problem "Unknown log level"
+// @Router 		/logging/level [PUT]
+func (h *Handlers) SetLogLevel(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	var level models.LogLevel
+	if err := ctx.Bind(&level); err != nil {
+		log.WithError(err).Warn("Could not bind request body to models.LogLevel")
+		return createdErrors.ErrInvalidBody
+	}
+
+	if err := utils.SetLogLevel(h.logger, level.Level); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, models.LogLevel{Level: h.logger.GetLevel().String()})
+}
@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+func TestHandlers_LogLevel(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		authorization  string
+		expectedStatus int
+		expected       interface{}
+		expectedLevel  logrus.Level
+	}{
+		{
+			name:           "Get current level",
+			method:         echo.GET,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusOK,
+			expected:       models.LogLevel{Level: "info"},
+			expectedLevel:  logrus.InfoLevel,
+		},
+		{
+			name:           "Successfully changed level",
+			method:         echo.PUT,
+			body:           `{"level": "debug"}`,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusOK,
+			expected:       models.LogLevel{Level: "debug"},
+			expectedLevel:  logrus.DebugLevel,
+		},
+		{
+			name:           "Unknown level",
+			method:         echo.PUT,
+			body:           `{"level": "verbose"}`,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %q", createdErrors.ErrInvalidLogLevel, "verbose"), ""),
+			expectedLevel:  logrus.InfoLevel,
+		},
+		{
+			name:           "Invalid body",
+			method:         echo.PUT,
+			body:           `{"level": 1}`,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
+			expectedLevel:  logrus.InfoLevel,
+		},
+		{
+			name:           "Invalid admin token",
+			method:         echo.PUT,
+			body:           `{"level": "debug"}`,
+			authorization:  "Bearer guess",
+			expectedStatus: http.StatusUnauthorized,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAdminToken, ""),
+			expectedLevel:  logrus.InfoLevel,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(&strings.Builder{})
+			logger.SetLevel(logrus.InfoLevel)
+
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+			server.Use(utils.RequestLogger(logger))
+			NewHandlers(logger).InitHandlers(server, utils.AdminOnly("secret"))
+
+			req := httptest.NewRequest(test.method, "/api/v1/logging/level", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, test.authorization)
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			expectedString, _ := json.Marshal(test.expected)
+			assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+			assert.Equal(t, test.expectedLevel, logger.GetLevel())
+		})
+	}
+}
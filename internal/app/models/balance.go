@@ -1,7 +1,12 @@
 package models
 
+import "avito-tech-task/internal/pkg/money"
+
 type RequestUpdateBalance struct {
-	UserID        int64   `json:"user_id,omitempty" param:"user_id" validate:"gt=0"`
-	OperationType int     `json:"operation_type,omitempty" form:"operation_type" validate:"operation_type"`
-	Amount        float64 `json:"amount,omitempty" form:"amount" validate:"required"`
+	UserID        int64       `json:"user_id,omitempty" param:"user_id" validate:"gt=0"`
+	OperationType int         `json:"operation_type,omitempty" form:"operation_type" validate:"operation_type"`
+	Amount        money.Money `json:"amount,omitempty" form:"amount" validate:"required,gt=0" swaggertype:"number" example:"1000.50"`
+	// Currency is the wallet to update, RUB by default
+	Currency string `json:"currency,omitempty" form:"currency" validate:"omitempty,iso4217" example:"RUB"`
+	Purpose
 }
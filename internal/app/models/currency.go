@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type CurrencyRate struct {
+	Currency string `json:"currency" example:"USD"`
+	// Rate is the amount of the currency for one unit of the base currency
+	Rate float64 `json:"rate" example:"0.0131"`
+	// Digits is the number of minor unit digits of the currency according to ISO 4217
+	Digits int `json:"digits" example:"2"`
+}
+
+// CurrencyRates is the actual rates used for conversion.
+type CurrencyRates struct {
+	Base string `json:"base" example:"RUB"`
+	// Date is the day the rates were set for by the provider
+	Date   string `json:"date" example:"2022-01-19"`
+	Source string `json:"source,omitempty" example:"cbr_json"`
+	// Fetched is the time the rates were received from the provider, it is absent if they never were
+	Fetched *time.Time `json:"fetched,omitempty"`
+	// AgeSeconds is the number of seconds passed since the rates were fetched
+	AgeSeconds *int64 `json:"age_seconds,omitempty" example:"3600"`
+	// Stale is true if the rates are older than the requested max age
+	Stale bool           `json:"stale"`
+	Rates []CurrencyRate `json:"rates"`
+}
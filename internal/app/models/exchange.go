@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"avito-tech-task/internal/pkg/money"
+)
+
+type ExchangeQuoteRequest struct {
+	UserID int64  `json:"user_id,omitempty" form:"user_id" validate:"gt=0" example:"1"`
+	From   string `json:"from,omitempty" form:"from" validate:"required,iso4217" example:"RUB"`
+	To     string `json:"to,omitempty" form:"to" validate:"required,iso4217" example:"USD"`
+}
+
+// ExchangeQuote locks the rate of the exchange for the user until it expires.
+type ExchangeQuote struct {
+	ID     string `json:"quote_id" example:"0b6b2a4e-8a52-4f4a-9a55-2f2c6f0f8d1e"`
+	UserID int64  `json:"user_id"`
+	From   string `json:"from" example:"RUB"`
+	To     string `json:"to" example:"USD"`
+	// Rate is the amount of to for one unit of from, the spread is already applied
+	Rate    float64   `json:"rate" example:"0.013"`
+	Expires time.Time `json:"expires"`
+}
+
+type ExchangeRequest struct {
+	UserID int64       `json:"user_id,omitempty" form:"user_id" validate:"gt=0" example:"1"`
+	From   string      `json:"from,omitempty" form:"from" validate:"required,iso4217" example:"RUB"`
+	To     string      `json:"to,omitempty" form:"to" validate:"required,iso4217" example:"USD"`
+	Amount money.Money `json:"amount,omitempty" form:"amount" validate:"required,gt=0" swaggertype:"number" example:"1000.50"`
+	// QuoteID exchanges at the rate of the quote, the current rate is used if it is empty
+	QuoteID string `json:"quote_id,omitempty" form:"quote_id" validate:"omitempty,uuid"`
+	Purpose
+}
+
+// ExchangeResult is the wallets of the user after the exchange.
+type ExchangeResult struct {
+	// Amount is debited from the wallet in from, Received is credited to the wallet in to
+	Amount   money.Money `json:"amount" swaggertype:"number"`
+	Received money.Money `json:"received" swaggertype:"number"`
+	Rate     float64     `json:"rate"`
+	From     *UserData   `json:"from"`
+	To       *UserData   `json:"to"`
+}
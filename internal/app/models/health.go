@@ -0,0 +1,14 @@
+package models
+
+// Health is the status of the service, ok or fail, with the results of its checks.
+type Health struct {
+	Status string                  `json:"status" example:"ok"`
+	Checks map[string]*HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the result of the check of one dependency of the service.
+type HealthCheck struct {
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty" example:"database is unavailable"`
+}
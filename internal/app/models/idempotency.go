@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	StatusCode  int // zero while the request is still being processed
+	ContentType string
+	Response    []byte
+	Expires     time.Time
+	Token       string // lock token of the request that must be processed, set only for such requests
+}
@@ -0,0 +1,6 @@
+package models
+
+// LogLevel is the level of the service logger, one of panic, fatal, error, warn, info, debug and trace.
+type LogLevel struct {
+	Level string `json:"level" example:"info"`
+}
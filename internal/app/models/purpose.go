@@ -0,0 +1,16 @@
+package models
+
+// Purpose describes where money came from and why it was credited or debited, all its fields are optional.
+type Purpose struct {
+	Comment string `json:"comment,omitempty" form:"comment" validate:"max=1024" example:"Payment for order #10"`
+	// Reason is a short machine-readable category of the operation, history can be filtered by it
+	Reason string  `json:"reason,omitempty" form:"reason" validate:"max=64" example:"order_payment"`
+	Source *Source `json:"source,omitempty"`
+}
+
+// Source identifies the object the money movement is related to.
+type Source struct {
+	OrderID   int64  `json:"order_id,omitempty" validate:"gte=0" example:"10"`
+	ServiceID int64  `json:"service_id,omitempty" validate:"gte=0" example:"100"`
+	PromoCode string `json:"promo_code,omitempty" validate:"max=64" example:"WELCOME100"`
+}
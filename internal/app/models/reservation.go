@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"avito-tech-task/internal/pkg/money"
+)
+
+type ReservationRequest struct {
+	UserID    int64 `json:"user_id,omitempty" form:"user_id" validate:"gt=0" example:"1"`
+	OrderID   int64 `json:"order_id,omitempty" form:"order_id" validate:"gt=0" example:"10"`
+	ServiceID int64 `json:"service_id,omitempty" form:"service_id" validate:"gt=0" example:"100"`
+	// Amount is only used to reserve money, commit and cancel take the amount of the reservation
+	Amount money.Money `json:"amount,omitempty" form:"amount" validate:"required,gt=0" swaggertype:"number" example:"1000.50"`
+}
+
+type Reservation struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	OrderID   int64       `json:"order_id"`
+	ServiceID int64       `json:"service_id"`
+	Amount    money.Money `json:"amount" swaggertype:"number"`
+	Status    string      `json:"status"`
+	Created   time.Time   `json:"created"`
+	Updated   time.Time   `json:"updated"`
+}
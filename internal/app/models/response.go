@@ -1,5 +1,14 @@
 package models
 
-type ResponseMessage struct {
-	Message string `json:"message,omitempty"`
+// Problem is the RFC 7807 problem details of a failed request, it is sent as application/problem+json.
+type Problem struct {
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Unprocessable Entity"`
+	Status int    `json:"status" example:"422"`
+	Detail string `json:"detail,omitempty" example:"not enough money on balance"`
+	// Code is a stable machine-readable identifier of the error
+	Code string `json:"code" example:"not_enough_money"`
+	// CorrelationID identifies the log record of an internal error, details of it are not shown
+	CorrelationID string   `json:"correlation_id,omitempty" example:"5f0c2a6e9d1b4c3a8e7f6d5c4b3a2910"`
+	Supported     []string `json:"supported_currencies,omitempty" example:"EUR,RUB,USD"`
 }
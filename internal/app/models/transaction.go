@@ -1,20 +1,82 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"avito-tech-task/internal/pkg/money"
+)
 
 type Transaction struct {
-	OperationType string    `json:"operation_type"`
-	ReceiverID    int64     `json:"receiver_id,omitempty"`
-	Amount        float64   `json:"amount"`
-	Created       time.Time `json:"created"`
+	ID            int64  `json:"id"`
+	OperationType string `json:"operation_type"`
+	// Direction is incoming if the operation credited the user balance and outgoing otherwise
+	Direction string `json:"direction"`
+	// CounterpartyID is the other user of a transfer
+	CounterpartyID int64       `json:"counterparty_id,omitempty"`
+	Amount         money.Money `json:"amount" swaggertype:"number"`
+	// BalanceAfter is the balance of the user wallet after the operation, it is missing for operations
+	// that do not change the balance such as commit of a reservation
+	BalanceAfter *money.Money `json:"balance_after,omitempty" swaggertype:"number"`
+	Created      time.Time    `json:"created"`
+	// Currency is the currency of amount
+	Currency string `json:"currency" example:"RUB"`
+	// ReceiverCurrency, ReceiverAmount and Rate are set for transfers with conversion,
+	// the receiver got receiver_amount = amount * rate
+	ReceiverCurrency string       `json:"receiver_currency,omitempty" example:"USD"`
+	ReceiverAmount   *money.Money `json:"receiver_amount,omitempty" swaggertype:"number"`
+	Rate             float64      `json:"rate,omitempty"`
+	Purpose
+	// Converted is set if the list was requested in another currency
+	Converted *ConvertedAmount `json:"converted,omitempty"`
+}
+
+// ConvertedAmount is the transaction in another currency at the rate of the day it was created.
+type ConvertedAmount struct {
+	Currency string `json:"currency" example:"USD"`
+	// Amount and BalanceAfter have as many fractional digits as the minor unit of the currency, e.g. none for JPY
+	Amount       money.Amount  `json:"amount" swaggertype:"number" example:"13.10"`
+	BalanceAfter *money.Amount `json:"balance_after,omitempty" swaggertype:"number" example:"19.65"`
+	// Rate is the amount of currency for one unit of the transaction currency
+	Rate float64 `json:"rate"`
+	// RateDate is the date of the rate used, it is the closest date at or before the transaction date in UTC
+	RateDate string `json:"rate_date" example:"2022-01-19"`
 }
 
 type TransactionsSelectionParams struct {
-	Limit         int    `json:"limit,omitempty" form:"limit" validate:"gte=0"`
-	Since         string `json:"since,omitempty" form:"since"`
-	OperationType int    `json:"operation_type,omitempty" form:"operation_type"`
-	OrderAmount   bool   `json:"order_amount,omitempty" form:"order_amount"`
-	OrderDate     bool   `json:"order_date,omitempty" form:"order_date"`
+	Limit int `json:"limit,omitempty" form:"limit" validate:"gte=0"`
+	// Since selects transactions created at or after it, it is the same as From
+	Since         *time.Time `json:"since,omitempty" form:"since"`
+	OperationType int        `json:"operation_type,omitempty" form:"operation_type"`
+	OrderAmount   bool       `json:"order_amount,omitempty" form:"order_amount"`
+	OrderDate     bool       `json:"order_date,omitempty" form:"order_date"`
+	// Order is the direction of sorting, desc by default
+	Order  string `json:"order,omitempty" form:"order" validate:"omitempty,oneof=asc desc" enums:"asc,desc"`
+	Reason string `json:"reason,omitempty" form:"reason"`
+	// Cursor is next_cursor of the previous page, it must be used with the same sorting
+	Cursor string `json:"cursor,omitempty" form:"cursor"`
+	// From and To select transactions created in [from, to)
+	From *time.Time `json:"from,omitempty" form:"from"`
+	To   *time.Time `json:"to,omitempty" form:"to"`
+	// MinAmount and MaxAmount select transactions with amount in [min_amount, max_amount]
+	MinAmount *money.Money `json:"min_amount,omitempty" form:"min_amount" validate:"omitempty,gte=0" swaggertype:"number"`
+	MaxAmount *money.Money `json:"max_amount,omitempty" form:"max_amount" validate:"omitempty,gte=0" swaggertype:"number"`
+	// OperationTypes selects transactions of any of the types, it is combined with OperationType
+	OperationTypes []int `json:"operation_types,omitempty" form:"operation_types"`
+	// CounterpartyIDs selects transfers from and to any of the users
+	CounterpartyIDs []int64 `json:"counterparty_ids,omitempty" form:"counterparty_ids"`
+	// Sort is a list of amount and created keys with optional direction, e.g. amount:asc,created:desc,
+	// it overrides order_amount, order_date and order
+	Sort string `json:"sort,omitempty" form:"sort" example:"amount:asc,created:desc"`
+	// Currency converts every transaction at the rate of the day it was created
+	Currency string `json:"currency,omitempty" form:"currency" example:"USD"`
 }
 
 type Transactions []*Transaction
+
+// TransactionsPage is one page of the transactions list.
+type TransactionsPage struct {
+	Items Transactions `json:"items"`
+	// NextCursor is passed as cursor to get the next page, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
@@ -1,9 +1,17 @@
 package models
 
+import "avito-tech-task/internal/pkg/money"
+
 type TransferRequest struct {
-	SenderID   int64   `json:"sender_id,omitempty" form:"sender_id" validate:"required" example:"1"`
-	ReceiverID int64   `json:"receiver_id,omitempty" form:"receiver_id" validate:"required" example:"2"`
-	Amount     float64 `json:"amount,omitempty" form:"amount" validate:"required" example:"1000"`
+	SenderID   int64       `json:"sender_id,omitempty" form:"sender_id" validate:"required" example:"1"`
+	ReceiverID int64       `json:"receiver_id,omitempty" form:"receiver_id" validate:"required" example:"2"`
+	Amount     money.Money `json:"amount,omitempty" form:"amount" validate:"required,gt=0" swaggertype:"number" example:"1000.50"`
+	// Currency is the sender wallet and the currency of amount, RUB by default
+	Currency string `json:"currency,omitempty" form:"currency" validate:"omitempty,iso4217" example:"RUB"`
+	// ReceiverCurrency is the receiver wallet, it is the sender currency by default. If it differs,
+	// amount is converted at the current rate
+	ReceiverCurrency string `json:"receiver_currency,omitempty" form:"receiver_currency" validate:"omitempty,iso4217" example:"USD"`
+	Purpose
 }
 
 type TransferUsersData struct {
@@ -1,6 +1,22 @@
 package models
 
+import "avito-tech-task/internal/pkg/money"
+
 type UserData struct {
-	UserID  int64   `json:"user_id,omitempty"`
-	Balance float64 `json:"balance,omitempty"`
+	UserID   int64       `json:"user_id,omitempty"`
+	Balance  money.Money `json:"balance,omitempty" swaggertype:"number"`
+	Reserved money.Money `json:"reserved,omitempty" swaggertype:"number"`
+	Currency string      `json:"currency,omitempty" example:"RUB"`
+}
+
+// Balance is the wallet of the user converted to the currency, amounts are rounded to its minor units.
+type Balance struct {
+	UserID   int64        `json:"user_id"`
+	Wallet   string       `json:"wallet" example:"RUB"`
+	Balance  money.Amount `json:"balance" swaggertype:"number" example:"13.10"`
+	Reserved money.Amount `json:"reserved" swaggertype:"number" example:"0.00"`
+	Currency string       `json:"currency" example:"USD"`
+	// Rate is the amount of currency for one unit of the wallet currency
+	Rate     float64 `json:"rate" example:"0.0131"`
+	RateDate string  `json:"rate_date" example:"2022-01-19"`
 }
@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/app/reserve"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Handlers struct {
+	service reserve.Service
+}
+
+func NewHandlers(service reserve.Service) *Handlers {
+	return &Handlers{
+		service: service,
+	}
+}
+
+func (h *Handlers) InitHandlers(server *echo.Echo) {
+	server.POST("/api/v1/reserve", h.Reserve)
+	server.POST("/api/v1/reserve/commit", h.Commit)
+	server.POST("/api/v1/reserve/cancel", h.Cancel)
+}
+
+// Reserve
+// @Summary 	Reserve money on user balance for an order
+// @Produce 	json
+// @Param 		data body models.ReservationRequest true "Order, service and amount to reserve"
+// @Success 	200 {object} models.Reservation
+// @Failure		400 {object} models.Problem "Invalid request body | malformed amount"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		409 {object} models.Problem "Reservation already exists"
+// @Failure		422 {object} models.Problem "Not enough money | Amount field is required | Invalid IDs"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/reserve [POST]
+func (h *Handlers) Reserve(ctx echo.Context) error {
+	return h.handle(ctx, h.service.Reserve)
+}
+
+// Commit
+// @Summary 	Recognize reserved money as revenue
+// @Produce 	json
+// @Param 		data body models.ReservationRequest true "Order and service of the reservation"
+// @Success 	200 {object} models.Reservation
+// @Failure		400 {object} models.Problem "Invalid request body"
+// @Failure		404 {object} models.Problem "Reservation not found"
+// @Failure		422 {object} models.Problem "Invalid IDs"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/reserve/commit [POST]
+func (h *Handlers) Commit(ctx echo.Context) error {
+	return h.handle(ctx, h.service.Commit)
+}
+
+// Cancel
+// @Summary 	Release reserved money back to user balance
+// @Produce 	json
+// @Param 		data body models.ReservationRequest true "Order and service of the reservation"
+// @Success 	200 {object} models.Reservation
+// @Failure		400 {object} models.Problem "Invalid request body"
+// @Failure		404 {object} models.Problem "Reservation not found"
+// @Failure		422 {object} models.Problem "Invalid IDs"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/reserve/cancel [POST]
+func (h *Handlers) Cancel(ctx echo.Context) error {
+	return h.handle(ctx, h.service.Cancel)
+}
+
+func (h *Handlers) handle(ctx echo.Context,
+	action func(context.Context, *models.ReservationRequest) (*models.Reservation, error)) error {
+	log := utils.Log(ctx.Request().Context())
+	var data models.ReservationRequest
+	if err := ctx.Bind(&data); err != nil {
+		log.WithError(err).Warn("Could not bind request body to models.ReservationRequest")
+		return utils.BindError(err)
+	}
+	log = utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": data.UserID})
+	log.WithField("request", data).Debug("Request data")
+
+	reservation, err := action(ctx.Request().Context(), &data)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("response", reservation).Debug("Request was processed")
+	return ctx.JSON(http.StatusOK, reservation)
+}
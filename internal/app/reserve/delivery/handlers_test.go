@@ -0,0 +1,157 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/app/reserve/mock"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+// requestID is the correlation ID of internal errors in responses of tests
+const requestID = "test-request-id"
+
+func TestHandlers_Reservations(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	internalServerErr := errors.New("Internal server error")
+	reservation := &models.Reservation{ID: 1, UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000, Status: "reserved"}
+	tests := []struct {
+		name           string
+		serviceMock    *mock.MockService
+		handler        func(*Handlers) echo.HandlerFunc
+		body           string
+		expectedStatus int
+		expected       interface{}
+	}{
+		{
+			name: "Successfully reserved money",
+			serviceMock: &mock.MockService{
+				ReserveFunc: func(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+					return reservation, nil
+				},
+			},
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Reserve },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100, "amount": 10}`,
+			expectedStatus: http.StatusOK,
+			expected:       reservation,
+		},
+		{
+			name:           "Malformed amount",
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Reserve },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100, "amount": 10.001}`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidAmount, ""),
+		},
+		{
+			name:           "Invalid body",
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Reserve },
+			body:           `{"user_id": 1, "order_id": "tenth", "service_id": 100, "amount": 10}`,
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
+		},
+		{
+			name: "Not enough money",
+			serviceMock: &mock.MockService{
+				ReserveFunc: func(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+					return nil, createdErrors.ErrNotEnoughMoney
+				},
+			},
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Reserve },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100, "amount": 10}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrNotEnoughMoney, ""),
+		},
+		{
+			name: "Reservation already exists",
+			serviceMock: &mock.MockService{
+				ReserveFunc: func(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+					return nil, createdErrors.ErrReservationAlreadyExists
+				},
+			},
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Reserve },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100, "amount": 10}`,
+			expectedStatus: http.StatusConflict,
+			expected:       utils.NewProblem(createdErrors.ErrReservationAlreadyExists, ""),
+		},
+		{
+			name: "Reservation to commit not found",
+			serviceMock: &mock.MockService{
+				CommitFunc: func(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+					return nil, createdErrors.ErrReservationDoesNotExist
+				},
+			},
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Commit },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100}`,
+			expectedStatus: http.StatusNotFound,
+			expected:       utils.NewProblem(createdErrors.ErrReservationDoesNotExist, ""),
+		},
+		{
+			name: "Internal server error during cancelling reservation",
+			serviceMock: &mock.MockService{
+				CancelFunc: func(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+					return nil, internalServerErr
+				},
+			},
+			handler:        func(h *Handlers) echo.HandlerFunc { return h.Cancel },
+			body:           `{"user_id": 1, "order_id": 10, "service_id": 100}`,
+			expectedStatus: http.StatusInternalServerError,
+			expected:       utils.NewProblem(internalServerErr, requestID),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := server.NewContext(req, rec)
+
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(test.handler(handlers))(ctx)) {
+				assert.Equal(t, test.expectedStatus, rec.Code)
+
+				expectedString, _ := json.Marshal(test.expected)
+				assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+			}
+		})
+	}
+}
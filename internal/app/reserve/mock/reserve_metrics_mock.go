@@ -0,0 +1,132 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/reserve"
+	"avito-tech-task/internal/pkg/money"
+	"sync"
+)
+
+// Ensure, that MockMetrics does implement reserve.Metrics.
+// If this is not the case, regenerate this file with moq.
+var _ reserve.Metrics = &MockMetrics{}
+
+// MockMetrics is a mock implementation of reserve.Metrics.
+//
+//	func TestSomethingThatUsesMetrics(t *testing.T) {
+//
+//		// make and configure a mocked reserve.Metrics
+//		mockedMetrics := &MockMetrics{
+//			InsufficientFundsFunc: func(operationType string)  {
+//				panic("mock out the InsufficientFunds method")
+//			},
+//			TransactionCommittedFunc: func(operationType string, currency string, amount money.Money)  {
+//				panic("mock out the TransactionCommitted method")
+//			},
+//		}
+//
+//		// use mockedMetrics in code that requires reserve.Metrics
+//		// and then make assertions.
+//
+//	}
+type MockMetrics struct {
+	// InsufficientFundsFunc mocks the InsufficientFunds method.
+	InsufficientFundsFunc func(operationType string)
+
+	// TransactionCommittedFunc mocks the TransactionCommitted method.
+	TransactionCommittedFunc func(operationType string, currency string, amount money.Money)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// InsufficientFunds holds details about calls to the InsufficientFunds method.
+		InsufficientFunds []struct {
+			// OperationType is the operationType argument value.
+			OperationType string
+		}
+		// TransactionCommitted holds details about calls to the TransactionCommitted method.
+		TransactionCommitted []struct {
+			// OperationType is the operationType argument value.
+			OperationType string
+			// Currency is the currency argument value.
+			Currency string
+			// Amount is the amount argument value.
+			Amount money.Money
+		}
+	}
+	lockInsufficientFunds    sync.RWMutex
+	lockTransactionCommitted sync.RWMutex
+}
+
+// InsufficientFunds calls InsufficientFundsFunc.
+func (mock *MockMetrics) InsufficientFunds(operationType string) {
+	if mock.InsufficientFundsFunc == nil {
+		panic("MockMetrics.InsufficientFundsFunc: method is nil but Metrics.InsufficientFunds was just called")
+	}
+	callInfo := struct {
+		OperationType string
+	}{
+		OperationType: operationType,
+	}
+	mock.lockInsufficientFunds.Lock()
+	mock.calls.InsufficientFunds = append(mock.calls.InsufficientFunds, callInfo)
+	mock.lockInsufficientFunds.Unlock()
+	mock.InsufficientFundsFunc(operationType)
+}
+
+// InsufficientFundsCalls gets all the calls that were made to InsufficientFunds.
+// Check the length with:
+//
+//	len(mockedMetrics.InsufficientFundsCalls())
+func (mock *MockMetrics) InsufficientFundsCalls() []struct {
+	OperationType string
+} {
+	var calls []struct {
+		OperationType string
+	}
+	mock.lockInsufficientFunds.RLock()
+	calls = mock.calls.InsufficientFunds
+	mock.lockInsufficientFunds.RUnlock()
+	return calls
+}
+
+// TransactionCommitted calls TransactionCommittedFunc.
+func (mock *MockMetrics) TransactionCommitted(operationType string, currency string, amount money.Money) {
+	if mock.TransactionCommittedFunc == nil {
+		panic("MockMetrics.TransactionCommittedFunc: method is nil but Metrics.TransactionCommitted was just called")
+	}
+	callInfo := struct {
+		OperationType string
+		Currency      string
+		Amount        money.Money
+	}{
+		OperationType: operationType,
+		Currency:      currency,
+		Amount:        amount,
+	}
+	mock.lockTransactionCommitted.Lock()
+	mock.calls.TransactionCommitted = append(mock.calls.TransactionCommitted, callInfo)
+	mock.lockTransactionCommitted.Unlock()
+	mock.TransactionCommittedFunc(operationType, currency, amount)
+}
+
+// TransactionCommittedCalls gets all the calls that were made to TransactionCommitted.
+// Check the length with:
+//
+//	len(mockedMetrics.TransactionCommittedCalls())
+func (mock *MockMetrics) TransactionCommittedCalls() []struct {
+	OperationType string
+	Currency      string
+	Amount        money.Money
+} {
+	var calls []struct {
+		OperationType string
+		Currency      string
+		Amount        money.Money
+	}
+	mock.lockTransactionCommitted.RLock()
+	calls = mock.calls.TransactionCommitted
+	mock.lockTransactionCommitted.RUnlock()
+	return calls
+}
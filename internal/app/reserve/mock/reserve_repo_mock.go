@@ -0,0 +1,226 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/app/reserve"
+	"avito-tech-task/internal/pkg/money"
+	"context"
+	"sync"
+)
+
+// Ensure, that MockStorage does implement reserve.Storage.
+// If this is not the case, regenerate this file with moq.
+var _ reserve.Storage = &MockStorage{}
+
+// MockStorage is a mock implementation of reserve.Storage.
+//
+//	func TestSomethingThatUsesStorage(t *testing.T) {
+//
+//		// make and configure a mocked reserve.Storage
+//		mockedStorage := &MockStorage{
+//			CancelFunc: func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error) {
+//				panic("mock out the Cancel method")
+//			},
+//			CommitFunc: func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error) {
+//				panic("mock out the Commit method")
+//			},
+//			ReserveFunc: func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64, moneyMoqParam money.Money) (*models.Reservation, error) {
+//				panic("mock out the Reserve method")
+//			},
+//		}
+//
+//		// use mockedStorage in code that requires reserve.Storage
+//		// and then make assertions.
+//
+//	}
+type MockStorage struct {
+	// CancelFunc mocks the Cancel method.
+	CancelFunc func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error)
+
+	// CommitFunc mocks the Commit method.
+	CommitFunc func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error)
+
+	// ReserveFunc mocks the Reserve method.
+	ReserveFunc func(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64, moneyMoqParam money.Money) (*models.Reservation, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Cancel holds details about calls to the Cancel method.
+		Cancel []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// N1 is the n1 argument value.
+			N1 int64
+			// N2 is the n2 argument value.
+			N2 int64
+			// N3 is the n3 argument value.
+			N3 int64
+		}
+		// Commit holds details about calls to the Commit method.
+		Commit []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// N1 is the n1 argument value.
+			N1 int64
+			// N2 is the n2 argument value.
+			N2 int64
+			// N3 is the n3 argument value.
+			N3 int64
+		}
+		// Reserve holds details about calls to the Reserve method.
+		Reserve []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// N1 is the n1 argument value.
+			N1 int64
+			// N2 is the n2 argument value.
+			N2 int64
+			// N3 is the n3 argument value.
+			N3 int64
+			// MoneyMoqParam is the moneyMoqParam argument value.
+			MoneyMoqParam money.Money
+		}
+	}
+	lockCancel  sync.RWMutex
+	lockCommit  sync.RWMutex
+	lockReserve sync.RWMutex
+}
+
+// Cancel calls CancelFunc.
+func (mock *MockStorage) Cancel(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error) {
+	if mock.CancelFunc == nil {
+		panic("MockStorage.CancelFunc: method is nil but Storage.Cancel was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+	}{
+		ContextMoqParam: contextMoqParam,
+		N1:              n1,
+		N2:              n2,
+		N3:              n3,
+	}
+	mock.lockCancel.Lock()
+	mock.calls.Cancel = append(mock.calls.Cancel, callInfo)
+	mock.lockCancel.Unlock()
+	return mock.CancelFunc(contextMoqParam, n1, n2, n3)
+}
+
+// CancelCalls gets all the calls that were made to Cancel.
+// Check the length with:
+//
+//	len(mockedStorage.CancelCalls())
+func (mock *MockStorage) CancelCalls() []struct {
+	ContextMoqParam context.Context
+	N1              int64
+	N2              int64
+	N3              int64
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+	}
+	mock.lockCancel.RLock()
+	calls = mock.calls.Cancel
+	mock.lockCancel.RUnlock()
+	return calls
+}
+
+// Commit calls CommitFunc.
+func (mock *MockStorage) Commit(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64) (*models.Reservation, error) {
+	if mock.CommitFunc == nil {
+		panic("MockStorage.CommitFunc: method is nil but Storage.Commit was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+	}{
+		ContextMoqParam: contextMoqParam,
+		N1:              n1,
+		N2:              n2,
+		N3:              n3,
+	}
+	mock.lockCommit.Lock()
+	mock.calls.Commit = append(mock.calls.Commit, callInfo)
+	mock.lockCommit.Unlock()
+	return mock.CommitFunc(contextMoqParam, n1, n2, n3)
+}
+
+// CommitCalls gets all the calls that were made to Commit.
+// Check the length with:
+//
+//	len(mockedStorage.CommitCalls())
+func (mock *MockStorage) CommitCalls() []struct {
+	ContextMoqParam context.Context
+	N1              int64
+	N2              int64
+	N3              int64
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+	}
+	mock.lockCommit.RLock()
+	calls = mock.calls.Commit
+	mock.lockCommit.RUnlock()
+	return calls
+}
+
+// Reserve calls ReserveFunc.
+func (mock *MockStorage) Reserve(contextMoqParam context.Context, n1 int64, n2 int64, n3 int64, moneyMoqParam money.Money) (*models.Reservation, error) {
+	if mock.ReserveFunc == nil {
+		panic("MockStorage.ReserveFunc: method is nil but Storage.Reserve was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+		MoneyMoqParam   money.Money
+	}{
+		ContextMoqParam: contextMoqParam,
+		N1:              n1,
+		N2:              n2,
+		N3:              n3,
+		MoneyMoqParam:   moneyMoqParam,
+	}
+	mock.lockReserve.Lock()
+	mock.calls.Reserve = append(mock.calls.Reserve, callInfo)
+	mock.lockReserve.Unlock()
+	return mock.ReserveFunc(contextMoqParam, n1, n2, n3, moneyMoqParam)
+}
+
+// ReserveCalls gets all the calls that were made to Reserve.
+// Check the length with:
+//
+//	len(mockedStorage.ReserveCalls())
+func (mock *MockStorage) ReserveCalls() []struct {
+	ContextMoqParam context.Context
+	N1              int64
+	N2              int64
+	N3              int64
+	MoneyMoqParam   money.Money
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		N1              int64
+		N2              int64
+		N3              int64
+		MoneyMoqParam   money.Money
+	}
+	mock.lockReserve.RLock()
+	calls = mock.calls.Reserve
+	mock.lockReserve.RUnlock()
+	return calls
+}
@@ -0,0 +1,183 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/app/reserve"
+	"context"
+	"sync"
+)
+
+// Ensure, that MockService does implement reserve.Service.
+// If this is not the case, regenerate this file with moq.
+var _ reserve.Service = &MockService{}
+
+// MockService is a mock implementation of reserve.Service.
+//
+//	func TestSomethingThatUsesService(t *testing.T) {
+//
+//		// make and configure a mocked reserve.Service
+//		mockedService := &MockService{
+//			CancelFunc: func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+//				panic("mock out the Cancel method")
+//			},
+//			CommitFunc: func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+//				panic("mock out the Commit method")
+//			},
+//			ReserveFunc: func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+//				panic("mock out the Reserve method")
+//			},
+//		}
+//
+//		// use mockedService in code that requires reserve.Service
+//		// and then make assertions.
+//
+//	}
+type MockService struct {
+	// CancelFunc mocks the Cancel method.
+	CancelFunc func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error)
+
+	// CommitFunc mocks the Commit method.
+	CommitFunc func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error)
+
+	// ReserveFunc mocks the Reserve method.
+	ReserveFunc func(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Cancel holds details about calls to the Cancel method.
+		Cancel []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ReservationRequest is the reservationRequest argument value.
+			ReservationRequest *models.ReservationRequest
+		}
+		// Commit holds details about calls to the Commit method.
+		Commit []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ReservationRequest is the reservationRequest argument value.
+			ReservationRequest *models.ReservationRequest
+		}
+		// Reserve holds details about calls to the Reserve method.
+		Reserve []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// ReservationRequest is the reservationRequest argument value.
+			ReservationRequest *models.ReservationRequest
+		}
+	}
+	lockCancel  sync.RWMutex
+	lockCommit  sync.RWMutex
+	lockReserve sync.RWMutex
+}
+
+// Cancel calls CancelFunc.
+func (mock *MockService) Cancel(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+	if mock.CancelFunc == nil {
+		panic("MockService.CancelFunc: method is nil but Service.Cancel was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}{
+		ContextMoqParam:    contextMoqParam,
+		ReservationRequest: reservationRequest,
+	}
+	mock.lockCancel.Lock()
+	mock.calls.Cancel = append(mock.calls.Cancel, callInfo)
+	mock.lockCancel.Unlock()
+	return mock.CancelFunc(contextMoqParam, reservationRequest)
+}
+
+// CancelCalls gets all the calls that were made to Cancel.
+// Check the length with:
+//
+//	len(mockedService.CancelCalls())
+func (mock *MockService) CancelCalls() []struct {
+	ContextMoqParam    context.Context
+	ReservationRequest *models.ReservationRequest
+} {
+	var calls []struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}
+	mock.lockCancel.RLock()
+	calls = mock.calls.Cancel
+	mock.lockCancel.RUnlock()
+	return calls
+}
+
+// Commit calls CommitFunc.
+func (mock *MockService) Commit(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+	if mock.CommitFunc == nil {
+		panic("MockService.CommitFunc: method is nil but Service.Commit was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}{
+		ContextMoqParam:    contextMoqParam,
+		ReservationRequest: reservationRequest,
+	}
+	mock.lockCommit.Lock()
+	mock.calls.Commit = append(mock.calls.Commit, callInfo)
+	mock.lockCommit.Unlock()
+	return mock.CommitFunc(contextMoqParam, reservationRequest)
+}
+
+// CommitCalls gets all the calls that were made to Commit.
+// Check the length with:
+//
+//	len(mockedService.CommitCalls())
+func (mock *MockService) CommitCalls() []struct {
+	ContextMoqParam    context.Context
+	ReservationRequest *models.ReservationRequest
+} {
+	var calls []struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}
+	mock.lockCommit.RLock()
+	calls = mock.calls.Commit
+	mock.lockCommit.RUnlock()
+	return calls
+}
+
+// Reserve calls ReserveFunc.
+func (mock *MockService) Reserve(contextMoqParam context.Context, reservationRequest *models.ReservationRequest) (*models.Reservation, error) {
+	if mock.ReserveFunc == nil {
+		panic("MockService.ReserveFunc: method is nil but Service.Reserve was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}{
+		ContextMoqParam:    contextMoqParam,
+		ReservationRequest: reservationRequest,
+	}
+	mock.lockReserve.Lock()
+	mock.calls.Reserve = append(mock.calls.Reserve, callInfo)
+	mock.lockReserve.Unlock()
+	return mock.ReserveFunc(contextMoqParam, reservationRequest)
+}
+
+// ReserveCalls gets all the calls that were made to Reserve.
+// Check the length with:
+//
+//	len(mockedService.ReserveCalls())
+func (mock *MockService) ReserveCalls() []struct {
+	ContextMoqParam    context.Context
+	ReservationRequest *models.ReservationRequest
+} {
+	var calls []struct {
+		ContextMoqParam    context.Context
+		ReservationRequest *models.ReservationRequest
+	}
+	mock.lockReserve.RLock()
+	calls = mock.calls.Reserve
+	mock.lockReserve.RUnlock()
+	return calls
+}
@@ -0,0 +1,15 @@
+package reserve
+
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/money"
+)
+
+//go:generate moq -out ./mock/reserve_repo_mock.go -pkg mock . Storage:MockStorage
+type Storage interface {
+	Reserve(context.Context, int64, int64, int64, money.Money) (*models.Reservation, error)
+	Commit(context.Context, int64, int64, int64) (*models.Reservation, error)
+	Cancel(context.Context, int64, int64, int64) (*models.Reservation, error)
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/ledger"
+	"avito-tech-task/internal/pkg/money"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Storage struct {
+	db      utils.PgxIface
+	journal ledger.Writer
+}
+
+func NewStorage(conn utils.PgxIface) *Storage {
+	return &Storage{db: conn, journal: ledger.Journal{}}
+}
+
+const (
+	statusCommitted = "committed"
+	statusCancelled = "cancelled"
+
+	// reservations are always made from the wallet in the base currency
+	queryLockBalance       = `SELECT balance FROM balance WHERE user_id = $1 AND currency = 'RUB' FOR UPDATE`
+	queryUpdateBalance     = `UPDATE balance SET balance = balance + $1 WHERE user_id = $2 AND currency = 'RUB' RETURNING balance`
+	queryInsertReservation = `
+		INSERT INTO reservations (user_id, order_id, service_id, amount)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, order_id, service_id) DO NOTHING
+		RETURNING id, status, created, updated`
+	queryCloseReservation = `
+		UPDATE reservations SET status = $1, updated = now()
+		WHERE user_id = $2 AND order_id = $3 AND service_id = $4 AND status = 'reserved'
+		RETURNING id, amount, status, created, updated`
+)
+
+// Reserve moves amount from the user balance into a reservation for the order and service.
+func (s *Storage) Reserve(ctx context.Context, userID, orderID, serviceID int64,
+	amount money.Money) (_ *models.Reservation, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	var balance money.Money
+	if err = transaction.QueryRow(ctx, queryLockBalance, userID).Scan(&balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = createdErrors.ErrUserDoesNotExist
+		}
+		return nil, err
+	}
+	if balance < amount {
+		err = createdErrors.ErrNotEnoughMoney
+		return nil, err
+	}
+
+	reservation := &models.Reservation{
+		UserID:    userID,
+		OrderID:   orderID,
+		ServiceID: serviceID,
+		Amount:    amount,
+	}
+	if err = transaction.QueryRow(ctx, queryInsertReservation, userID, orderID, serviceID,
+		amount).Scan(&reservation.ID, &reservation.Status, &reservation.Created, &reservation.Updated); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = createdErrors.ErrReservationAlreadyExists
+		}
+		return nil, err
+	}
+
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, amount*-1, userID).Scan(&balance); err != nil {
+		return nil, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, &ledger.Entry{
+		OperationType: "reserve",
+		SenderID:      userID,
+		Amount:        amount,
+		Postings: []ledger.Posting{
+			ledger.User(userID, amount*-1, balance),
+			ledger.System(ledger.Reservations, amount),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// Commit recognizes reserved money as revenue, the money does not return to the user balance.
+func (s *Storage) Commit(ctx context.Context, userID, orderID, serviceID int64) (_ *models.Reservation, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	var reservation *models.Reservation
+	if reservation, err = closeReservation(ctx, transaction, statusCommitted, userID, orderID, serviceID); err != nil {
+		return nil, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, &ledger.Entry{
+		OperationType: "revenue",
+		SenderID:      userID,
+		Amount:        reservation.Amount,
+		Postings: []ledger.Posting{
+			ledger.System(ledger.Reservations, reservation.Amount*-1),
+			ledger.System(ledger.Revenue, reservation.Amount),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// Cancel releases reserved money back to the user balance.
+func (s *Storage) Cancel(ctx context.Context, userID, orderID, serviceID int64) (_ *models.Reservation, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	var reservation *models.Reservation
+	if reservation, err = closeReservation(ctx, transaction, statusCancelled, userID, orderID, serviceID); err != nil {
+		return nil, err
+	}
+	var balance money.Money
+	if err = transaction.QueryRow(ctx, queryUpdateBalance, reservation.Amount, userID).Scan(&balance); err != nil {
+		return nil, err
+	}
+	if _, err = s.journal.Write(ctx, transaction, &ledger.Entry{
+		OperationType: "release",
+		SenderID:      userID,
+		Amount:        reservation.Amount,
+		Postings: []ledger.Posting{
+			ledger.System(ledger.Reservations, reservation.Amount*-1),
+			ledger.User(userID, reservation.Amount, balance),
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func closeReservation(ctx context.Context, transaction pgx.Tx, status string,
+	userID, orderID, serviceID int64) (*models.Reservation, error) {
+	reservation := &models.Reservation{
+		UserID:    userID,
+		OrderID:   orderID,
+		ServiceID: serviceID,
+	}
+	if err := transaction.QueryRow(ctx, queryCloseReservation, status, userID, orderID,
+		serviceID).Scan(&reservation.ID, &reservation.Amount, &reservation.Status, &reservation.Created,
+		&reservation.Updated); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, createdErrors.ErrReservationDoesNotExist
+		}
+		return nil, err
+	}
+
+	return reservation, nil
+}
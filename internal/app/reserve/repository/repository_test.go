@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/ledger"
+	ledgerMock "avito-tech-task/internal/pkg/ledger/mock"
+	"avito-tech-task/internal/pkg/money"
+)
+
+func TestStorage_Reserve(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	dbErr := errors.New("Error in database")
+	timeNow := time.Now()
+
+	var (
+		userID    int64       = 1
+		orderID   int64       = 10
+		serviceID int64       = 100
+		amount    money.Money = 1000
+	)
+
+	tests := []struct {
+		name        string
+		mock        func()
+		entry       *ledger.Entry
+		ledgerErr   error
+		expected    *models.Reservation
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Successfully reserved money",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockBalance)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryInsertReservation)).WithArgs(userID, orderID, serviceID, amount).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "status", "created", "updated"}).
+						AddRow(int64(1), "reserved", timeNow, timeNow))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: "reserve",
+				SenderID:      userID,
+				Amount:        amount,
+				Postings: []ledger.Posting{
+					ledger.User(userID, amount*-1, 500),
+					ledger.System(ledger.Reservations, amount),
+				},
+			},
+			expected: &models.Reservation{
+				ID:        1,
+				UserID:    userID,
+				OrderID:   orderID,
+				ServiceID: serviceID,
+				Amount:    amount,
+				Status:    "reserved",
+				Created:   timeNow,
+				Updated:   timeNow,
+			},
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockBalance)).WithArgs(userID).WillReturnError(pgx.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrUserDoesNotExist,
+		},
+		{
+			name: "Not enough money",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockBalance)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotEnoughMoney,
+		},
+		{
+			name: "Reservation already exists",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockBalance)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryInsertReservation)).WithArgs(userID, orderID, serviceID, amount).
+					WillReturnError(pgx.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrReservationAlreadyExists,
+		},
+		{
+			name: "Error in database during saving transaction",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLockBalance)).WithArgs(userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectQuery(regexp.QuoteMeta(queryInsertReservation)).WithArgs(userID, orderID, serviceID, amount).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "status", "created", "updated"}).
+						AddRow(int64(1), "reserved", timeNow, timeNow))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount*-1, userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(500)))
+				mock.ExpectRollback()
+			},
+			entry: &ledger.Entry{
+				OperationType: "reserve",
+				SenderID:      userID,
+				Amount:        amount,
+				Postings: []ledger.Posting{
+					ledger.User(userID, amount*-1, 500),
+					ledger.System(ledger.Reservations, amount),
+				},
+			},
+			ledgerErr:   dbErr,
+			expectedErr: true,
+			err:         dbErr,
+		},
+	}
+
+	var got *models.Reservation
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			var written *ledger.Entry
+			storage.journal = &ledgerMock.MockWriter{
+				WriteFunc: func(_ context.Context, _ pgx.Tx, entry *ledger.Entry) (int64, error) {
+					written = entry
+					return 1, test.ledgerErr
+				},
+			}
+			test.mock()
+			got, err = storage.Reserve(context.Background(), userID, orderID, serviceID, amount)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+			assert.Equal(t, test.entry, written)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorage_CommitAndCancel(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	storage := NewStorage(mock)
+	dbErr := errors.New("Error in database")
+	timeNow := time.Now()
+
+	var (
+		userID    int64       = 1
+		orderID   int64       = 10
+		serviceID int64       = 100
+		amount    money.Money = 1000
+	)
+	closedRows := func(status string) *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "amount", "status", "created", "updated"}).
+			AddRow(int64(1), amount, status, timeNow, timeNow)
+	}
+
+	tests := []struct {
+		name        string
+		action      func() (*models.Reservation, error)
+		mock        func()
+		entry       *ledger.Entry
+		ledgerErr   error
+		expected    *models.Reservation
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Successfully committed reservation",
+			action: func() (*models.Reservation, error) {
+				return storage.Commit(context.Background(), userID, orderID, serviceID)
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryCloseReservation)).
+					WithArgs(statusCommitted, userID, orderID, serviceID).WillReturnRows(closedRows(statusCommitted))
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: "revenue",
+				SenderID:      userID,
+				Amount:        amount,
+				Postings: []ledger.Posting{
+					ledger.System(ledger.Reservations, amount*-1),
+					ledger.System(ledger.Revenue, amount),
+				},
+			},
+			expected: &models.Reservation{
+				ID:        1,
+				UserID:    userID,
+				OrderID:   orderID,
+				ServiceID: serviceID,
+				Amount:    amount,
+				Status:    statusCommitted,
+				Created:   timeNow,
+				Updated:   timeNow,
+			},
+		},
+		{
+			name: "Successfully cancelled reservation",
+			action: func() (*models.Reservation, error) {
+				return storage.Cancel(context.Background(), userID, orderID, serviceID)
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryCloseReservation)).
+					WithArgs(statusCancelled, userID, orderID, serviceID).WillReturnRows(closedRows(statusCancelled))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID).
+					WillReturnRows(pgxmock.NewRows([]string{"balance"}).AddRow(money.Money(1500)))
+				mock.ExpectCommit()
+			},
+			entry: &ledger.Entry{
+				OperationType: "release",
+				SenderID:      userID,
+				Amount:        amount,
+				Postings: []ledger.Posting{
+					ledger.System(ledger.Reservations, amount*-1),
+					ledger.User(userID, amount, 1500),
+				},
+			},
+			expected: &models.Reservation{
+				ID:        1,
+				UserID:    userID,
+				OrderID:   orderID,
+				ServiceID: serviceID,
+				Amount:    amount,
+				Status:    statusCancelled,
+				Created:   timeNow,
+				Updated:   timeNow,
+			},
+		},
+		{
+			name: "Active reservation does not exist",
+			action: func() (*models.Reservation, error) {
+				return storage.Commit(context.Background(), userID, orderID, serviceID)
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryCloseReservation)).
+					WithArgs(statusCommitted, userID, orderID, serviceID).WillReturnError(pgx.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrReservationDoesNotExist,
+		},
+		{
+			name: "Error in database during returning money to balance",
+			action: func() (*models.Reservation, error) {
+				return storage.Cancel(context.Background(), userID, orderID, serviceID)
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryCloseReservation)).
+					WithArgs(statusCancelled, userID, orderID, serviceID).WillReturnRows(closedRows(statusCancelled))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUpdateBalance)).WithArgs(amount, userID).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			var written *ledger.Entry
+			storage.journal = &ledgerMock.MockWriter{
+				WriteFunc: func(_ context.Context, _ pgx.Tx, entry *ledger.Entry) (int64, error) {
+					written = entry
+					return 1, test.ledgerErr
+				},
+			}
+			test.mock()
+			got, err := test.action()
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+			assert.Equal(t, test.entry, written)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
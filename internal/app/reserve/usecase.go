@@ -0,0 +1,23 @@
+package reserve
+
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/money"
+)
+
+//go:generate moq -out ./mock/reserve_usecase_mock.go -pkg mock . Service:MockService
+type Service interface {
+	Reserve(context.Context, *models.ReservationRequest) (*models.Reservation, error)
+	Commit(context.Context, *models.ReservationRequest) (*models.Reservation, error)
+	Cancel(context.Context, *models.ReservationRequest) (*models.Reservation, error)
+}
+
+//go:generate moq -out ./mock/reserve_metrics_mock.go -pkg mock . Metrics:MockMetrics
+
+// Metrics records money movements, operation types are the ones of the ledger, e.g. reserve.
+type Metrics interface {
+	TransactionCommitted(operationType, currency string, amount money.Money)
+	InsufficientFunds(operationType string)
+}
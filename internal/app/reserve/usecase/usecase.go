@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/app/reserve"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Service struct {
+	validator *utils.Validation
+	storage   reserve.Storage
+	metrics   reserve.Metrics
+}
+
+func NewService(storage reserve.Storage, validator *utils.Validation, metrics reserve.Metrics) *Service {
+	return &Service{
+		storage:   storage,
+		validator: validator,
+		metrics:   metrics,
+	}
+}
+
+func (s *Service) Reserve(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+	if err := s.validate(data, true); err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.storage.Reserve(ctx, data.UserID, data.OrderID, data.ServiceID, data.Amount)
+	switch {
+	case err == nil:
+		s.metrics.TransactionCommitted("reserve", constants.BaseCurrency, reservation.Amount)
+	case errors.Is(err, createdErrors.ErrNotEnoughMoney):
+		s.metrics.InsufficientFunds("reserve")
+	}
+
+	return reservation, err
+}
+
+func (s *Service) Commit(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+	if err := s.validate(data, false); err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.storage.Commit(ctx, data.UserID, data.OrderID, data.ServiceID)
+	if err == nil {
+		s.metrics.TransactionCommitted("revenue", constants.BaseCurrency, reservation.Amount)
+	}
+
+	return reservation, err
+}
+
+func (s *Service) Cancel(ctx context.Context, data *models.ReservationRequest) (*models.Reservation, error) {
+	if err := s.validate(data, false); err != nil {
+		return nil, err
+	}
+
+	reservation, err := s.storage.Cancel(ctx, data.UserID, data.OrderID, data.ServiceID)
+	if err == nil {
+		s.metrics.TransactionCommitted("release", constants.BaseCurrency, reservation.Amount)
+	}
+
+	return reservation, err
+}
+
+// validate checks the request, the amount is only checked if withAmount is set.
+func (s *Service) validate(data *models.ReservationRequest, withAmount bool) error {
+	errs := s.validator.Validate(data)
+	for _, err := range errs {
+		switch err.Field() {
+		case "UserID":
+			return createdErrors.ErrNegativeUserID
+		case "OrderID":
+			return createdErrors.ErrOrderIDisRequired
+		case "ServiceID":
+			return createdErrors.ErrServiceIDisRequired
+		case "Amount":
+			if withAmount {
+				return createdErrors.ErrAmountFiledIsRequired
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	storageMock "avito-tech-task/internal/app/reserve/mock"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+// newMetricsMock accepts all records of metrics, they are checked by the calls of the mock.
+func newMetricsMock() *storageMock.MockMetrics {
+	return &storageMock.MockMetrics{
+		TransactionCommittedFunc: func(operationType string, currency string, amount money.Money) {},
+		InsufficientFundsFunc:    func(operationType string) {},
+	}
+}
+
+func TestService_Reserve(t *testing.T) {
+	storageError := errors.New("Error in storage")
+
+	tests := []struct {
+		name        string
+		data        *models.ReservationRequest
+		storageMock *storageMock.MockStorage
+		expected    *models.Reservation
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Successfully reserved money",
+			data: &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000},
+			storageMock: &storageMock.MockStorage{
+				ReserveFunc: func(ctx context.Context, userID int64, orderID int64, serviceID int64, amount money.Money) (*models.Reservation, error) {
+					return &models.Reservation{ID: 1, UserID: userID, OrderID: orderID, ServiceID: serviceID,
+						Amount: amount, Status: "reserved"}, nil
+				},
+			},
+			expected: &models.Reservation{ID: 1, UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000,
+				Status: "reserved"},
+		},
+		{
+			name: "Error in storage",
+			data: &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000},
+			storageMock: &storageMock.MockStorage{
+				ReserveFunc: func(ctx context.Context, userID int64, orderID int64, serviceID int64, amount money.Money) (*models.Reservation, error) {
+					return nil, storageError
+				},
+			},
+			expectedErr: true,
+			err:         storageError,
+		},
+		{
+			name: "Not enough money",
+			data: &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000},
+			storageMock: &storageMock.MockStorage{
+				ReserveFunc: func(ctx context.Context, userID int64, orderID int64, serviceID int64, amount money.Money) (*models.Reservation, error) {
+					return nil, createdErrors.ErrNotEnoughMoney
+				},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotEnoughMoney,
+		},
+		{
+			name:        "Amount is not set",
+			data:        &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
+		{
+			name:        "Negative amount",
+			data:        &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100, Amount: -1},
+			expectedErr: true,
+			err:         createdErrors.ErrAmountFiledIsRequired,
+		},
+		{
+			name:        "Negative user ID",
+			data:        &models.ReservationRequest{UserID: -1, OrderID: 10, ServiceID: 100, Amount: 1000},
+			expectedErr: true,
+			err:         createdErrors.ErrNegativeUserID,
+		},
+		{
+			name:        "Order ID is not set",
+			data:        &models.ReservationRequest{UserID: 1, ServiceID: 100, Amount: 1000},
+			expectedErr: true,
+			err:         createdErrors.ErrOrderIDisRequired,
+		},
+		{
+			name:        "Service ID is not set",
+			data:        &models.ReservationRequest{UserID: 1, OrderID: 10, Amount: 1000},
+			expectedErr: true,
+			err:         createdErrors.ErrServiceIDisRequired,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			validator := utils.NewValidator()
+			metrics := newMetricsMock()
+			service := NewService(test.storageMock, validator, metrics)
+
+			got, err := service.Reserve(context.Background(), test.data)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+				assert.Empty(t, metrics.TransactionCommittedCalls())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+				if assert.Len(t, metrics.TransactionCommittedCalls(), 1) {
+					assert.Equal(t, "reserve", metrics.TransactionCommittedCalls()[0].OperationType)
+					assert.Equal(t, test.expected.Amount, metrics.TransactionCommittedCalls()[0].Amount)
+				}
+			}
+			assert.Equal(t, errors.Is(test.err, createdErrors.ErrNotEnoughMoney), len(metrics.InsufficientFundsCalls()) == 1)
+		})
+	}
+}
+
+func TestService_CommitAndCancel(t *testing.T) {
+	storageMock := &storageMock.MockStorage{
+		CommitFunc: func(ctx context.Context, userID int64, orderID int64, serviceID int64) (*models.Reservation, error) {
+			return &models.Reservation{ID: 1, UserID: userID, OrderID: orderID, ServiceID: serviceID,
+				Amount: 1000, Status: "committed"}, nil
+		},
+		CancelFunc: func(ctx context.Context, userID int64, orderID int64, serviceID int64) (*models.Reservation, error) {
+			return nil, createdErrors.ErrReservationDoesNotExist
+		},
+	}
+	metrics := newMetricsMock()
+	service := NewService(storageMock, utils.NewValidator(), metrics)
+
+	got, err := service.Commit(context.Background(), &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Reservation{ID: 1, UserID: 1, OrderID: 10, ServiceID: 100, Amount: 1000,
+		Status: "committed"}, got)
+
+	_, err = service.Cancel(context.Background(), &models.ReservationRequest{UserID: 1, OrderID: 10, ServiceID: 100})
+	assert.Equal(t, createdErrors.ErrReservationDoesNotExist, err)
+
+	_, err = service.Cancel(context.Background(), &models.ReservationRequest{UserID: 1, OrderID: 10})
+	assert.Equal(t, createdErrors.ErrServiceIDisRequired, err)
+
+	assert.Len(t, storageMock.CommitCalls(), 1)
+	assert.Len(t, storageMock.CancelCalls(), 1)
+	if assert.Len(t, metrics.TransactionCommittedCalls(), 1, "only the commit made a transaction") {
+		assert.Equal(t, "revenue", metrics.TransactionCommittedCalls()[0].OperationType)
+		assert.Equal(t, "RUB", metrics.TransactionCommittedCalls()[0].Currency)
+	}
+}
@@ -2,6 +2,7 @@ package delivery
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -10,79 +11,111 @@ import (
 
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/app/transactions"
-	"avito-tech-task/internal/pkg/constants"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
 )
 
 type Handlers struct {
 	service transactions.Service
-	logger  *logrus.Logger
 }
 
-func NewHandlers(service transactions.Service, logger *logrus.Logger) *Handlers {
+func NewHandlers(service transactions.Service) *Handlers {
 	return &Handlers{
 		service: service,
-		logger:  logger,
 	}
 }
 
 func (h *Handlers) InitHandlers(server *echo.Echo) {
 	server.GET("/api/v1/transactions/:user_id", h.GetTransactions)
+	server.POST("/api/v1/transactions/:user_id", h.SearchTransactions)
 }
 
 // GetTransactions
 // @Summary 	Get list of user transactions
 // @Produce 	json
 // @Param 		user_id path int true "User ID in BalanceApplication"
+// @Param 		limit query int false "Max number of transactions, 100 by default"
+// @Param 		since query string false "Transactions created at or after the date in RFC3339 format"
+// @Param 		operation_type query int false "Operation type"
+// @Param 		operation_types query []int false "Operation types, combined with operation_type" collectionFormat(multi)
+// @Param 		counterparty_ids query []int false "Users transfers were made from or to" collectionFormat(multi)
+// @Param 		order_amount query bool false "Sort by amount"
+// @Param 		order_date query bool false "Sort by date"
+// @Param 		order query string false "Direction of sorting" Enums(asc, desc)
+// @Param 		sort query string false "Sort keys with optional direction, e.g. amount:asc,created:desc"
+// @Param 		reason query string false "Reason of the operation"
+// @Param 		from query string false "Transactions created at or after the date in RFC3339 format"
+// @Param 		to query string false "Transactions created before the date in RFC3339 format"
+// @Param 		min_amount query number false "Min amount of transaction"
+// @Param 		max_amount query number false "Max amount of transaction"
+// @Param 		cursor query string false "next_cursor of the previous page"
+// @Param 		currency query string false "Currency to convert transactions in at the rate of the day they were created"
+// @Success 	200 {object} models.TransactionsPage
+// @Failure		400 {object} models.Problem "Invalid user ID in query param | invalid query params | malformed amount"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		422 {object} models.Problem "Negative limit | invalid order, sort or cursor | negative amount filter | not supported operation type or currency"
+// @Failure		500 {object} models.Problem "Internal server error"
+// @Router 		/transactions/{user_id} [GET]
+func (h *Handlers) GetTransactions(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
+
+	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		log.WithError(err).Warn("Could not convert user id from string to int")
+		return createdErrors.ErrInvalidUserID
+	}
+
+	var params models.TransactionsSelectionParams
+	if err = bindQueryParams(ctx, &params); err != nil {
+		log.WithError(err).Warn("Could not bind query params to models.TransactionsSelectionParams")
+		if errors.Is(err, createdErrors.ErrInvalidAmount) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, err)
+	}
+
+	return h.listTransactions(ctx, userID, &params)
+}
+
+// SearchTransactions
+// @Summary 	Search user transactions with parameters in body
+// @Accept		json
+// @Produce 	json
+// @Param 		user_id path int true "User ID in BalanceApplication"
 // @Param 		params body models.TransactionsSelectionParams true "Parameters for transactions selection"
-// @Success 	200 {object} models.Transactions
-// @Failure		400 {object} models.ResponseMessage "Invalid user ID in query param | invalid body"
-// @Failure		404 {object} models.ResponseMessage "User not found"
-// @Failure		500 {object} models.ResponseMessage "Internal server error"
+// @Success 	200 {object} models.TransactionsPage
+// @Failure		400 {object} models.Problem "Invalid user ID in query param | invalid body | malformed amount"
+// @Failure		404 {object} models.Problem "User not found"
+// @Failure		422 {object} models.Problem "Negative limit | invalid order, sort or cursor | negative amount filter | not supported operation type or currency"
+// @Failure		500 {object} models.Problem "Internal server error"
 // @Router 		/transactions/{user_id} [POST]
-func (h *Handlers) GetTransactions(ctx echo.Context) error {
-	h.logger.Info("Called handler GetTransactions for GET /api/v1/transactions/:user_id")
+func (h *Handlers) SearchTransactions(ctx echo.Context) error {
+	log := utils.Log(ctx.Request().Context())
 
 	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
 	if err != nil {
-		h.logger.Warnf("Could not convert user id from string to int: %s", err)
-		return ctx.JSON(
-			http.StatusBadRequest,
-			&models.ResponseMessage{Message: constants.InvalidUserIDMessage})
+		log.WithError(err).Warn("Could not convert user id from string to int")
+		return createdErrors.ErrInvalidUserID
 	}
 
 	var params models.TransactionsSelectionParams
-	if err = ctx.Bind(&params); err != nil {
-		h.logger.Warnf("Could not bind query params to models.TransactionsSelectionParams: %s", err)
-		return ctx.JSON(
-			http.StatusBadRequest,
-			&models.ResponseMessage{Message: constants.InvalidQueryParams})
+	if err = (&echo.DefaultBinder{}).BindBody(ctx, &params); err != nil {
+		log.WithError(err).Warn("Could not bind body to models.TransactionsSelectionParams")
+		return utils.BindError(err)
 	}
 
-	transactions, err := h.service.GetUserTransactions(userID, &params)
-	switch errors.Is(err, createdErrors.ErrUserDoesNotExist) {
-	case true:
-		h.logger.Warnf("Bad request: %s", err)
-		return ctx.JSON(
-			http.StatusNotFound,
-			&models.ResponseMessage{Message: err.Error()})
-	case false:
-		switch errors.Is(err, createdErrors.ErrNegativeLimit) {
-		case true:
-			h.logger.Warnf("Bad request: %s", err)
-			return ctx.JSON(
-				http.StatusUnprocessableEntity,
-				&models.ResponseMessage{Message: err.Error()})
-		case false:
-			if err != nil {
-				h.logger.Errorf("Internal server error: %s", err)
-				return ctx.JSON(
-					http.StatusInternalServerError,
-					&models.ResponseMessage{Message: err.Error()})
-			}
-		}
+	return h.listTransactions(ctx, userID, &params)
+}
+
+func (h *Handlers) listTransactions(ctx echo.Context, userID int64, params *models.TransactionsSelectionParams) error {
+	log := utils.AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": userID})
+	log.WithField("request", params).Debug("Request data")
+
+	transactions, err := h.service.GetUserTransactions(ctx.Request().Context(), userID, params)
+	if err != nil {
+		return err
 	}
 
-	h.logger.Infof("Request was successfully processed, received response: %v", transactions)
+	log.WithField("transactions", len(transactions.Items)).Debug("Request was processed")
 	return ctx.JSON(http.StatusOK, transactions)
 }
@@ -1,8 +1,10 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -16,18 +18,20 @@ import (
 	"avito-tech-task/config"
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/app/transactions/mock"
-	"avito-tech-task/internal/pkg/constants"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
 )
 
-func TestHandlers_GetTransactions(t *testing.T) {
+// requestID is the correlation ID of internal errors in responses of tests
+const requestID = "test-request-id"
+
+func TestHandlers_SearchTransactions(t *testing.T) {
 	const removeLogs = true // set false to deny deleting logs after test
 
 	config := &config.Config{
 		LoggingLevel:    "debug",
 		LoggingFilePath: "./logs/",
-		CurrencyAPIURL:  "",
 		Server:          config.ServerConfig{},
 	}
 	logger, closeF := utils.NewLogger(config)
@@ -58,63 +62,87 @@ func TestHandlers_GetTransactions(t *testing.T) {
 		{
 			name: "Successfully get user transactions list",
 			serviceMock: &mock.MockService{
-				GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
-					return models.Transactions{
-						&models.Transaction{
-							OperationType: "add",
-							Amount:        1000,
-							Created:       timeNow,
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return &models.TransactionsPage{
+						Items: models.Transactions{
+							&models.Transaction{
+								ID:            1,
+								OperationType: "add",
+								Direction:     "incoming",
+								Amount:        1000,
+								Created:       timeNow,
+							},
 						},
+						NextCursor: "cursor",
+						HasMore:    true,
 					}, nil
 				},
 			},
 			userIDParam:    "1",
 			body:           `{"limit": 10, "operation_type":1}`,
 			expectedStatus: http.StatusOK,
-			expected: models.Transactions{
-				&models.Transaction{
-					OperationType: "add",
-					Amount:        1000,
-					Created:       timeNow,
+			expected: &models.TransactionsPage{
+				Items: models.Transactions{
+					&models.Transaction{
+						ID:            1,
+						OperationType: "add",
+						Direction:     "incoming",
+						Amount:        1000,
+						Created:       timeNow,
+					},
 				},
+				NextCursor: "cursor",
+				HasMore:    true,
 			},
 		},
 		{
 			name: "User does not exist",
 			serviceMock: &mock.MockService{
-				GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
 					return nil, createdErrors.ErrUserDoesNotExist
 				},
 			},
 			userIDParam:    "1",
 			body:           `{"limit": 10, "operation_type":1}`,
 			expectedStatus: http.StatusNotFound,
-			expected:       &models.ResponseMessage{Message: createdErrors.ErrUserDoesNotExist.Error()},
+			expected:       utils.NewProblem(createdErrors.ErrUserDoesNotExist, ""),
 		},
 		{
 			name: "Internal server error",
 			serviceMock: &mock.MockService{
-				GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
 					return nil, internalServerErr
 				},
 			},
 			userIDParam:    "1",
 			body:           `{"limit": 10, "operation_type":1}`,
 			expectedStatus: http.StatusInternalServerError,
-			expected:       &models.ResponseMessage{Message: internalServerErr.Error()},
+			expected:       utils.NewProblem(internalServerErr, requestID),
+		},
+		{
+			name: "Invalid cursor",
+			serviceMock: &mock.MockService{
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return nil, createdErrors.ErrInvalidCursor
+				},
+			},
+			userIDParam:    "1",
+			body:           `{"cursor": "abc"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidCursor, ""),
 		},
 		{
 			name:           "Invalid user ID as param",
 			userIDParam:    "hello",
 			expectedStatus: http.StatusBadRequest,
-			expected:       &models.ResponseMessage{Message: constants.InvalidUserIDMessage},
+			expected:       utils.NewProblem(createdErrors.ErrInvalidUserID, ""),
 		},
 		{
-			name:           "Invalid query params",
+			name:           "Invalid body",
 			userIDParam:    "1",
 			body:           `{"limit": "string???"}`,
 			expectedStatus: http.StatusBadRequest,
-			expected:       &models.ResponseMessage{Message: constants.InvalidQueryParams},
+			expected:       utils.NewProblem(createdErrors.ErrInvalidBody, ""),
 		},
 	}
 
@@ -122,6 +150,7 @@ func TestHandlers_GetTransactions(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
 
 			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(test.body))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -131,8 +160,142 @@ func TestHandlers_GetTransactions(t *testing.T) {
 			ctx.SetParamNames("user_id")
 			ctx.SetParamValues(test.userIDParam)
 
-			handlers := NewHandlers(test.serviceMock, logger)
-			if assert.NoError(t, handlers.GetTransactions(ctx)) {
+			handlers := NewHandlers(test.serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.SearchTransactions)(ctx)) {
+				assert.Equal(t, test.expectedStatus, rec.Code)
+
+				expectedString, _ := json.Marshal(test.expected)
+				assert.Equal(t, string(expectedString)+"\n", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_GetTransactions(t *testing.T) {
+	const removeLogs = true // set false to deny deleting logs after test
+
+	config := &config.Config{
+		LoggingLevel:    "debug",
+		LoggingFilePath: "./logs/",
+		Server:          config.ServerConfig{},
+	}
+	logger, closeF := utils.NewLogger(config)
+	defer func(closeF func() error) {
+		if err := closeF(); err != nil {
+			t.Errorf("Could not close file: %s", err)
+		}
+	}(closeF)
+
+	if removeLogs {
+		defer func() {
+			if err := os.RemoveAll("./logs/"); err != nil {
+				t.Errorf("Could not remove temporary logs directory: %s", err)
+			}
+		}()
+	}
+
+	since := time.Date(2022, 1, 15, 21, 37, 23, 0, time.FixedZone("", 3*60*60))
+	minAmount := money.Money(1050)
+	tests := []struct {
+		name           string
+		userIDParam    string
+		query          string
+		expectedParams *models.TransactionsSelectionParams
+		expectedStatus int
+		expected       interface{}
+	}{
+		{
+			name:        "Successfully get user transactions list",
+			userIDParam: "1",
+			query: "limit=10&since=2022-01-15T21:37:23%2B03:00&operation_types=1&operation_types=3" +
+				"&counterparty_ids=2&min_amount=10.50&sort=amount:asc&reason=order_payment&currency=USD",
+			expectedParams: &models.TransactionsSelectionParams{
+				Limit:           10,
+				Since:           &since,
+				Reason:          "order_payment",
+				MinAmount:       &minAmount,
+				OperationTypes:  []int{1, 3},
+				CounterpartyIDs: []int64{2},
+				Sort:            "amount:asc",
+				Currency:        "USD",
+			},
+			expectedStatus: http.StatusOK,
+			expected:       &models.TransactionsPage{Items: models.Transactions{}},
+		},
+		{
+			name:           "Without params",
+			userIDParam:    "1",
+			expectedParams: &models.TransactionsSelectionParams{},
+			expectedStatus: http.StatusOK,
+			expected:       &models.TransactionsPage{Items: models.Transactions{}},
+		},
+		{
+			name:           "Invalid user ID as param",
+			userIDParam:    "hello",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(createdErrors.ErrInvalidUserID, ""),
+		},
+		{
+			name:           "Unknown query param",
+			userIDParam:    "1",
+			query:          "limit=10&page=2",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `unknown query param "page"`), ""),
+		},
+		{
+			name:           "Date is not in RFC3339 format",
+			userIDParam:    "1",
+			query:          "since=2022-01-15 21:37:23",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `invalid value of query param "since"`), ""),
+		},
+		{
+			name:           "Not a number",
+			userIDParam:    "1",
+			query:          "limit=string???",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `invalid value of query param "limit"`), ""),
+		},
+		{
+			name:           "Repeated single param",
+			userIDParam:    "1",
+			query:          "limit=10&limit=20",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidQueryParams, `query param "limit" must be passed once`), ""),
+		},
+		{
+			name:           "Malformed amount",
+			userIDParam:    "1",
+			query:          "min_amount=10.505",
+			expectedStatus: http.StatusBadRequest,
+			expected:       utils.NewProblem(fmt.Errorf("%w: %s", createdErrors.ErrInvalidAmount, `query param "min_amount"`), ""),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			server := echo.New()
+			server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+
+			serviceMock := &mock.MockService{
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					assert.Equal(t, test.expectedParams, transactionsSelectionParams)
+					return &models.TransactionsPage{Items: models.Transactions{}}, nil
+				},
+			}
+
+			req := httptest.NewRequest(echo.GET, "/?"+strings.ReplaceAll(test.query, " ", "%20"), nil)
+			rec := httptest.NewRecorder()
+			ctx := server.NewContext(req, rec)
+			ctx.SetPath("/api/v1/transactions/:user_id")
+			ctx.SetParamNames("user_id")
+			ctx.SetParamValues(test.userIDParam)
+
+			handlers := NewHandlers(serviceMock)
+			ctx.Request().Header.Set(echo.HeaderXRequestID, requestID)
+			if assert.NoError(t, utils.RequestLogger(logger)(handlers.GetTransactions)(ctx)) {
 				assert.Equal(t, test.expectedStatus, rec.Code)
 
 				expectedString, _ := json.Marshal(test.expected)
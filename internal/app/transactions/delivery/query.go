@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
+)
+
+// selectionQueryParams are the query params of models.TransactionsSelectionParams,
+// the value tells whether the param may be passed several times.
+var selectionQueryParams = map[string]bool{
+	"limit":            false,
+	"since":            false,
+	"operation_type":   false,
+	"order_amount":     false,
+	"order_date":       false,
+	"order":            false,
+	"reason":           false,
+	"cursor":           false,
+	"from":             false,
+	"to":               false,
+	"min_amount":       false,
+	"max_amount":       false,
+	"operation_types":  true,
+	"counterparty_ids": true,
+	"sort":             false,
+	"currency":         false,
+}
+
+// bindQueryParams strictly parses the selection params from the query: unknown params, repeated single params
+// and malformed values are rejected, dates must be in RFC3339 format with "+" of the offset encoded as %2B.
+func bindQueryParams(ctx echo.Context, params *models.TransactionsSelectionParams) error {
+	query := ctx.QueryParams()
+	for name, values := range query {
+		multiple, ok := selectionQueryParams[name]
+		if !ok {
+			return fmt.Errorf("unknown query param %q", name)
+		}
+		if len(values) > 1 && !multiple {
+			return fmt.Errorf("query param %q must be passed once", name)
+		}
+	}
+
+	var (
+		since, from, to      time.Time
+		minAmount, maxAmount money.Money
+	)
+	err := echo.QueryParamsBinder(ctx).FailFast(true).
+		Int("limit", &params.Limit).
+		Time("since", &since, time.RFC3339).
+		Int("operation_type", &params.OperationType).
+		Bool("order_amount", &params.OrderAmount).
+		Bool("order_date", &params.OrderDate).
+		String("order", &params.Order).
+		String("reason", &params.Reason).
+		String("cursor", &params.Cursor).
+		Time("from", &from, time.RFC3339).
+		Time("to", &to, time.RFC3339).
+		BindUnmarshaler("min_amount", &minAmount).
+		BindUnmarshaler("max_amount", &maxAmount).
+		Ints("operation_types", &params.OperationTypes).
+		Int64s("counterparty_ids", &params.CounterpartyIDs).
+		String("sort", &params.Sort).
+		String("currency", &params.Currency).
+		BindError()
+	if err != nil {
+		var bindingErr *echo.BindingError
+		if errors.As(err, &bindingErr) {
+			if errors.Is(err, createdErrors.ErrInvalidAmount) {
+				return fmt.Errorf("%w: query param %q", createdErrors.ErrInvalidAmount, bindingErr.Field)
+			}
+			return fmt.Errorf("invalid value of query param %q", bindingErr.Field)
+		}
+		return err
+	}
+
+	if query.Get("since") != "" {
+		params.Since = &since
+	}
+	if query.Get("from") != "" {
+		params.From = &from
+	}
+	if query.Get("to") != "" {
+		params.To = &to
+	}
+	if query.Get("min_amount") != "" {
+		params.MinAmount = &minAmount
+	}
+	if query.Get("max_amount") != "" {
+		params.MaxAmount = &maxAmount
+	}
+
+	return nil
+}
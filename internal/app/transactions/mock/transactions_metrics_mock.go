@@ -0,0 +1,81 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/app/transactions"
+	"sync"
+)
+
+// Ensure, that MockMetrics does implement transactions.Metrics.
+// If this is not the case, regenerate this file with moq.
+var _ transactions.Metrics = &MockMetrics{}
+
+// MockMetrics is a mock implementation of transactions.Metrics.
+//
+//	func TestSomethingThatUsesMetrics(t *testing.T) {
+//
+//		// make and configure a mocked transactions.Metrics
+//		mockedMetrics := &MockMetrics{
+//			HistoryConvertedFunc: func(currency string, transactions int)  {
+//				panic("mock out the HistoryConverted method")
+//			},
+//		}
+//
+//		// use mockedMetrics in code that requires transactions.Metrics
+//		// and then make assertions.
+//
+//	}
+type MockMetrics struct {
+	// HistoryConvertedFunc mocks the HistoryConverted method.
+	HistoryConvertedFunc func(currency string, transactions int)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// HistoryConverted holds details about calls to the HistoryConverted method.
+		HistoryConverted []struct {
+			// Currency is the currency argument value.
+			Currency string
+			// Transactions is the transactions argument value.
+			Transactions int
+		}
+	}
+	lockHistoryConverted sync.RWMutex
+}
+
+// HistoryConverted calls HistoryConvertedFunc.
+func (mock *MockMetrics) HistoryConverted(currency string, transactions int) {
+	if mock.HistoryConvertedFunc == nil {
+		panic("MockMetrics.HistoryConvertedFunc: method is nil but Metrics.HistoryConverted was just called")
+	}
+	callInfo := struct {
+		Currency     string
+		Transactions int
+	}{
+		Currency:     currency,
+		Transactions: transactions,
+	}
+	mock.lockHistoryConverted.Lock()
+	mock.calls.HistoryConverted = append(mock.calls.HistoryConverted, callInfo)
+	mock.lockHistoryConverted.Unlock()
+	mock.HistoryConvertedFunc(currency, transactions)
+}
+
+// HistoryConvertedCalls gets all the calls that were made to HistoryConverted.
+// Check the length with:
+//
+//	len(mockedMetrics.HistoryConvertedCalls())
+func (mock *MockMetrics) HistoryConvertedCalls() []struct {
+	Currency     string
+	Transactions int
+} {
+	var calls []struct {
+		Currency     string
+		Transactions int
+	}
+	mock.lockHistoryConverted.RLock()
+	calls = mock.calls.HistoryConverted
+	mock.lockHistoryConverted.RUnlock()
+	return calls
+}
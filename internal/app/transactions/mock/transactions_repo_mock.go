@@ -6,6 +6,7 @@ package mock
 import (
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/app/transactions"
+	"context"
 	"sync"
 )
 
@@ -15,38 +16,42 @@ var _ transactions.Storage = &MockStorage{}
 
 // MockStorage is a mock implementation of transactions.Storage.
 //
-// 	func TestSomethingThatUsesStorage(t *testing.T) {
+//	func TestSomethingThatUsesStorage(t *testing.T) {
 //
-// 		// make and configure a mocked transactions.Storage
-// 		mockedStorage := &MockStorage{
-// 			DoesUserExistFunc: func(n int64) (bool, error) {
-// 				panic("mock out the DoesUserExist method")
-// 			},
-// 			GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
-// 				panic("mock out the GetUserTransactions method")
-// 			},
-// 		}
+//		// make and configure a mocked transactions.Storage
+//		mockedStorage := &MockStorage{
+//			DoesUserExistFunc: func(contextMoqParam context.Context, n int64) (bool, error) {
+//				panic("mock out the DoesUserExist method")
+//			},
+//			GetUserTransactionsFunc: func(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+//				panic("mock out the GetUserTransactions method")
+//			},
+//		}
 //
-// 		// use mockedStorage in code that requires transactions.Storage
-// 		// and then make assertions.
+//		// use mockedStorage in code that requires transactions.Storage
+//		// and then make assertions.
 //
-// 	}
+//	}
 type MockStorage struct {
 	// DoesUserExistFunc mocks the DoesUserExist method.
-	DoesUserExistFunc func(n int64) (bool, error)
+	DoesUserExistFunc func(contextMoqParam context.Context, n int64) (bool, error)
 
 	// GetUserTransactionsFunc mocks the GetUserTransactions method.
-	GetUserTransactionsFunc func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error)
+	GetUserTransactionsFunc func(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// DoesUserExist holds details about calls to the DoesUserExist method.
 		DoesUserExist []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
 		}
 		// GetUserTransactions holds details about calls to the GetUserTransactions method.
 		GetUserTransactions []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
 			// TransactionsSelectionParams is the transactionsSelectionParams argument value.
@@ -58,29 +63,34 @@ type MockStorage struct {
 }
 
 // DoesUserExist calls DoesUserExistFunc.
-func (mock *MockStorage) DoesUserExist(n int64) (bool, error) {
+func (mock *MockStorage) DoesUserExist(contextMoqParam context.Context, n int64) (bool, error) {
 	if mock.DoesUserExistFunc == nil {
 		panic("MockStorage.DoesUserExistFunc: method is nil but Storage.DoesUserExist was just called")
 	}
 	callInfo := struct {
-		N int64
+		ContextMoqParam context.Context
+		N               int64
 	}{
-		N: n,
+		ContextMoqParam: contextMoqParam,
+		N:               n,
 	}
 	mock.lockDoesUserExist.Lock()
 	mock.calls.DoesUserExist = append(mock.calls.DoesUserExist, callInfo)
 	mock.lockDoesUserExist.Unlock()
-	return mock.DoesUserExistFunc(n)
+	return mock.DoesUserExistFunc(contextMoqParam, n)
 }
 
 // DoesUserExistCalls gets all the calls that were made to DoesUserExist.
 // Check the length with:
-//     len(mockedStorage.DoesUserExistCalls())
+//
+//	len(mockedStorage.DoesUserExistCalls())
 func (mock *MockStorage) DoesUserExistCalls() []struct {
-	N int64
+	ContextMoqParam context.Context
+	N               int64
 } {
 	var calls []struct {
-		N int64
+		ContextMoqParam context.Context
+		N               int64
 	}
 	mock.lockDoesUserExist.RLock()
 	calls = mock.calls.DoesUserExist
@@ -89,31 +99,36 @@ func (mock *MockStorage) DoesUserExistCalls() []struct {
 }
 
 // GetUserTransactions calls GetUserTransactionsFunc.
-func (mock *MockStorage) GetUserTransactions(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
+func (mock *MockStorage) GetUserTransactions(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
 	if mock.GetUserTransactionsFunc == nil {
 		panic("MockStorage.GetUserTransactionsFunc: method is nil but Storage.GetUserTransactions was just called")
 	}
 	callInfo := struct {
+		ContextMoqParam             context.Context
 		N                           int64
 		TransactionsSelectionParams *models.TransactionsSelectionParams
 	}{
+		ContextMoqParam:             contextMoqParam,
 		N:                           n,
 		TransactionsSelectionParams: transactionsSelectionParams,
 	}
 	mock.lockGetUserTransactions.Lock()
 	mock.calls.GetUserTransactions = append(mock.calls.GetUserTransactions, callInfo)
 	mock.lockGetUserTransactions.Unlock()
-	return mock.GetUserTransactionsFunc(n, transactionsSelectionParams)
+	return mock.GetUserTransactionsFunc(contextMoqParam, n, transactionsSelectionParams)
 }
 
 // GetUserTransactionsCalls gets all the calls that were made to GetUserTransactions.
 // Check the length with:
-//     len(mockedStorage.GetUserTransactionsCalls())
+//
+//	len(mockedStorage.GetUserTransactionsCalls())
 func (mock *MockStorage) GetUserTransactionsCalls() []struct {
+	ContextMoqParam             context.Context
 	N                           int64
 	TransactionsSelectionParams *models.TransactionsSelectionParams
 } {
 	var calls []struct {
+		ContextMoqParam             context.Context
 		N                           int64
 		TransactionsSelectionParams *models.TransactionsSelectionParams
 	}
@@ -6,6 +6,7 @@ package mock
 import (
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/app/transactions"
+	"context"
 	"sync"
 )
 
@@ -15,27 +16,29 @@ var _ transactions.Service = &MockService{}
 
 // MockService is a mock implementation of transactions.Service.
 //
-// 	func TestSomethingThatUsesService(t *testing.T) {
+//	func TestSomethingThatUsesService(t *testing.T) {
 //
-// 		// make and configure a mocked transactions.Service
-// 		mockedService := &MockService{
-// 			GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
-// 				panic("mock out the GetUserTransactions method")
-// 			},
-// 		}
+//		// make and configure a mocked transactions.Service
+//		mockedService := &MockService{
+//			GetUserTransactionsFunc: func(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+//				panic("mock out the GetUserTransactions method")
+//			},
+//		}
 //
-// 		// use mockedService in code that requires transactions.Service
-// 		// and then make assertions.
+//		// use mockedService in code that requires transactions.Service
+//		// and then make assertions.
 //
-// 	}
+//	}
 type MockService struct {
 	// GetUserTransactionsFunc mocks the GetUserTransactions method.
-	GetUserTransactionsFunc func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error)
+	GetUserTransactionsFunc func(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// GetUserTransactions holds details about calls to the GetUserTransactions method.
 		GetUserTransactions []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 			// N is the n argument value.
 			N int64
 			// TransactionsSelectionParams is the transactionsSelectionParams argument value.
@@ -46,31 +49,36 @@ type MockService struct {
 }
 
 // GetUserTransactions calls GetUserTransactionsFunc.
-func (mock *MockService) GetUserTransactions(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
+func (mock *MockService) GetUserTransactions(contextMoqParam context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
 	if mock.GetUserTransactionsFunc == nil {
 		panic("MockService.GetUserTransactionsFunc: method is nil but Service.GetUserTransactions was just called")
 	}
 	callInfo := struct {
+		ContextMoqParam             context.Context
 		N                           int64
 		TransactionsSelectionParams *models.TransactionsSelectionParams
 	}{
+		ContextMoqParam:             contextMoqParam,
 		N:                           n,
 		TransactionsSelectionParams: transactionsSelectionParams,
 	}
 	mock.lockGetUserTransactions.Lock()
 	mock.calls.GetUserTransactions = append(mock.calls.GetUserTransactions, callInfo)
 	mock.lockGetUserTransactions.Unlock()
-	return mock.GetUserTransactionsFunc(n, transactionsSelectionParams)
+	return mock.GetUserTransactionsFunc(contextMoqParam, n, transactionsSelectionParams)
 }
 
 // GetUserTransactionsCalls gets all the calls that were made to GetUserTransactions.
 // Check the length with:
-//     len(mockedService.GetUserTransactionsCalls())
+//
+//	len(mockedService.GetUserTransactionsCalls())
 func (mock *MockService) GetUserTransactionsCalls() []struct {
+	ContextMoqParam             context.Context
 	N                           int64
 	TransactionsSelectionParams *models.TransactionsSelectionParams
 } {
 	var calls []struct {
+		ContextMoqParam             context.Context
 		N                           int64
 		TransactionsSelectionParams *models.TransactionsSelectionParams
 	}
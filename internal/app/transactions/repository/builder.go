@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder composes a query from conditions, values are never written into the query text,
+// they are passed as arguments and referenced by placeholders.
+type queryBuilder struct {
+	query      string
+	conditions []string
+	args       []interface{}
+}
+
+// newQueryBuilder starts the query, its text may reference args as $1, $2 and so on.
+func newQueryBuilder(query string, args ...interface{}) *queryBuilder {
+	return &queryBuilder{query: query, args: args}
+}
+
+// arg adds the argument and returns its placeholder.
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// where adds the condition joined with the others by AND.
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// anyOf adds the condition which holds if at least one of conditions holds.
+func (b *queryBuilder) anyOf(conditions []string) {
+	if len(conditions) > 0 {
+		b.where("(" + strings.Join(conditions, " OR ") + ")")
+	}
+}
+
+// build returns the query with conditions, ordering and limit, and its arguments.
+func (b *queryBuilder) build(orderBy string, limit int) (string, []interface{}) {
+	var query strings.Builder
+	query.WriteString(b.query)
+	for _, condition := range b.conditions {
+		query.WriteString(" AND ")
+		query.WriteString(condition)
+	}
+	query.WriteString(" ORDER BY ")
+	query.WriteString(orderBy)
+	query.WriteString(" LIMIT ")
+	query.WriteString(b.arg(limit))
+
+	return query.String(), b.args
+}
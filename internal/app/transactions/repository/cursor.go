@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
+)
+
+// pageCursor is the position of the last transaction of a page, clients get it as an opaque token.
+type pageCursor struct {
+	// Sort is the sorting the cursor was created for, the cursor can not be used with another one
+	Sort    string      `json:"s"`
+	Amount  money.Money `json:"a"`
+	Created time.Time   `json:"c"`
+	ID      int64       `json:"i"`
+}
+
+func newPageCursor(sort sorting, last *models.Transaction) *pageCursor {
+	return &pageCursor{
+		Sort:    sort.String(),
+		Amount:  last.Amount,
+		Created: last.Created,
+		ID:      last.ID,
+	}
+}
+
+func (c *pageCursor) value(key sortKey) interface{} {
+	switch key {
+	case sortByAmount:
+		return c.Amount
+	case sortByCreated:
+		return c.Created
+	default:
+		return c.ID
+	}
+}
+
+func (c *pageCursor) encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses the token and checks that it was created for the sorting.
+func decodeCursor(token string, sort sorting) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, createdErrors.ErrInvalidCursor
+	}
+
+	cursor := &pageCursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.Sort != sort.String() {
+		return nil, createdErrors.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -3,12 +3,16 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v4"
 
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 	"avito-tech-task/internal/pkg/utils"
 )
 
@@ -21,112 +25,182 @@ func NewStorage(pool utils.PgxIface) *Storage {
 }
 
 const (
-	queryGetUserID = `SELECT user_id FROM balance WHERE user_id = $1`
+	queryGetUserID = `SELECT id FROM users WHERE id = $1`
 )
 
-//nolint:cyclop
-func (s *Storage) GetUserTransactions(userID int64, params *models.TransactionsSelectionParams) (models.Transactions, error) {
-	var (
-		rows pgx.Rows
-		err  error
-	)
+// operationTypeConditions select transactions of the operation types from the selection params.
+var operationTypeConditions = map[int]string{
+	constants.ADD:      `t.operation_type = 'add'`,
+	constants.REDUCE:   `t.operation_type = 'write_off'`,
+	constants.TRANSFER: `t.operation_type = 'transfer'`,
+	constants.RESERVE:  `t.operation_type = 'reserve'`,
+	constants.REVENUE:  `t.operation_type = 'revenue'`,
+	constants.RELEASE:  `t.operation_type = 'release'`,
+	constants.INCOMING: `t.operation_type = 'transfer' AND t.receiver = $1`,
+	constants.OUTGOING: `t.operation_type = 'transfer' AND t.sender = $1`,
+	constants.EXCHANGE: `t.operation_type = 'exchange'`,
+}
+
+// GetUserTransactions returns the page of transactions which follow params.Cursor,
+// the page size is params.Limit or DefaultTransactionsLimit if it is not set.
+func (s *Storage) GetUserTransactions(ctx context.Context, userID int64,
+	params *models.TransactionsSelectionParams) (_ *models.TransactionsPage, err error) {
+	sort, err := newSorting(params)
+	if err != nil {
+		return nil, err
+	}
+	var after *pageCursor
+	if params.Cursor != "" {
+		if after, err = decodeCursor(params.Cursor, sort); err != nil {
+			return nil, err
+		}
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = constants.DefaultTransactionsLimit
+	}
+
+	// a user takes part in an operation as a sender or a receiver, the user posting of the operation
+	// holds the balance after it and its sign gives the direction, revenue operations have no user posting.
+	// An exchange has postings to both wallets of the user, the one in the debited currency is joined
+	builder := newQueryBuilder(`SELECT t.id, t.operation_type,
+			CASE WHEN p.amount > 0 OR (p.amount IS NULL AND t.receiver = $1) THEN 'incoming' ELSE 'outgoing' END,
+			CASE WHEN t.sender = $1 THEN t.receiver ELSE t.sender END, abs(t.amount) AS amount, p.balance_after, t.created,
+			t.comment, t.reason, t.source, t.currency, t.receiver_currency, t.receiver_amount, t.rate
+		FROM transactions t LEFT JOIN postings p ON p.transaction_id = t.id AND p.user_id = $1
+			AND p.currency = CASE WHEN t.sender = $1 THEN t.currency ELSE COALESCE(t.receiver_currency, t.currency) END
+		WHERE (t.sender = $1 OR t.receiver = $1)`, userID)
+
+	operationTypes := params.OperationTypes
+	if params.OperationType != 0 {
+		operationTypes = append([]int{params.OperationType}, operationTypes...)
+	}
+	conditions := make([]string, 0, len(operationTypes))
+	for _, operationType := range operationTypes {
+		condition, ok := operationTypeConditions[operationType]
+		if !ok {
+			return nil, createdErrors.ErrNotSupportedOperationType
+		}
+		conditions = append(conditions, condition)
+	}
+	builder.anyOf(conditions)
+
+	if len(params.CounterpartyIDs) != 0 {
+		ids := builder.arg(params.CounterpartyIDs)
+		builder.where(fmt.Sprintf(`(t.sender = ANY(%s) OR t.receiver = ANY(%s))`, ids, ids))
+	}
+	if params.Reason != "" {
+		builder.where(`t.reason = ` + builder.arg(params.Reason))
+	}
+	if params.Since != nil {
+		builder.where(`t.created >= ` + builder.arg(*params.Since))
+	}
+	if params.From != nil {
+		builder.where(`t.created >= ` + builder.arg(*params.From))
+	}
+	if params.To != nil {
+		builder.where(`t.created < ` + builder.arg(*params.To))
+	}
+	if params.MinAmount != nil {
+		builder.where(`abs(t.amount) >= ` + builder.arg(*params.MinAmount))
+	}
+	if params.MaxAmount != nil {
+		builder.where(`abs(t.amount) <= ` + builder.arg(*params.MaxAmount))
+	}
+	if after != nil {
+		sort.after(builder, after)
+	}
 
-	transaction, err := s.db.Begin(context.Background())
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	query := `SELECT operation_type, receiver, amount, created FROM transactions WHERE sender = $1 `
-
-	switch params.OperationType {
-	case constants.ADD:
-		query += `AND operation_type = 'add' `
-	case constants.REDUCE:
-		query += `AND operation_type = 'write_off' `
-	case constants.TRANSFER:
-		query += `AND operation_type = 'transfer' `
-	}
-
-	if params.Since == "" { // no filter by transaction time
-		switch params.OrderDate {
-		case true:
-			switch params.OrderAmount {
-			case true:
-				query += `ORDER BY amount DESC, created DESC LIMIT NULLIF($2, 0)`
-			case false:
-				query += `ORDER BY created DESC LIMIT NULLIF($2, 0)`
-			}
-		case false:
-			switch params.OrderAmount {
-			case true:
-				query += `ORDER BY amount DESC LIMIT NULLIF($2, 0)`
-			case false:
-				query += `LIMIT NULLIF($2, 0)`
-			}
-		}
-		rows, err = transaction.Query(context.Background(), query, userID, params.Limit)
-		if err != nil {
-			return nil, err
-		}
-	} else { // since transaction time
-		switch params.OrderDate {
-		case true:
-			switch params.OrderAmount {
-			case true:
-				query += `AND created <= $2 ORDER BY amount DESC, created DESC LIMIT NULLIF($3, 0)`
-			case false:
-				query += `AND created <= $2 ORDER BY created DESC LIMIT NULLIF($3, 0)`
-			}
-		case false:
-			switch params.OrderAmount {
-			case true:
-				query += `AND created <= $2 ORDER BY amount DESC LIMIT NULLIF($3, 0)`
-			case false:
-				query += `AND created <= $2 LIMIT NULLIF($3, 0)`
-			}
-		}
-		rows, err = transaction.Query(context.Background(), query, userID, params.Since, params.Limit)
-		if err != nil {
-			return nil, err
-		}
+	// one more transaction is selected to find out whether there is the next page
+	query, args := builder.build(sort.orderBy(), limit+1)
+	rows, err := transaction.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
 	userTransactions := models.Transactions{}
-	var receiver sql.NullInt64
+	var (
+		counterparty     sql.NullInt64
+		balanceAfter     sql.NullInt64
+		comment, reason  sql.NullString
+		source           []byte
+		receiverCurrency sql.NullString
+		receiverAmount   sql.NullInt64
+		rate             sql.NullFloat64
+	)
 	for rows.Next() {
 		var userTransaction models.Transaction
-		if err = rows.Scan(&userTransaction.OperationType, &receiver, &userTransaction.Amount,
-			&userTransaction.Created); err != nil {
+		if err = rows.Scan(&userTransaction.ID, &userTransaction.OperationType, &userTransaction.Direction,
+			&counterparty, &userTransaction.Amount, &balanceAfter, &userTransaction.Created, &comment, &reason,
+			&source, &userTransaction.Currency, &receiverCurrency, &receiverAmount, &rate); err != nil {
 			return nil, err
 		}
 
-		if receiver.Valid {
-			userTransaction.ReceiverID = receiver.Int64
+		if counterparty.Valid {
+			userTransaction.CounterpartyID = counterparty.Int64
 		} else {
-			userTransaction.ReceiverID = 0
+			userTransaction.CounterpartyID = 0
+		}
+		if balanceAfter.Valid {
+			value := money.FromMinor(balanceAfter.Int64)
+			userTransaction.BalanceAfter = &value
+		}
+		userTransaction.Comment, userTransaction.Reason = comment.String, reason.String
+		if receiverAmount.Valid {
+			value := money.FromMinor(receiverAmount.Int64)
+			userTransaction.ReceiverCurrency, userTransaction.ReceiverAmount = receiverCurrency.String, &value
+			userTransaction.Rate = rate.Float64
+		}
+		if source != nil {
+			userTransaction.Source = &models.Source{}
+			if err = json.Unmarshal(source, userTransaction.Source); err != nil {
+				return nil, err
+			}
 		}
 		userTransactions = append(userTransactions, &userTransaction)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return userTransactions, nil
+	page := &models.TransactionsPage{Items: userTransactions}
+	if len(userTransactions) > limit {
+		page.Items, page.HasMore = userTransactions[:limit], true
+		if page.NextCursor, err = newPageCursor(sort, page.Items[len(page.Items)-1]).encode(); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
 }
 
-func (s *Storage) DoesUserExist(userID int64) (bool, error) {
-	transaction, err := s.db.Begin(context.Background())
+func (s *Storage) DoesUserExist(ctx context.Context, userID int64) (_ bool, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
 	defer func() {
 		if err != nil {
 			_ = transaction.Rollback(context.Background())
 		} else {
-			_ = transaction.Commit(context.Background())
+			err = transaction.Commit(ctx)
 		}
 	}()
 
-	if err = transaction.QueryRow(context.Background(), queryGetUserID, userID).Scan(&userID); err != nil {
+	if err = transaction.QueryRow(ctx, queryGetUserID, userID).Scan(&userID); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return false, err
 		}
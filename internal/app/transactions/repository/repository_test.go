@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"regexp"
 	"testing"
@@ -11,10 +12,13 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 )
 
 func TestStorage_DoesUserExist(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
@@ -72,7 +76,7 @@ func TestStorage_DoesUserExist(t *testing.T) {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			test.mock()
-			got, err = storage.DoesUserExist(test.userID)
+			got, err = storage.DoesUserExist(context.Background(), test.userID)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -85,21 +89,43 @@ func TestStorage_DoesUserExist(t *testing.T) {
 	}
 }
 
+const queryHistory = `SELECT t.id, t.operation_type,
+			CASE WHEN p.amount > 0 OR (p.amount IS NULL AND t.receiver = $1) THEN 'incoming' ELSE 'outgoing' END,
+			CASE WHEN t.sender = $1 THEN t.receiver ELSE t.sender END, abs(t.amount) AS amount, p.balance_after, t.created,
+			t.comment, t.reason, t.source, t.currency, t.receiver_currency, t.receiver_amount, t.rate
+		FROM transactions t LEFT JOIN postings p ON p.transaction_id = t.id AND p.user_id = $1
+			AND p.currency = CASE WHEN t.sender = $1 THEN t.currency ELSE COALESCE(t.receiver_currency, t.currency) END
+		WHERE (t.sender = $1 OR t.receiver = $1)`
+
+var historyColumns = []string{"id", "operation_type", "direction", "counterparty", "amount", "balance_after",
+	"created", "comment", "reason", "source", "currency", "receiver_currency", "receiver_amount", "rate"}
+
+func moneyPointer(value money.Money) *money.Money {
+	return &value
+}
+
+func encodedCursor(t *testing.T, cursor *pageCursor) string {
+	token, err := cursor.encode()
+	assert.NoError(t, err)
+	return token
+}
+
 func TestStorage_GetUserTransactions(t *testing.T) {
-	mock, err := pgxmock.NewConn()
+	mock, err := pgxmock.NewPool()
 	if err != nil {
 		t.Errorf("Could not mock database connection: %s", err)
 	}
 	storage := NewStorage(mock)
 
 	timeNow := time.Now()
+	cursorTime := time.Date(2022, 1, 15, 21, 37, 23, 822151000, time.UTC)
 	dbErr := errors.New("Error in database")
 	tests := []struct {
 		name        string
 		userID      int64
 		params      *models.TransactionsSelectionParams
 		mock        func()
-		expected    models.Transactions
+		expected    *models.TransactionsPage
 		expectedErr bool
 		err         error
 	}{
@@ -109,34 +135,416 @@ func TestStorage_GetUserTransactions(t *testing.T) {
 			params: &models.TransactionsSelectionParams{
 				Limit:         10,
 				OperationType: 1,
-				Since:         "",
 				OrderAmount:   false,
 				OrderDate:     false,
 			},
 			mock: func() {
 				var (
-					userID        int64 = 1
-					limit               = 10
-					operationType       = "add"
-					receiver      int64
-					amount        float64 = 1000
-					created               = timeNow
+					userID        int64       = 1
+					limit                     = 11
+					id            int64       = 7
+					operationType             = "add"
+					direction                 = "incoming"
+					amount        money.Money = 1000
+					balanceAfter  int64       = 150000
+					created                   = timeNow
 				)
-				query := `SELECT operation_type, receiver, amount, created FROM transactions WHERE sender = $1 
-				AND operation_type = 'add' LIMIT NULLIF($2, 0)`
-				rows := pgxmock.NewRows([]string{"operation_type", "receiver", "amount", "created"})
-				rows.AddRow(operationType, receiver, amount, created)
+				query := queryHistory + ` AND (t.operation_type = 'add') ORDER BY t.created DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(id, operationType, direction, nil, amount, balanceAfter, created, nil, nil, nil, "RUB", nil, nil, nil)
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, limit).WillReturnRows(rows)
 				mock.ExpectCommit()
 			},
-			expected: models.Transactions{
+			expected: &models.TransactionsPage{Items: models.Transactions{
 				&models.Transaction{
+					ID:            7,
 					OperationType: "add",
+					Direction:     "incoming",
 					Amount:        1000,
+					BalanceAfter:  moneyPointer(150000),
+					Created:       timeNow,
+					Currency:      "RUB",
+				},
+			}},
+		},
+		{
+			name:   "Incoming and outgoing transfers",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				OperationType: constants.TRANSFER,
+				OrderDate:     true,
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.operation_type = 'transfer') ORDER BY t.created DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(2), "transfer", "incoming", int64(2), money.Money(500), int64(1500), timeNow,
+					nil, nil, nil, "RUB", nil, nil, nil)
+				rows.AddRow(int64(1), "transfer", "outgoing", int64(3), money.Money(200), int64(1000), timeNow,
+					nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, constants.DefaultTransactionsLimit+1).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:             2,
+					OperationType:  "transfer",
+					Direction:      "incoming",
+					CounterpartyID: 2,
+					Amount:         500,
+					BalanceAfter:   moneyPointer(1500),
+					Created:        timeNow,
+					Currency:       "RUB",
+				},
+				&models.Transaction{
+					ID:             1,
+					OperationType:  "transfer",
+					Direction:      "outgoing",
+					CounterpartyID: 3,
+					Amount:         200,
+					BalanceAfter:   moneyPointer(1000),
+					Created:        timeNow,
+					Currency:       "RUB",
+				},
+			}},
+		},
+		{
+			name:   "Only incoming transfers with conversion",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:         10,
+				OperationType: constants.INCOMING,
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.operation_type = 'transfer' AND t.receiver = $1) ` +
+					`ORDER BY t.created DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(2), "transfer", "incoming", int64(2), money.Money(50000), int64(1655), timeNow,
+					nil, nil, nil, "RUB", "USD", int64(655), 0.0131)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, 11).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:               2,
+					OperationType:    "transfer",
+					Direction:        "incoming",
+					CounterpartyID:   2,
+					Amount:           50000,
+					BalanceAfter:     moneyPointer(1655),
+					Created:          timeNow,
+					Currency:         "RUB",
+					ReceiverCurrency: "USD",
+					ReceiverAmount:   moneyPointer(655),
+					Rate:             0.0131,
+				},
+			}},
+		},
+		{
+			name:   "Exchanges between wallets of the user",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				OperationType: constants.EXCHANGE,
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.operation_type = 'exchange') ORDER BY t.created DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(9), "exchange", "outgoing", nil, money.Money(100000), int64(50000), timeNow,
+					nil, nil, nil, "RUB", "USD", int64(1303), 0.013035)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, constants.DefaultTransactionsLimit+1).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:               9,
+					OperationType:    "exchange",
+					Direction:        "outgoing",
+					Amount:           100000,
+					BalanceAfter:     moneyPointer(50000),
+					Created:          timeNow,
+					Currency:         "RUB",
+					ReceiverCurrency: "USD",
+					ReceiverAmount:   moneyPointer(1303),
+					Rate:             0.013035,
+				},
+			}},
+		},
+		{
+			name:   "Filter by reason",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:     5,
+				Since:     &cursorTime,
+				OrderDate: true,
+				Reason:    "order_payment",
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND t.reason = $2 AND t.created >= $3 ` +
+					`ORDER BY t.created DESC, t.id DESC LIMIT $4`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(3), "write_off", "outgoing", nil, money.Money(300), int64(700), timeNow,
+					"Payment for order #10", "order_payment", []byte(`{"order_id":10,"service_id":100}`), "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, "order_payment", cursorTime, 6).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:            3,
+					OperationType: "write_off",
+					Direction:     "outgoing",
+					Amount:        300,
+					BalanceAfter:  moneyPointer(700),
 					Created:       timeNow,
+					Currency:      "RUB",
+					Purpose: models.Purpose{
+						Comment: "Payment for order #10",
+						Reason:  "order_payment",
+						Source:  &models.Source{OrderID: 10, ServiceID: 100},
+					},
 				},
+			}},
+		},
+		{
+			name:   "Commit of a reservation does not change the balance",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:         10,
+				OperationType: constants.REVENUE,
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.operation_type = 'revenue') ORDER BY t.created DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(4), "revenue", "outgoing", nil, money.Money(300), nil, timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, 11).WillReturnRows(rows)
+				mock.ExpectCommit()
 			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:            4,
+					OperationType: "revenue",
+					Direction:     "outgoing",
+					Amount:        300,
+					Created:       timeNow,
+					Currency:      "RUB",
+				},
+			}},
+		},
+		{
+			name:   "First page of transactions sorted by amount",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:       2,
+				OrderAmount: true,
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` ORDER BY abs(t.amount) DESC, t.id DESC LIMIT $2`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(5), "add", "incoming", nil, money.Money(500), int64(1500), timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				rows.AddRow(int64(3), "add", "incoming", nil, money.Money(300), int64(1000), timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				rows.AddRow(int64(2), "add", "incoming", nil, money.Money(300), int64(700), timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, 3).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{
+				Items: models.Transactions{
+					&models.Transaction{
+						ID:            5,
+						OperationType: "add",
+						Direction:     "incoming",
+						Amount:        500,
+						BalanceAfter:  moneyPointer(1500),
+						Created:       timeNow,
+						Currency:      "RUB",
+					},
+					&models.Transaction{
+						ID:            3,
+						OperationType: "add",
+						Direction:     "incoming",
+						Amount:        300,
+						BalanceAfter:  moneyPointer(1000),
+						Created:       timeNow,
+						Currency:      "RUB",
+					},
+				},
+				NextCursor: encodedCursor(t, &pageCursor{Sort: "amount:desc,id:desc", Amount: 300, Created: timeNow, ID: 3}),
+				HasMore:    true,
+			},
+		},
+		{
+			name:   "Next page starts after the cursor, ties are ordered by ID",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:       2,
+				OrderAmount: true,
+				OrderDate:   true,
+				Order:       "asc",
+				Cursor: encodedCursor(t, &pageCursor{
+					Sort: "amount:asc,created:asc,id:asc", Amount: 300, Created: cursorTime, ID: 3,
+				}),
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (abs(t.amount) > $2 OR abs(t.amount) = $2 AND ` +
+					`(t.created > $3 OR t.created = $3 AND (t.id > $4))) ` +
+					`ORDER BY abs(t.amount) ASC, t.created ASC, t.id ASC LIMIT $5`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(4), "add", "incoming", nil, money.Money(300), int64(1300), timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, money.Money(300), cursorTime, int64(3), 3).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:            4,
+					OperationType: "add",
+					Direction:     "incoming",
+					Amount:        300,
+					BalanceAfter:  moneyPointer(1300),
+					Created:       timeNow,
+					Currency:      "RUB",
+				},
+			}},
+		},
+		{
+			name:   "Date and amount ranges, several types and counterparties",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:           10,
+				OperationType:   constants.ADD,
+				OperationTypes:  []int{constants.OUTGOING},
+				CounterpartyIDs: []int64{2, 3},
+				From:            &cursorTime,
+				To:              &timeNow,
+				MinAmount:       moneyPointer(100),
+				MaxAmount:       moneyPointer(500),
+				Sort:            "amount:asc,created:desc",
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.operation_type = 'add' OR t.operation_type = 'transfer' AND ` +
+					`t.sender = $1) AND (t.sender = ANY($2) OR t.receiver = ANY($2)) AND t.created >= $3 AND ` +
+					`t.created < $4 AND abs(t.amount) >= $5 AND abs(t.amount) <= $6 ` +
+					`ORDER BY abs(t.amount) ASC, t.created DESC, t.id DESC LIMIT $7`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(6), "transfer", "outgoing", int64(2), money.Money(200), int64(800), timeNow,
+					nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, []int64{2, 3}, cursorTime, timeNow, money.Money(100), money.Money(500), 11).
+					WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					ID:             6,
+					OperationType:  "transfer",
+					Direction:      "outgoing",
+					CounterpartyID: 2,
+					Amount:         200,
+					BalanceAfter:   moneyPointer(800),
+					Created:        timeNow,
+					Currency:       "RUB",
+				},
+			}},
+		},
+		{
+			name:   "Cursor with mixed sort directions",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit: 1,
+				Sort:  "created:asc,amount:desc",
+				Cursor: encodedCursor(t, &pageCursor{
+					Sort: "created:asc,amount:desc,id:desc", Amount: 300, Created: cursorTime, ID: 3,
+				}),
+			},
+			mock: func() {
+				var userID int64 = 1
+				query := queryHistory + ` AND (t.created > $2 OR t.created = $2 AND ` +
+					`(abs(t.amount) < $3 OR abs(t.amount) = $3 AND (t.id < $4))) ` +
+					`ORDER BY t.created ASC, abs(t.amount) DESC, t.id DESC LIMIT $5`
+				rows := pgxmock.NewRows(historyColumns)
+				rows.AddRow(int64(2), "add", "incoming", nil, money.Money(300), int64(1300), cursorTime, nil, nil, nil, "RUB", nil, nil, nil)
+				rows.AddRow(int64(8), "add", "incoming", nil, money.Money(100), int64(1400), timeNow, nil, nil, nil, "RUB", nil, nil, nil)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs(userID, cursorTime, money.Money(300), int64(3), 2).WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			expected: &models.TransactionsPage{
+				Items: models.Transactions{
+					&models.Transaction{
+						ID:            2,
+						OperationType: "add",
+						Direction:     "incoming",
+						Amount:        300,
+						BalanceAfter:  moneyPointer(1300),
+						Created:       cursorTime,
+						Currency:      "RUB",
+					},
+				},
+				NextCursor: encodedCursor(t, &pageCursor{
+					Sort: "created:asc,amount:desc,id:desc", Amount: 300, Created: cursorTime, ID: 2,
+				}),
+				HasMore: true,
+			},
+		},
+		{
+			name:   "Invalid sort",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Sort: "amount:up",
+			},
+			mock:        func() {},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidSort,
+		},
+		{
+			name:   "Not supported operation type",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				OperationTypes: []int{constants.ADD, 100},
+			},
+			mock:        func() {},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedOperationType,
+		},
+		{
+			name:   "Cursor was created for another sorting",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Limit:     2,
+				OrderDate: true,
+				Cursor:    encodedCursor(t, &pageCursor{Sort: "amount:desc,id:desc", Amount: 300, ID: 3}),
+			},
+			mock:        func() {},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidCursor,
+		},
+		{
+			name:   "Malformed cursor",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Cursor: "not a cursor",
+			},
+			mock:        func() {},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidCursor,
 		},
 		{
 			name:   "Error in database",
@@ -144,17 +552,15 @@ func TestStorage_GetUserTransactions(t *testing.T) {
 			params: &models.TransactionsSelectionParams{
 				Limit:         10,
 				OperationType: 1,
-				Since:         "",
 				OrderAmount:   false,
 				OrderDate:     false,
 			},
 			mock: func() {
 				var (
 					userID int64 = 1
-					limit        = 10
+					limit        = 11
 				)
-				query := `SELECT operation_type, receiver, amount, created FROM transactions WHERE sender = $1 
-				AND operation_type = 'add' LIMIT NULLIF($2, 0)`
+				query := queryHistory + ` AND (t.operation_type = 'add') ORDER BY t.created DESC, t.id DESC LIMIT $2`
 				mock.ExpectBegin()
 				mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID, limit).WillReturnError(dbErr)
 				mock.ExpectRollback()
@@ -164,20 +570,20 @@ func TestStorage_GetUserTransactions(t *testing.T) {
 		},
 	}
 
-	var got models.Transactions
+	var got *models.TransactionsPage
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			test.mock()
-			got, err = storage.GetUserTransactions(test.userID, test.params)
+			got, err = storage.GetUserTransactions(context.Background(), test.userID, test.params)
 
 			if test.expectedErr {
-				assert.Error(t, err)
-				assert.Equal(t, test.err, err)
+				assert.ErrorIs(t, err, test.err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expected, got)
 			}
+			assert.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
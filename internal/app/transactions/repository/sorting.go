@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+// sortKey is a column transactions can be ordered by.
+type sortKey struct {
+	name   string
+	column string
+}
+
+var (
+	sortByAmount  = sortKey{name: "amount", column: "abs(t.amount)"}
+	sortByCreated = sortKey{name: "created", column: "t.created"}
+	// sortByID is always the last key, so transactions with equal amounts and dates keep the same order
+	sortByID = sortKey{name: "id", column: "t.id"}
+
+	sortKeys = map[string]sortKey{
+		sortByAmount.name:  sortByAmount,
+		sortByCreated.name: sortByCreated,
+	}
+)
+
+type sortField struct {
+	key  sortKey
+	desc bool
+}
+
+func (f sortField) direction() string {
+	if f.desc {
+		return "desc"
+	}
+
+	return "asc"
+}
+
+// sorting is the order of transactions in the list.
+type sorting []sortField
+
+// newSorting parses params.Sort like "amount:asc,created:desc", a key without direction is sorted descending.
+// If params.Sort is empty, the order is set by order_amount, order_date and order, by default it is created:desc.
+func newSorting(params *models.TransactionsSelectionParams) (sorting, error) {
+	var result sorting
+	if params.Sort == "" {
+		desc := params.Order != "asc"
+		if params.OrderAmount {
+			result = append(result, sortField{key: sortByAmount, desc: desc})
+		}
+		if params.OrderDate || !params.OrderAmount {
+			result = append(result, sortField{key: sortByCreated, desc: desc})
+		}
+	} else {
+		used := map[string]bool{}
+		for _, field := range strings.Split(params.Sort, ",") {
+			name, direction := field, "desc"
+			if i := strings.IndexByte(field, ':'); i >= 0 {
+				name, direction = field[:i], field[i+1:]
+			}
+
+			key, ok := sortKeys[name]
+			if !ok || used[name] || (direction != "asc" && direction != "desc") {
+				return nil, fmt.Errorf("%w: %q", createdErrors.ErrInvalidSort, field)
+			}
+			used[name] = true
+			result = append(result, sortField{key: key, desc: direction == "desc"})
+		}
+	}
+
+	return append(result, sortField{key: sortByID, desc: result[len(result)-1].desc}), nil
+}
+
+// String returns the sorting as it is saved in cursors, e.g. "amount:asc,created:desc,id:desc".
+func (s sorting) String() string {
+	fields := make([]string, 0, len(s))
+	for _, field := range s {
+		fields = append(fields, field.key.name+":"+field.direction())
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// orderBy returns the list of ORDER BY clause.
+func (s sorting) orderBy() string {
+	columns := make([]string, 0, len(s))
+	for _, field := range s {
+		columns = append(columns, field.key.column+" "+strings.ToUpper(field.direction()))
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// after adds the condition selecting transactions which follow the cursor in the sorting:
+// k1 > $2 OR k1 = $2 AND (k2 < $3 OR k2 = $3 AND (...)), the operator depends on the direction of the key.
+func (s sorting) after(builder *queryBuilder, cursor *pageCursor) {
+	placeholders := make([]string, 0, len(s))
+	for _, field := range s {
+		placeholders = append(placeholders, builder.arg(cursor.value(field.key)))
+	}
+
+	var condition string
+	for i := len(s) - 1; i >= 0; i-- {
+		operator := ">"
+		if s[i].desc {
+			operator = "<"
+		}
+
+		column := s[i].key.column
+		if condition == "" {
+			condition = fmt.Sprintf("%s %s %s", column, operator, placeholders[i])
+		} else {
+			condition = fmt.Sprintf("%s %s %s OR %s = %s AND (%s)", column, operator, placeholders[i],
+				column, placeholders[i], condition)
+		}
+	}
+	builder.where("(" + condition + ")")
+}
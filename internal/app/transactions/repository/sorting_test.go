@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestNewSorting(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      *models.TransactionsSelectionParams
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name:     "Created descending by default",
+			params:   &models.TransactionsSelectionParams{},
+			expected: "created:desc,id:desc",
+		},
+		{
+			name:     "Order by amount and date ascending",
+			params:   &models.TransactionsSelectionParams{OrderAmount: true, OrderDate: true, Order: "asc"},
+			expected: "amount:asc,created:asc,id:asc",
+		},
+		{
+			name:     "Sort overrides order flags",
+			params:   &models.TransactionsSelectionParams{OrderAmount: true, Sort: "created:asc,amount:desc"},
+			expected: "created:asc,amount:desc,id:desc",
+		},
+		{
+			name:     "Key without direction is sorted descending",
+			params:   &models.TransactionsSelectionParams{Sort: "amount"},
+			expected: "amount:desc,id:desc",
+		},
+		{
+			name:        "Unknown key",
+			params:      &models.TransactionsSelectionParams{Sort: "receiver:asc"},
+			expectedErr: true,
+		},
+		{
+			name:        "Unknown direction",
+			params:      &models.TransactionsSelectionParams{Sort: "amount:up"},
+			expectedErr: true,
+		},
+		{
+			name:        "Key is used twice",
+			params:      &models.TransactionsSelectionParams{Sort: "amount:asc,amount:desc"},
+			expectedErr: true,
+		},
+		{
+			name:        "Empty key",
+			params:      &models.TransactionsSelectionParams{Sort: "amount:asc,"},
+			expectedErr: true,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			got, err := newSorting(test.params)
+
+			if test.expectedErr {
+				assert.ErrorIs(t, err, createdErrors.ErrInvalidSort)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got.String())
+			}
+		})
+	}
+}
@@ -1,8 +1,19 @@
 package transactions
 
-import "avito-tech-task/internal/app/models"
+import (
+	"context"
+
+	"avito-tech-task/internal/app/models"
+)
 
 //go:generate moq -out ./mock/transactions_usecase_mock.go -pkg mock . Service:MockService
 type Service interface {
-	GetUserTransactions(int64, *models.TransactionsSelectionParams) (models.Transactions, error)
+	GetUserTransactions(context.Context, int64, *models.TransactionsSelectionParams) (*models.TransactionsPage, error)
+}
+
+//go:generate moq -out ./mock/transactions_metrics_mock.go -pkg mock . Metrics:MockMetrics
+
+// Metrics records the use of historical rates.
+type Metrics interface {
+	HistoryConverted(currency string, transactions int)
 }
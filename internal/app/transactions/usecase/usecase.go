@@ -1,35 +1,55 @@
 package usecase
 
 import (
-	"avito-tech-task/internal/pkg/utils"
+	"context"
 	"strings"
+	"time"
+
+	"avito-tech-task/internal/pkg/utils"
 
 	"avito-tech-task/internal/app/models"
 	"avito-tech-task/internal/app/transactions"
+	"avito-tech-task/internal/pkg/constants"
+	"avito-tech-task/internal/pkg/currency"
 	createdErrors "avito-tech-task/internal/pkg/errors"
 )
 
+const (
+	dateLayout        = "2006-01-02"
+	directionIncoming = "incoming"
+)
+
 type Service struct {
 	storage   transactions.Storage
 	validator *utils.Validation
+	converter currency.ConverterIface
+	metrics   transactions.Metrics
 }
 
-func NewService(storage transactions.Storage, validator *utils.Validation) *Service {
+func NewService(storage transactions.Storage, validator *utils.Validation, converter currency.ConverterIface,
+	metrics transactions.Metrics) *Service {
 	return &Service{
 		storage:   storage,
 		validator: validator,
+		converter: converter,
+		metrics:   metrics,
 	}
 }
 
-func (s *Service) GetUserTransactions(userID int64, params *models.TransactionsSelectionParams) (models.Transactions, error) {
+func (s *Service) GetUserTransactions(ctx context.Context, userID int64, params *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
 	errs := s.validator.Validate(params) // validation
 	for _, err := range errs {
-		if err.Field() == "Limit" {
+		switch err.Field() {
+		case "Limit":
 			return nil, createdErrors.ErrNegativeLimit
+		case "Order":
+			return nil, createdErrors.ErrInvalidSortOrder
+		case "MinAmount", "MaxAmount":
+			return nil, createdErrors.ErrInvalidAmountFilter
 		}
 	}
 
-	doesUserExist, err := s.storage.DoesUserExist(userID)
+	doesUserExist, err := s.storage.DoesUserExist(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -37,12 +57,78 @@ func (s *Service) GetUserTransactions(userID int64, params *models.TransactionsS
 		return nil, createdErrors.ErrUserDoesNotExist
 	}
 
-	if params.Since != "" {
-		// without this parsing we get timestamp like that: 2022-01-15T21:37:23.822151 03:00
-		// but database will process only this format: 2022-01-15T21:37:23.822151 +03:00
-		// so we need to add "+"
-		params.Since = strings.Join(strings.Split(params.Since, " "), " +")
+	params.Currency = strings.ToUpper(strings.TrimSpace(params.Currency))
+	if params.Currency != "" {
+		if _, err = s.converter.Get(params.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := s.storage.GetUserTransactions(ctx, userID, params)
+	if err != nil || params.Currency == "" {
+		return page, err
+	}
+
+	if err = s.convert(ctx, page.Items, params.Currency); err != nil {
+		return nil, err
+	}
+	s.metrics.HistoryConverted(params.Currency, len(page.Items))
+
+	return page, nil
+}
+
+// convert sets the converted amounts of the transactions at the rates of the days they were created.
+func (s *Service) convert(ctx context.Context, items models.Transactions, currencyCode string) error {
+	rates := make(map[string]*currency.Rate) // transactions of one day share the rates
+	rateAt := func(code string, at time.Time) (*currency.Rate, error) {
+		key := code + " " + at.UTC().Format(dateLayout)
+		if rate, ok := rates[key]; ok {
+			return rate, nil
+		}
+		rate, err := s.converter.GetAt(ctx, code, at)
+		if err != nil {
+			return nil, err
+		}
+		rates[key] = rate
+		return rate, nil
+	}
+	// exchangeRate is the amount of currencyCode for one unit of the currency, rates are stored for the base currency
+	exchangeRate := func(code string, at time.Time) (*currency.Rate, error) {
+		target, err := rateAt(currencyCode, at)
+		if err != nil || code == "" || code == constants.BaseCurrency {
+			return target, err
+		}
+		source, err := rateAt(code, at)
+		if err != nil {
+			return nil, err
+		}
+		return &currency.Rate{Value: target.Value / source.Value, Date: target.Date}, nil
+	}
+
+	digits := currency.Digits(currencyCode)
+	for _, item := range items {
+		rate, err := exchangeRate(item.Currency, item.Created)
+		if err != nil {
+			return err
+		}
+
+		item.Converted = &models.ConvertedAmount{
+			Currency: currencyCode,
+			Amount:   item.Amount.Convert(rate.Value, digits),
+			Rate:     rate.Value,
+			RateDate: rate.Date.Format(dateLayout),
+		}
+		if item.BalanceAfter != nil {
+			balanceRate := rate
+			if item.ReceiverCurrency != "" && item.Direction == directionIncoming { // the balance of the receiver wallet
+				if balanceRate, err = exchangeRate(item.ReceiverCurrency, item.Created); err != nil {
+					return err
+				}
+			}
+			balanceAfter := item.BalanceAfter.Convert(balanceRate.Value, digits)
+			item.Converted.BalanceAfter = &balanceAfter
+		}
 	}
 
-	return s.storage.GetUserTransactions(userID, params)
+	return nil
 }
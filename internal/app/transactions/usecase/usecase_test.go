@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"avito-tech-task/internal/pkg/utils"
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -10,19 +11,35 @@ import (
 
 	"avito-tech-task/internal/app/models"
 	storageMock "avito-tech-task/internal/app/transactions/mock"
+	"avito-tech-task/internal/pkg/currency"
+	converterMock "avito-tech-task/internal/pkg/currency/mock"
 	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
 )
 
 func TestService_GetUserTransactions(t *testing.T) {
 	storageError := errors.New("Error in storage")
 
 	timeNow := time.Now()
+	since := timeNow.Add(-time.Hour)
+	negativeAmount := money.Money(-100)
+	created := time.Date(2022, 1, 19, 21, 37, 23, 0, time.UTC)
+	rateDate := time.Date(2022, 1, 18, 0, 0, 0, 0, time.UTC)
+	balanceAfter := money.Money(150000)
+	convertedBalanceAfter := money.Amount{Minor: 1965, Digits: 2}
+	eurRate, usdRate := 0.0115, 0.0131
+	receiverAmount, receiverBalanceAfter := money.Money(76336), money.Money(100000)
+	convertedReceiverBalanceAfter := money.Amount{Minor: 1150, Digits: 2}
+	convertedYenBalanceAfter := money.Amount{Minor: 2280, Digits: 0}
 	tests := []struct {
-		name        string
-		userID      int64
-		params      *models.TransactionsSelectionParams
-		storageMock *storageMock.MockStorage
-		expected    models.Transactions
+		name          string
+		userID        int64
+		params        *models.TransactionsSelectionParams
+		storageMock   *storageMock.MockStorage
+		converterMock *converterMock.MockConverterIface
+		expected      *models.TransactionsPage
+		// converted is the currency the history is converted to, it is reported to metrics
+		converted   string
 		expectedErr bool
 		err         error
 	}{
@@ -32,31 +49,31 @@ func TestService_GetUserTransactions(t *testing.T) {
 			params: &models.TransactionsSelectionParams{
 				Limit:         0,
 				OperationType: 1,
-				Since:         "some datetime",
+				Since:         &since,
 				OrderAmount:   false,
 				OrderDate:     false,
 			},
 			storageMock: &storageMock.MockStorage{
-				DoesUserExistFunc: func(n int64) (bool, error) {
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
 					return true, nil
 				},
-				GetUserTransactionsFunc: func(n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (models.Transactions, error) {
-					return models.Transactions{
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return &models.TransactionsPage{Items: models.Transactions{
 						&models.Transaction{
 							OperationType: "add",
 							Amount:        1000,
 							Created:       timeNow,
 						},
-					}, nil
+					}}, nil
 				},
 			},
-			expected: models.Transactions{
+			expected: &models.TransactionsPage{Items: models.Transactions{
 				&models.Transaction{
 					OperationType: "add",
 					Amount:        1000,
 					Created:       timeNow,
 				},
-			},
+			}},
 		},
 		{
 			name:   "User does not exist",
@@ -64,12 +81,11 @@ func TestService_GetUserTransactions(t *testing.T) {
 			params: &models.TransactionsSelectionParams{
 				Limit:         0,
 				OperationType: 1,
-				Since:         "",
 				OrderAmount:   false,
 				OrderDate:     false,
 			},
 			storageMock: &storageMock.MockStorage{
-				DoesUserExistFunc: func(n int64) (bool, error) {
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
 					return false, nil
 				},
 			},
@@ -82,27 +98,214 @@ func TestService_GetUserTransactions(t *testing.T) {
 			params: &models.TransactionsSelectionParams{
 				Limit:         0,
 				OperationType: 1,
-				Since:         "",
 				OrderAmount:   false,
 				OrderDate:     false,
 			},
 			storageMock: &storageMock.MockStorage{
-				DoesUserExistFunc: func(n int64) (bool, error) {
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
 					return false, storageError
 				},
 			},
 			expectedErr: true,
 			err:         storageError,
 		},
+		{
+			name:   "Invalid sort order",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Order: "up",
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidSortOrder,
+		},
+		{
+			name:   "Negative amount filter",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				MinAmount: &negativeAmount,
+			},
+			storageMock: &storageMock.MockStorage{},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidAmountFilter,
+		},
+		{
+			name:      "Transactions are converted at the rate of their day",
+			converted: "USD",
+			userID:    1,
+			params: &models.TransactionsSelectionParams{
+				Currency: "USD",
+			},
+			storageMock: &storageMock.MockStorage{
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
+					return true, nil
+				},
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return &models.TransactionsPage{Items: models.Transactions{
+						&models.Transaction{OperationType: "add", Amount: 1000, BalanceAfter: &balanceAfter, Created: created},
+						&models.Transaction{OperationType: "add", Amount: 500, Created: created.Add(-time.Hour)},
+					}}, nil
+				},
+			},
+			converterMock: &converterMock.MockConverterIface{
+				GetFunc: func(s string) (float64, error) {
+					return 0.0132, nil
+				},
+				GetAtFunc: func(ctx context.Context, s string, at time.Time) (*currency.Rate, error) {
+					return &currency.Rate{Value: 0.0131, Date: rateDate}, nil
+				},
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					OperationType: "add",
+					Amount:        1000,
+					BalanceAfter:  &balanceAfter,
+					Created:       created,
+					Converted: &models.ConvertedAmount{
+						Currency:     "USD",
+						Amount:       money.Amount{Minor: 13, Digits: 2},
+						BalanceAfter: &convertedBalanceAfter,
+						Rate:         0.0131,
+						RateDate:     "2022-01-18",
+					},
+				},
+				&models.Transaction{
+					OperationType: "add",
+					Amount:        500,
+					Created:       created.Add(-time.Hour),
+					Converted: &models.ConvertedAmount{
+						Currency: "USD",
+						Amount:   money.Amount{Minor: 7, Digits: 2},
+						Rate:     0.0131,
+						RateDate: "2022-01-18",
+					},
+				},
+			}},
+		},
+		{
+			name:      "Transfer with conversion is converted from its currency, the balance from the receiver currency",
+			converted: "EUR",
+			userID:    2,
+			params: &models.TransactionsSelectionParams{
+				Currency: "EUR",
+			},
+			storageMock: &storageMock.MockStorage{
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
+					return true, nil
+				},
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return &models.TransactionsPage{Items: models.Transactions{
+						&models.Transaction{OperationType: "transfer", Direction: "incoming", Amount: 1000,
+							BalanceAfter: &receiverBalanceAfter, Created: created, Currency: "USD", ReceiverCurrency: "RUB",
+							ReceiverAmount: &receiverAmount, Rate: 76.336},
+					}}, nil
+				},
+			},
+			converterMock: &converterMock.MockConverterIface{
+				GetFunc: func(s string) (float64, error) {
+					return eurRate, nil
+				},
+				GetAtFunc: func(ctx context.Context, s string, at time.Time) (*currency.Rate, error) {
+					if s == "USD" {
+						return &currency.Rate{Value: usdRate, Date: rateDate}, nil
+					}
+					return &currency.Rate{Value: eurRate, Date: rateDate}, nil
+				},
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					OperationType:    "transfer",
+					Direction:        "incoming",
+					Amount:           1000,
+					BalanceAfter:     &receiverBalanceAfter,
+					Created:          created,
+					Currency:         "USD",
+					ReceiverCurrency: "RUB",
+					ReceiverAmount:   &receiverAmount,
+					Rate:             76.336,
+					Converted: &models.ConvertedAmount{
+						Currency:     "EUR",
+						Amount:       money.Amount{Minor: 878, Digits: 2},
+						BalanceAfter: &convertedReceiverBalanceAfter,
+						Rate:         eurRate / usdRate,
+						RateDate:     "2022-01-18",
+					},
+				},
+			}},
+		},
+		{
+			name:      "Currency without minor units is converted to whole units",
+			converted: "JPY",
+			userID:    1,
+			params: &models.TransactionsSelectionParams{
+				Currency: "jpy",
+			},
+			storageMock: &storageMock.MockStorage{
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
+					return true, nil
+				},
+				GetUserTransactionsFunc: func(ctx context.Context, n int64, transactionsSelectionParams *models.TransactionsSelectionParams) (*models.TransactionsPage, error) {
+					return &models.TransactionsPage{Items: models.Transactions{
+						&models.Transaction{OperationType: "add", Amount: 1050, BalanceAfter: &balanceAfter, Created: created},
+					}}, nil
+				},
+			},
+			converterMock: &converterMock.MockConverterIface{
+				GetFunc: func(s string) (float64, error) {
+					if s != "JPY" {
+						return 0, createdErrors.ErrNotSupportedCurrency
+					}
+					return 1.52, nil
+				},
+				GetAtFunc: func(ctx context.Context, s string, at time.Time) (*currency.Rate, error) {
+					return &currency.Rate{Value: 1.52, Date: rateDate}, nil
+				},
+			},
+			expected: &models.TransactionsPage{Items: models.Transactions{
+				&models.Transaction{
+					OperationType: "add",
+					Amount:        1050,
+					BalanceAfter:  &balanceAfter,
+					Created:       created,
+					Converted: &models.ConvertedAmount{
+						Currency:     "JPY",
+						Amount:       money.Amount{Minor: 16, Digits: 0},
+						BalanceAfter: &convertedYenBalanceAfter,
+						Rate:         1.52,
+						RateDate:     "2022-01-18",
+					},
+				},
+			}},
+		},
+		{
+			name:   "Not supported currency",
+			userID: 1,
+			params: &models.TransactionsSelectionParams{
+				Currency: "XXX",
+			},
+			storageMock: &storageMock.MockStorage{
+				DoesUserExistFunc: func(ctx context.Context, n int64) (bool, error) {
+					return true, nil
+				},
+			},
+			converterMock: &converterMock.MockConverterIface{
+				GetFunc: func(s string) (float64, error) {
+					return 0, createdErrors.ErrNotSupportedCurrency
+				},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedCurrency,
+		},
 	}
 
 	for _, current := range tests {
 		test := current
 		t.Run(test.name, func(t *testing.T) {
 			validator := utils.NewValidator()
-			service := NewService(test.storageMock, validator)
+			metrics := &storageMock.MockMetrics{HistoryConvertedFunc: func(currency string, transactions int) {}}
+			service := NewService(test.storageMock, validator, test.converterMock, metrics)
 
-			got, err := service.GetUserTransactions(test.userID, test.params)
+			got, err := service.GetUserTransactions(context.Background(), test.userID, test.params)
 
 			if test.expectedErr {
 				assert.Error(t, err)
@@ -111,6 +314,13 @@ func TestService_GetUserTransactions(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Equal(t, test.expected, got)
 			}
+
+			if test.converted == "" {
+				assert.Empty(t, metrics.HistoryConvertedCalls())
+			} else if assert.Len(t, metrics.HistoryConvertedCalls(), 1) {
+				assert.Equal(t, test.converted, metrics.HistoryConvertedCalls()[0].Currency)
+				assert.Equal(t, len(test.expected.Items), metrics.HistoryConvertedCalls()[0].Transactions)
+			}
 		})
 	}
 }
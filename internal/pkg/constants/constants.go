@@ -7,10 +7,48 @@ const (
 	ADD
 	REDUCE
 	TRANSFER
+	RESERVE
+	REVENUE
+	RELEASE
+	INCOMING // incoming transfers
+	OUTGOING // outgoing transfers
+	EXCHANGE // exchange between wallets of the user
 
 	ConfigPath              = "config/config.toml"
-	InvalidBodyMessage      = "Invalid body"
-	InvalidUserIDMessage    = "Invalid user id"
-	InvalidQueryParams      = "Invalid query params"
+	ProblemContentType      = "application/problem+json"
 	CurrencyAPIUpdatePeriod = 24 * time.Hour
+	DefaultMaxRateAge       = 2 * CurrencyAPIUpdatePeriod // the rates missed an update
+	BaseCurrency            = "RUB"
+	RateProviderTimeout     = 10 * time.Second
+
+	DefaultTransactionsLimit = 100
+
+	// CBR sets the rates of the next day at 11:30 Moscow time, cbr-xml-daily.ru gets them a few minutes later
+	DefaultRatesRefreshAt       = "11:45"
+	DefaultRatesRefreshTimezone = "Europe/Moscow"
+	DefaultRatesRetryMinBackoff = time.Minute
+	DefaultRatesRetryMaxBackoff = time.Hour
+
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	IdempotencyCleanupPeriod = time.Hour
+	IdempotencyKeyMaxLength  = 255
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+	// DefaultIdempotencyLockLease is longer than requests are processed for, so only keys of crashed ones are taken over
+	DefaultIdempotencyLockLease = time.Minute
+
+	DefaultExchangeQuoteTTL = time.Minute
+
+	ServerAddress          = "0.0.0.0:5000"
+	DefaultShutdownTimeout = 30 * time.Second
+
+	HealthStatusOK     = "ok"
+	HealthStatusFail   = "fail"
+	HealthCheckTimeout = 2 * time.Second
+
+	LogFileName     = "service.log"
+	LogOutputStdout = "stdout"
+	LogOutputFile   = "file"
+	LogFormatJSON   = "json"
+	LogFormatText   = "text"
 )
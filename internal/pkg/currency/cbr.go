@@ -0,0 +1,110 @@
+package currency
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CBRJSONProvider gets rates of the Central Bank of Russia from the JSON of cbr-xml-daily.ru.
+type CBRJSONProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewCBRJSONProvider(url string, client *http.Client) *CBRJSONProvider {
+	return &CBRJSONProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *CBRJSONProvider) Name() string {
+	return CBRJSON
+}
+
+func (p *CBRJSONProvider) Rates(ctx context.Context) (*Rates, error) {
+	body, err := fetch(ctx, p.client, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRateFile(body)
+}
+
+// CBRXMLProvider gets rates from the XML feed of the Central Bank of Russia, the feed quotes
+// rubles for the nominal amount of every currency.
+type CBRXMLProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewCBRXMLProvider(url string, client *http.Client) *CBRXMLProvider {
+	return &CBRXMLProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *CBRXMLProvider) Name() string {
+	return CBRXML
+}
+
+type cbrValCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+func (p *CBRXMLProvider) Rates(ctx context.Context) (*Rates, error) {
+	body, err := fetch(ctx, p.client, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(label, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("not supported charset %q", label)
+	}
+	valCurs := &cbrValCurs{}
+	if err = decoder.Decode(valCurs); err != nil {
+		return nil, fmt.Errorf("could not unmarshal rates: %w", err)
+	}
+
+	values := make(map[string]float64, len(valCurs.Valutes))
+	for _, valute := range valCurs.Valutes {
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(valute.Nominal), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nominal of %s: %w", valute.CharCode, err)
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(valute.Value), ",", "."), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid value of %s: %q", valute.CharCode, valute.Value)
+		}
+		values[valute.CharCode] = nominal / value
+	}
+
+	rates, err := newRates("RUB", values)
+	if err != nil {
+		return nil, err
+	}
+	if rates.Date, err = time.Parse("02.01.2006", valCurs.Date); err != nil {
+		return nil, fmt.Errorf("invalid date of rates: %w", err)
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,52 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+// Chain asks the providers in order and returns rates of the first one that succeeds.
+// Rates are expressed in units of base, so a provider that does not quote base is skipped.
+type Chain struct {
+	base      string
+	providers []RateProvider
+	logger    *logrus.Logger
+}
+
+func NewChain(base string, providers []RateProvider, logger *logrus.Logger) *Chain {
+	return &Chain{
+		base:      base,
+		providers: providers,
+		logger:    logger,
+	}
+}
+
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+func (c *Chain) Rates(ctx context.Context) (*Rates, error) {
+	failures := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		rates, err := provider.Rates(ctx)
+		if err == nil {
+			rates.Source = provider.Name()
+			rates, err = rates.rebase(c.base)
+		}
+		if err != nil {
+			c.logger.Warnf("Could not get rates from provider %s: %s", provider.Name(), err)
+			failures = append(failures, provider.Name()+": "+err.Error())
+			continue
+		}
+
+		c.logger.Infof("Got rates of %d currencies from provider %s", len(rates.Values), provider.Name())
+		return rates, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", createdErrors.ErrRatesUnavailable, strings.Join(failures, "; "))
+}
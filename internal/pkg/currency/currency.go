@@ -1,97 +1,176 @@
 package currency
 
 import (
+	"context"
 	"encoding/json"
-	"io"
-	"net/http"
+	"fmt"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
-	"avito-tech-task/config"
 	"avito-tech-task/internal/pkg/constants"
 	createdErrors "avito-tech-task/internal/pkg/errors"
 )
 
 type Converter struct {
 	Rates map[string]float64 `json:"rates,omitempty"`
-
-	logger *logrus.Logger
-	config *config.Config
-	mutex  *sync.RWMutex
+	// Date is the day the actual rates were set for
+	Date time.Time `json:"date"`
+
+	// source is the provider of the actual rates and fetched is the time they were received at, it is zero
+	// for the static and cached rates
+	source    string
+	fetched   time.Time
+	provider  RateProvider
+	storage   RateStorage
+	metrics   Metrics
+	cachePath string
+	logger    *logrus.Logger
+	mutex     *sync.RWMutex
 }
 
-func NewConverter(config *config.Config, logger *logrus.Logger) *Converter {
+// NewConverter gets the actual rates from the provider. If it fails, the rates saved to cachePath by
+// the last successful update are used, so the service starts without network. Without them the static
+// rates are used if the provider has them, otherwise only the base currency is supported until the next
+// update. Every fetched snapshot of rates is saved to the storage for conversion at a point in time.
+func NewConverter(provider RateProvider, storage RateStorage, cachePath string, metrics Metrics,
+	logger *logrus.Logger) *Converter {
 	currency := &Converter{
-		config: config,
-		logger: logger,
+		Rates:     map[string]float64{constants.BaseCurrency: 1},
+		Date:      day(time.Now()),
+		provider:  provider,
+		storage:   storage,
+		metrics:   metrics,
+		cachePath: cachePath,
+		logger:    logger,
 	}
 	currency.mutex = new(sync.RWMutex)
 
 	currency.logger.Info("Initializing currency data")
 
-	resp, err := http.Get(currency.config.CurrencyAPIURL)
-	if err != nil {
-		currency.logger.Fatalf("Could not get actual currency data: %s", err)
-		return nil
+	err := currency.update(context.Background())
+	currency.metrics.RatesUpdated(err)
+	if err == nil {
+		return currency
 	}
-	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			currency.logger.Errorf("Could not close response body: %s", err)
+	currency.logger.Errorf("Could not get actual currency data: %s", err)
+
+	if err = currency.loadCache(); err != nil {
+		if currency.source == Static {
+			currency.logger.Warnf("Could not load cached currency data, using %s rates until the next update: %s",
+				Static, err)
+			return currency
 		}
-	}()
-	if resp.StatusCode != http.StatusOK {
-		currency.logger.Fatalf("Bad response status: %d", resp.StatusCode)
-		return nil
+		currency.logger.Errorf("Could not load cached currency data, only %s is supported until the next update: %s",
+			constants.BaseCurrency, err)
+		return currency
 	}
+	currency.logger.Warnf("Using cached currency data from %s", currency.cachePath)
+
+	return currency
+}
 
-	body, err := io.ReadAll(resp.Body)
+// Update gets the actual rates from the provider, the current rates are kept if it fails. The fetch and
+// saving of the rates to storage are cancelled with ctx.
+func (c *Converter) Update(ctx context.Context) error {
+	c.logger.Info("Updating currency data")
+
+	err := c.update(ctx)
+	c.metrics.RatesUpdated(err)
 	if err != nil {
-		currency.logger.Fatalf("Could not read response body: %s", err)
-		return nil
+		c.logger.Errorf("Could not update currency data: %s", err)
+		return err
 	}
 
-	if err = json.Unmarshal(body, &currency); err != nil {
-		currency.logger.Fatalf("Could not umarshal response body into struct: %s", err)
-		return nil
+	return nil
+}
+
+func (c *Converter) update(ctx context.Context) error {
+	rates, err := c.provider.Rates(ctx)
+	if err != nil {
+		return err
+	}
+	if rates, err = rates.rebase(constants.BaseCurrency); err != nil {
+		return err
+	}
+	if rates.Source == Static {
+		return c.useStatic(rates)
+	}
+	if rates.Date.IsZero() {
+		rates.Date = day(time.Now())
 	}
-	currency.Rates["RUB"] = 1
 
-	return currency
+	fetched := time.Now()
+	c.mutex.Lock()
+	c.Rates = rates.Values
+	c.Date = rates.Date
+	c.source = rates.Source
+	c.fetched = fetched
+	c.mutex.Unlock()
+	c.metrics.RatesFetched(fetched)
+
+	if err = c.storage.SaveRates(ctx, rates); err != nil {
+		c.logger.Errorf("Could not save currency data to storage: %s", err)
+	}
+	if err = c.saveCache(rates); err != nil {
+		c.logger.Errorf("Could not save currency data to cache: %s", err)
+	}
+
+	return nil
 }
 
-func (c *Converter) Update() {
-	c.logger.Info("Updating currency data")
+// useStatic uses the rates of the static provider if there are no fetched ones. They are a fallback, not
+// the actual rates: like the cached ones they are never reported as fetched, so they are stale and are retried
+// by the updater, and they are not saved to the storage or the cache as the rates of the day.
+func (c *Converter) useStatic(rates *Rates) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fetched.IsZero() {
+		c.Rates = rates.Values
+		if !rates.Date.IsZero() {
+			c.Date = rates.Date
+		}
+		c.source = Static
+	}
 
-	resp, err := http.Get(c.config.CurrencyAPIURL)
-	if err != nil {
-		c.logger.Errorf("Could not get actual currency data: %s", err)
-		return
+	return fmt.Errorf("%w: only %s rates are available", createdErrors.ErrRatesUnavailable, Static)
+}
+
+func (c *Converter) saveCache(rates *Rates) error {
+	if c.cachePath == "" {
+		return nil
 	}
-	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			c.logger.Errorf("Could not close response body: %s", err)
-		}
-	}()
-	if resp.StatusCode != http.StatusOK {
-		c.logger.Errorf("Bad response status: %d", resp.StatusCode)
-		return
+
+	data, err := json.Marshal(&rateFile{Date: rates.Date.Format(dateLayout), Base: rates.Base, Rates: rates.Values})
+	if err != nil {
+		return err
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return os.WriteFile(c.cachePath, data, 0600)
+}
+
+func (c *Converter) loadCache() error {
+	rates, err := NewStaticProvider(c.cachePath, "", nil).Rates(context.Background())
 	if err != nil {
-		c.logger.Errorf("Could not read response body: %s", err)
-		return
+		return err
+	}
+	if rates, err = rates.rebase(constants.BaseCurrency); err != nil {
+		return err
 	}
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	if err = json.Unmarshal(body, &c); err != nil {
-		c.logger.Errorf("Could not unmarshal response body into struct: %s", err)
-		return
+	c.Rates = rates.Values
+	if !rates.Date.IsZero() {
+		c.Date = rates.Date
 	}
-	c.Rates["RUB"] = 1
+	c.source = Cache
+	c.fetched = time.Time{} // cached rates are stale until the actual ones are fetched
+
+	return nil
 }
 
 func (c *Converter) Get(currency string) (float64, error) {
@@ -108,13 +187,63 @@ func (c *Converter) Get(currency string) (float64, error) {
 	return value, nil
 }
 
-func UpdateCurrency(converter *Converter, cancel <-chan struct{}) {
-	for {
-		select {
-		case <-cancel:
-			return
-		case <-time.After(constants.CurrencyAPIUpdatePeriod):
-			converter.Update()
-		}
+// GetRate returns the actual rate of the currency with the date it was set for.
+func (c *Converter) GetRate(currency string) (*Rate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	value, ok := c.Rates[currency]
+	if !ok {
+		return nil, createdErrors.ErrNotSupportedCurrency
+	}
+
+	return &Rate{Value: value, Date: c.Date}, nil
+}
+
+// Supported returns the sorted codes of currencies with actual rates.
+func (c *Converter) Supported() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	codes := make([]string, 0, len(c.Rates))
+	for code := range c.Rates {
+		codes = append(codes, code)
 	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// Snapshot returns a copy of the actual rates.
+func (c *Converter) Snapshot() *Snapshot {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	rates := make(map[string]float64, len(c.Rates))
+	for code, value := range c.Rates {
+		rates[code] = value
+	}
+
+	return &Snapshot{
+		Base:    constants.BaseCurrency,
+		Rates:   rates,
+		Date:    c.Date,
+		Source:  c.source,
+		Fetched: c.fetched,
+	}
+}
+
+// GetAt returns the rate of the currency on the date of at. If there is no rate on the date, the closest
+// earlier one is used, and the earliest stored one if the currency was not quoted before the date.
+func (c *Converter) GetAt(ctx context.Context, currency string, at time.Time) (*Rate, error) {
+	if currency == constants.BaseCurrency {
+		return &Rate{Value: 1, Date: day(at)}, nil
+	}
+
+	rate, err := c.storage.GetRateAt(ctx, currency, day(at))
+	if err != nil {
+		return nil, err
+	}
+	if rate == nil {
+		return nil, createdErrors.ErrNotSupportedCurrency
+	}
+
+	return rate, nil
 }
@@ -0,0 +1,327 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestNewConverter(t *testing.T) {
+	logger := logrus.New()
+	up := newTestServer(t, http.StatusOK, cbrJSONResponse)
+	down := newTestServer(t, http.StatusInternalServerError, "")
+	date := time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		provider RateProvider
+		cache    string
+		mock     func(mock pgxmock.PgxPoolIface)
+		expected map[string]float64
+	}{
+		{
+			name:     "Actual rates are saved to storage",
+			provider: NewChain("RUB", []RateProvider{NewCBRJSONProvider(up.URL, up.Client())}, logger),
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(querySaveRate)).WithArgs("EUR", date, 0.0115, CBRJSON).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveRate)).WithArgs("USD", date, 0.0131, CBRJSON).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.0131, "EUR": 0.0115},
+		},
+		{
+			name:     "Actual rates are used if storage fails",
+			provider: NewCBRJSONProvider(up.URL, up.Client()),
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin().WillReturnError(errors.New("Error in database"))
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.0131, "EUR": 0.0115},
+		},
+		{
+			name:     "Cached rates when providers are down",
+			provider: NewCBRJSONProvider(down.URL, down.Client()),
+			cache:    `{"base":"RUB","rates":{"USD":0.0125}}`,
+			mock:     func(mock pgxmock.PgxPoolIface) {},
+			expected: map[string]float64{"RUB": 1, "USD": 0.0125},
+		},
+		{
+			name:     "Only base currency without cache",
+			provider: NewCBRJSONProvider(down.URL, down.Client()),
+			mock:     func(mock pgxmock.PgxPoolIface) {},
+			expected: map[string]float64{"RUB": 1},
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			if err != nil {
+				t.Errorf("Could not mock database connection: %s", err)
+			}
+			test.mock(mock)
+
+			cachePath := filepath.Join(t.TempDir(), "rates.json")
+			if test.cache != "" {
+				if err = os.WriteFile(cachePath, []byte(test.cache), 0600); err != nil {
+					t.Fatalf("Could not write cache: %s", err)
+				}
+			}
+
+			converter := NewConverter(test.provider, NewStorage(mock), cachePath, &fakeMetrics{}, logger)
+			assert.Equal(t, test.expected, converter.Rates)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			_, err = converter.Get("GBP")
+			assert.ErrorIs(t, err, createdErrors.ErrNotSupportedCurrency)
+		})
+	}
+}
+
+func TestConverter_UpdateSavesCache(t *testing.T) {
+	server := newTestServer(t, http.StatusOK, cbrJSONResponse)
+	cachePath := filepath.Join(t.TempDir(), "rates.json")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	mock.ExpectBegin().WillReturnError(errors.New("Error in database"))
+
+	NewConverter(NewCBRJSONProvider(server.URL, server.Client()), NewStorage(mock), cachePath, &fakeMetrics{},
+		logrus.New())
+
+	cached, err := NewStaticProvider(cachePath, "", nil).Rates(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]float64{"RUB": 1, "USD": 0.0131, "EUR": 0.0115}, cached.Values)
+		assert.Equal(t, time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC), cached.Date)
+	}
+}
+
+func TestConverter_GetRate(t *testing.T) {
+	server := newTestServer(t, http.StatusOK, cbrJSONResponse)
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	mock.ExpectBegin().WillReturnError(errors.New("Error in database"))
+
+	logger := logrus.New()
+	converter := NewConverter(NewChain("RUB", []RateProvider{NewCBRJSONProvider(server.URL, server.Client())}, logger),
+		NewStorage(mock), "", &fakeMetrics{}, logger)
+
+	rate, err := converter.GetRate("USD")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &Rate{Value: 0.0131, Date: time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC)}, rate)
+	}
+	_, err = converter.GetRate("GBP")
+	assert.ErrorIs(t, err, createdErrors.ErrNotSupportedCurrency)
+	assert.Equal(t, []string{"EUR", "RUB", "USD"}, converter.Supported())
+
+	snapshot := converter.Snapshot()
+	assert.Equal(t, CBRJSON, snapshot.Source)
+	assert.False(t, snapshot.Stale(time.Now(), time.Minute))
+	assert.True(t, snapshot.Stale(time.Now().Add(time.Hour), time.Minute))
+	snapshot.Rates["USD"] = 1
+	assert.Equal(t, 0.0131, converter.Rates["USD"], "snapshot must be a copy")
+}
+
+func TestConverter_Update(t *testing.T) {
+	server := newTestServer(t, http.StatusOK, cbrJSONResponse)
+	down := newTestServer(t, http.StatusInternalServerError, "")
+	cachePath := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(cachePath, []byte(`{"base":"RUB","rates":{"USD":0.0125}}`), 0600); err != nil {
+		t.Fatalf("Could not write cache: %s", err)
+	}
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	mock.ExpectBegin().WillReturnError(errors.New("Error in database"))
+	logger := logrus.New()
+	provider := &switchProvider{provider: NewCBRJSONProvider(down.URL, down.Client())}
+
+	metrics := &fakeMetrics{}
+
+	converter := NewConverter(NewChain("RUB", []RateProvider{provider}, logger), NewStorage(mock), cachePath,
+		metrics, logger)
+	snapshot := converter.Snapshot()
+	assert.Equal(t, Cache, snapshot.Source)
+	assert.True(t, snapshot.Fetched.IsZero())
+	assert.True(t, snapshot.Stale(time.Now(), time.Hour), "cached rates are stale until the actual ones are fetched")
+	assert.True(t, metrics.fetched.IsZero())
+
+	assert.ErrorIs(t, converter.Update(context.Background()), createdErrors.ErrRatesUnavailable)
+	assert.Equal(t, map[string]float64{"RUB": 1, "USD": 0.0125}, converter.Rates, "current rates must be kept")
+
+	provider.provider = NewCBRJSONProvider(server.URL, server.Client())
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, converter.Update(cancelled), "the fetch must be cancelled with the context")
+	assert.Equal(t, map[string]float64{"RUB": 1, "USD": 0.0125}, converter.Rates)
+
+	assert.NoError(t, converter.Update(context.Background()))
+	assert.Equal(t, 0.0131, converter.Rates["USD"])
+	assert.Equal(t, CBRJSON, converter.Snapshot().Source)
+	assert.True(t, metrics.fetched.Equal(converter.Snapshot().Fetched))
+
+	// the update on start, the failed, the cancelled and the successful ones
+	if assert.Len(t, metrics.updates, 4) {
+		assert.Error(t, metrics.updates[0])
+		assert.Error(t, metrics.updates[1])
+		assert.Error(t, metrics.updates[2])
+		assert.NoError(t, metrics.updates[3])
+	}
+}
+
+func TestConverter_UpdateWithStaticRates(t *testing.T) {
+	server := newTestServer(t, http.StatusOK, cbrJSONResponse)
+	down := newTestServer(t, http.StatusInternalServerError, "")
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	logger := logrus.New()
+	live := &switchProvider{provider: NewCBRJSONProvider(down.URL, down.Client())}
+	static := NewStaticProvider("", "RUB", map[string]float64{"USD": 0.0125})
+	metrics := &fakeMetrics{}
+
+	// static rates are not saved to the storage, so the database is not expected to be called
+	converter := NewConverter(NewChain("RUB", []RateProvider{live, static}, logger), NewStorage(mock), "",
+		metrics, logger)
+	assert.Equal(t, map[string]float64{"RUB": 1, "USD": 0.0125}, converter.Rates)
+	snapshot := converter.Snapshot()
+	assert.Equal(t, Static, snapshot.Source)
+	assert.True(t, snapshot.Fetched.IsZero())
+	assert.True(t, snapshot.Stale(time.Now(), time.Hour), "static rates are stale until the actual ones are fetched")
+	assert.True(t, metrics.fetched.IsZero())
+	assert.ErrorIs(t, converter.Update(context.Background()), createdErrors.ErrRatesUnavailable,
+		"the updater must retry the static rates")
+
+	live.provider = NewCBRJSONProvider(server.URL, server.Client())
+	mock.ExpectBegin().WillReturnError(errors.New("Error in database"))
+	assert.NoError(t, converter.Update(context.Background()))
+	assert.Equal(t, CBRJSON, converter.Snapshot().Source)
+
+	live.provider = NewCBRJSONProvider(down.URL, down.Client())
+	assert.ErrorIs(t, converter.Update(context.Background()), createdErrors.ErrRatesUnavailable)
+	assert.Equal(t, 0.0131, converter.Rates["USD"], "static rates must not replace the fetched ones")
+	assert.Equal(t, CBRJSON, converter.Snapshot().Source)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakeMetrics remembers the results of updates and the time of the last received rates.
+type fakeMetrics struct {
+	updates []error
+	fetched time.Time
+}
+
+func (m *fakeMetrics) RatesUpdated(err error) {
+	m.updates = append(m.updates, err)
+}
+
+func (m *fakeMetrics) RatesFetched(fetched time.Time) {
+	m.fetched = fetched
+}
+
+// switchProvider lets a test replace the provider of a converter.
+type switchProvider struct {
+	provider RateProvider
+}
+
+func (p *switchProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *switchProvider) Rates(ctx context.Context) (*Rates, error) {
+	return p.provider.Rates(ctx)
+}
+
+func TestConverter_GetAt(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	converter := &Converter{storage: NewStorage(mock)}
+	dbErr := errors.New("Error in database")
+	at := time.Date(2022, 1, 20, 1, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	date := time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		currency    string
+		mock        func()
+		expected    *Rate
+		expectedErr bool
+		err         error
+	}{
+		{
+			name:     "Rate on the date in UTC",
+			currency: "USD",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetRateAt)).WithArgs("USD", date).
+					WillReturnRows(pgxmock.NewRows([]string{"rate", "date"}).AddRow(0.0131, date))
+				mock.ExpectCommit()
+			},
+			expected: &Rate{Value: 0.0131, Date: date},
+		},
+		{
+			name:     "Base currency",
+			currency: "RUB",
+			mock:     func() {},
+			expected: &Rate{Value: 1, Date: date},
+		},
+		{
+			name:     "Currency was never stored",
+			currency: "XXX",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetRateAt)).WithArgs("XXX", date).WillReturnError(pgx.ErrNoRows)
+				mock.ExpectCommit()
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrNotSupportedCurrency,
+		},
+		{
+			name:     "Error in database",
+			currency: "USD",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetRateAt)).WithArgs("USD", date).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.mock()
+			rate, err := converter.GetAt(context.Background(), test.currency, at)
+			if test.expectedErr {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, rate)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
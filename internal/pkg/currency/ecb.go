@@ -0,0 +1,66 @@
+package currency
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ECBProvider gets euro reference rates of the European Central Bank from eurofxref XML. RUB is not quoted
+// there since March 2022, so the rates can not be rebased to RUB and the provider only works with a EUR base.
+type ECBProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewECBProvider(url string, client *http.Client) *ECBProvider {
+	return &ECBProvider{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *ECBProvider) Name() string {
+	return ECB
+}
+
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func (p *ECBProvider) Rates(ctx context.Context) (*Rates, error) {
+	body, err := fetch(ctx, p.client, p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &ecbEnvelope{}
+	if err = xml.Unmarshal(body, envelope); err != nil {
+		return nil, fmt.Errorf("could not unmarshal rates: %w", err)
+	}
+
+	values := make(map[string]float64, len(envelope.Cube.Cube.Rates))
+	for _, rate := range envelope.Cube.Cube.Rates {
+		values[rate.Currency] = rate.Rate
+	}
+
+	rates, err := newRates("EUR", values)
+	if err != nil {
+		return nil, err
+	}
+	if rates.Date, err = time.Parse(dateLayout, envelope.Cube.Cube.Time); err != nil {
+		return nil, fmt.Errorf("invalid date of rates: %w", err)
+	}
+
+	return rates, nil
+}
@@ -1,7 +1,28 @@
 package currency
 
+import (
+	"context"
+	"time"
+)
+
 //go:generate moq -out ./mock/currency_mock.go -pkg mock . ConverterIface:MockConverterIface
 type ConverterIface interface {
-	Update()
+	Update(context.Context) error
 	Get(string) (float64, error)
+	GetRate(string) (*Rate, error)
+	Supported() []string
+	Snapshot() *Snapshot
+	GetAt(context.Context, string, time.Time) (*Rate, error)
+}
+
+// RateStorage keeps the history of rates.
+type RateStorage interface {
+	SaveRates(context.Context, *Rates) error
+	GetRateAt(context.Context, string, time.Time) (*Rate, error)
+}
+
+// Metrics records the outcome of updates of the rates and the time the actual rates were received at.
+type Metrics interface {
+	RatesUpdated(error)
+	RatesFetched(time.Time)
 }
@@ -5,7 +5,9 @@ package mock
 
 import (
 	"avito-tech-task/internal/pkg/currency"
+	"context"
 	"sync"
+	"time"
 )
 
 // Ensure, that MockConverterIface does implement currency.ConverterIface.
@@ -14,28 +16,52 @@ var _ currency.ConverterIface = &MockConverterIface{}
 
 // MockConverterIface is a mock implementation of currency.ConverterIface.
 //
-// 	func TestSomethingThatUsesConverterIface(t *testing.T) {
+//	func TestSomethingThatUsesConverterIface(t *testing.T) {
 //
-// 		// make and configure a mocked currency.ConverterIface
-// 		mockedConverterIface := &MockConverterIface{
-// 			GetFunc: func(s string) (float64, error) {
-// 				panic("mock out the Get method")
-// 			},
-// 			UpdateFunc: func()  {
-// 				panic("mock out the Update method")
-// 			},
-// 		}
+//		// make and configure a mocked currency.ConverterIface
+//		mockedConverterIface := &MockConverterIface{
+//			GetFunc: func(s string) (float64, error) {
+//				panic("mock out the Get method")
+//			},
+//			GetAtFunc: func(contextMoqParam context.Context, s string, timeMoqParam time.Time) (*currency.Rate, error) {
+//				panic("mock out the GetAt method")
+//			},
+//			GetRateFunc: func(s string) (*currency.Rate, error) {
+//				panic("mock out the GetRate method")
+//			},
+//			SnapshotFunc: func() *currency.Snapshot {
+//				panic("mock out the Snapshot method")
+//			},
+//			SupportedFunc: func() []string {
+//				panic("mock out the Supported method")
+//			},
+//			UpdateFunc: func(contextMoqParam context.Context) error {
+//				panic("mock out the Update method")
+//			},
+//		}
 //
-// 		// use mockedConverterIface in code that requires currency.ConverterIface
-// 		// and then make assertions.
+//		// use mockedConverterIface in code that requires currency.ConverterIface
+//		// and then make assertions.
 //
-// 	}
+//	}
 type MockConverterIface struct {
 	// GetFunc mocks the Get method.
 	GetFunc func(s string) (float64, error)
 
+	// GetAtFunc mocks the GetAt method.
+	GetAtFunc func(contextMoqParam context.Context, s string, timeMoqParam time.Time) (*currency.Rate, error)
+
+	// GetRateFunc mocks the GetRate method.
+	GetRateFunc func(s string) (*currency.Rate, error)
+
+	// SnapshotFunc mocks the Snapshot method.
+	SnapshotFunc func() *currency.Snapshot
+
+	// SupportedFunc mocks the Supported method.
+	SupportedFunc func() []string
+
 	// UpdateFunc mocks the Update method.
-	UpdateFunc func()
+	UpdateFunc func(contextMoqParam context.Context) error
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -44,12 +70,38 @@ type MockConverterIface struct {
 			// S is the s argument value.
 			S string
 		}
+		// GetAt holds details about calls to the GetAt method.
+		GetAt []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// S is the s argument value.
+			S string
+			// TimeMoqParam is the timeMoqParam argument value.
+			TimeMoqParam time.Time
+		}
+		// GetRate holds details about calls to the GetRate method.
+		GetRate []struct {
+			// S is the s argument value.
+			S string
+		}
+		// Snapshot holds details about calls to the Snapshot method.
+		Snapshot []struct {
+		}
+		// Supported holds details about calls to the Supported method.
+		Supported []struct {
+		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
 		}
 	}
-	lockGet    sync.RWMutex
-	lockUpdate sync.RWMutex
+	lockGet       sync.RWMutex
+	lockGetAt     sync.RWMutex
+	lockGetRate   sync.RWMutex
+	lockSnapshot  sync.RWMutex
+	lockSupported sync.RWMutex
+	lockUpdate    sync.RWMutex
 }
 
 // Get calls GetFunc.
@@ -70,7 +122,8 @@ func (mock *MockConverterIface) Get(s string) (float64, error) {
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
-//     len(mockedConverterIface.GetCalls())
+//
+//	len(mockedConverterIface.GetCalls())
 func (mock *MockConverterIface) GetCalls() []struct {
 	S string
 } {
@@ -83,25 +136,157 @@ func (mock *MockConverterIface) GetCalls() []struct {
 	return calls
 }
 
+// GetAt calls GetAtFunc.
+func (mock *MockConverterIface) GetAt(contextMoqParam context.Context, s string, timeMoqParam time.Time) (*currency.Rate, error) {
+	if mock.GetAtFunc == nil {
+		panic("MockConverterIface.GetAtFunc: method is nil but ConverterIface.GetAt was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		S               string
+		TimeMoqParam    time.Time
+	}{
+		ContextMoqParam: contextMoqParam,
+		S:               s,
+		TimeMoqParam:    timeMoqParam,
+	}
+	mock.lockGetAt.Lock()
+	mock.calls.GetAt = append(mock.calls.GetAt, callInfo)
+	mock.lockGetAt.Unlock()
+	return mock.GetAtFunc(contextMoqParam, s, timeMoqParam)
+}
+
+// GetAtCalls gets all the calls that were made to GetAt.
+// Check the length with:
+//
+//	len(mockedConverterIface.GetAtCalls())
+func (mock *MockConverterIface) GetAtCalls() []struct {
+	ContextMoqParam context.Context
+	S               string
+	TimeMoqParam    time.Time
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		S               string
+		TimeMoqParam    time.Time
+	}
+	mock.lockGetAt.RLock()
+	calls = mock.calls.GetAt
+	mock.lockGetAt.RUnlock()
+	return calls
+}
+
+// GetRate calls GetRateFunc.
+func (mock *MockConverterIface) GetRate(s string) (*currency.Rate, error) {
+	if mock.GetRateFunc == nil {
+		panic("MockConverterIface.GetRateFunc: method is nil but ConverterIface.GetRate was just called")
+	}
+	callInfo := struct {
+		S string
+	}{
+		S: s,
+	}
+	mock.lockGetRate.Lock()
+	mock.calls.GetRate = append(mock.calls.GetRate, callInfo)
+	mock.lockGetRate.Unlock()
+	return mock.GetRateFunc(s)
+}
+
+// GetRateCalls gets all the calls that were made to GetRate.
+// Check the length with:
+//
+//	len(mockedConverterIface.GetRateCalls())
+func (mock *MockConverterIface) GetRateCalls() []struct {
+	S string
+} {
+	var calls []struct {
+		S string
+	}
+	mock.lockGetRate.RLock()
+	calls = mock.calls.GetRate
+	mock.lockGetRate.RUnlock()
+	return calls
+}
+
+// Snapshot calls SnapshotFunc.
+func (mock *MockConverterIface) Snapshot() *currency.Snapshot {
+	if mock.SnapshotFunc == nil {
+		panic("MockConverterIface.SnapshotFunc: method is nil but ConverterIface.Snapshot was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockSnapshot.Lock()
+	mock.calls.Snapshot = append(mock.calls.Snapshot, callInfo)
+	mock.lockSnapshot.Unlock()
+	return mock.SnapshotFunc()
+}
+
+// SnapshotCalls gets all the calls that were made to Snapshot.
+// Check the length with:
+//
+//	len(mockedConverterIface.SnapshotCalls())
+func (mock *MockConverterIface) SnapshotCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockSnapshot.RLock()
+	calls = mock.calls.Snapshot
+	mock.lockSnapshot.RUnlock()
+	return calls
+}
+
+// Supported calls SupportedFunc.
+func (mock *MockConverterIface) Supported() []string {
+	if mock.SupportedFunc == nil {
+		panic("MockConverterIface.SupportedFunc: method is nil but ConverterIface.Supported was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockSupported.Lock()
+	mock.calls.Supported = append(mock.calls.Supported, callInfo)
+	mock.lockSupported.Unlock()
+	return mock.SupportedFunc()
+}
+
+// SupportedCalls gets all the calls that were made to Supported.
+// Check the length with:
+//
+//	len(mockedConverterIface.SupportedCalls())
+func (mock *MockConverterIface) SupportedCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockSupported.RLock()
+	calls = mock.calls.Supported
+	mock.lockSupported.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
-func (mock *MockConverterIface) Update() {
+func (mock *MockConverterIface) Update(contextMoqParam context.Context) error {
 	if mock.UpdateFunc == nil {
 		panic("MockConverterIface.UpdateFunc: method is nil but ConverterIface.Update was just called")
 	}
 	callInfo := struct {
-	}{}
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
 	mock.lockUpdate.Lock()
 	mock.calls.Update = append(mock.calls.Update, callInfo)
 	mock.lockUpdate.Unlock()
-	mock.UpdateFunc()
+	return mock.UpdateFunc(contextMoqParam)
 }
 
 // UpdateCalls gets all the calls that were made to Update.
 // Check the length with:
-//     len(mockedConverterIface.UpdateCalls())
+//
+//	len(mockedConverterIface.UpdateCalls())
 func (mock *MockConverterIface) UpdateCalls() []struct {
+	ContextMoqParam context.Context
 } {
 	var calls []struct {
+		ContextMoqParam context.Context
 	}
 	mock.lockUpdate.RLock()
 	calls = mock.calls.Update
@@ -0,0 +1,188 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+const (
+	CBRJSON = "cbr_json"
+	CBRXML  = "cbr_xml"
+	ECB     = "ecb"
+	Static  = "static"
+	// Cache is the source of rates loaded from the cache file
+	Cache = "cache"
+
+	dateLayout = "2006-01-02"
+)
+
+// RateProvider is a source of exchange rates.
+type RateProvider interface {
+	Name() string
+	Rates(ctx context.Context) (*Rates, error)
+}
+
+// Rates are amounts of currencies equal to one unit of Base.
+type Rates struct {
+	Base   string
+	Values map[string]float64
+	// Date is the day the rates were set for, it is zero if the provider does not know it
+	Date time.Time
+	// Source is the name of the provider
+	Source string
+}
+
+// Snapshot is the rates used by the converter at some moment.
+type Snapshot struct {
+	Base  string
+	Rates map[string]float64
+	Date  time.Time
+	// Source is the name of the provider or cache if the rates were loaded from the cache on startup
+	Source string
+	// Fetched is the time the rates were received from the provider, it is zero if they never were
+	Fetched time.Time
+}
+
+// Age returns the time passed since the rates were fetched, rates that were never fetched are infinitely old.
+func (s *Snapshot) Age(now time.Time) time.Duration {
+	if s.Fetched.IsZero() {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return now.Sub(s.Fetched)
+}
+
+// Stale reports whether the rates are older than maxAge, any rates are fresh if maxAge is not positive.
+func (s *Snapshot) Stale(now time.Time, maxAge time.Duration) bool {
+	return maxAge > 0 && s.Age(now) > maxAge
+}
+
+// Rate is the amount of a currency equal to one unit of the base currency on Date.
+type Rate struct {
+	Value float64
+	Date  time.Time
+}
+
+// day returns the date of t in UTC, rates are stored and looked up by such dates.
+func day(t time.Time) time.Time {
+	year, month, date := t.UTC().Date()
+	return time.Date(year, month, date, 0, 0, 0, 0, time.UTC)
+}
+
+// rebase expresses the rates in units of base, base must be quoted by the rates.
+func (r *Rates) rebase(base string) (*Rates, error) {
+	unit := 1.0
+	if r.Base != base {
+		var ok bool
+		if unit, ok = r.Values[base]; !ok || unit <= 0 {
+			return nil, fmt.Errorf("%w: %s is not quoted against %s", createdErrors.ErrNotSupportedCurrency, base, r.Base)
+		}
+	}
+
+	values := make(map[string]float64, len(r.Values)+1)
+	for code, value := range r.Values {
+		values[code] = value / unit
+	}
+	values[r.Base] = 1 / unit
+	values[base] = 1
+
+	return &Rates{Base: base, Values: values, Date: r.Date, Source: r.Source}, nil
+}
+
+// rateFile is the JSON with rates used by cbr-xml-daily.ru, static provider files and the rates cache.
+type rateFile struct {
+	Date  string             `json:"date,omitempty"`
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func parseRateFile(data []byte) (*Rates, error) {
+	file := &rateFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("could not unmarshal rates: %w", err)
+	}
+	if file.Base == "" {
+		file.Base = constants.BaseCurrency
+	}
+
+	rates, err := newRates(file.Base, file.Rates)
+	if err != nil || file.Date == "" {
+		return rates, err
+	}
+	if rates.Date, err = time.Parse(dateLayout, file.Date); err != nil {
+		return nil, fmt.Errorf("invalid date of rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// newRates checks that there are rates and all of them are positive.
+func newRates(base string, values map[string]float64) (*Rates, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("there are no rates")
+	}
+	for code, value := range values {
+		if value <= 0 {
+			return nil, fmt.Errorf("rate of %s must be positive, got %v", code, value)
+		}
+	}
+
+	return &Rates{Base: base, Values: values}, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad response status: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// NewProviders creates the providers in the configured order.
+func NewProviders(settings []config.RateProviderConfig) ([]RateProvider, error) {
+	providers := make([]RateProvider, 0, len(settings))
+	for _, setting := range settings {
+		client := &http.Client{Timeout: constants.RateProviderTimeout}
+		if setting.Timeout.Duration > 0 {
+			client.Timeout = setting.Timeout.Duration
+		}
+
+		switch setting.Type {
+		case CBRJSON:
+			providers = append(providers, NewCBRJSONProvider(setting.URL, client))
+		case CBRXML:
+			providers = append(providers, NewCBRXMLProvider(setting.URL, client))
+		case ECB:
+			// the ECB does not quote the ruble, so its rates could never be rebased to the base currency
+			return nil, fmt.Errorf("%w: %s does not quote %s", createdErrors.ErrUnsupportedRateProvider, ECB,
+				constants.BaseCurrency)
+		case Static:
+			providers = append(providers, NewStaticProvider(setting.Path, setting.Base, setting.Rates))
+		default:
+			return nil, fmt.Errorf("%w, got %q", createdErrors.ErrUnknownRateProvider, setting.Type)
+		}
+	}
+
+	return providers, nil
+}
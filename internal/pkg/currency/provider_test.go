@@ -0,0 +1,283 @@
+package currency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+
+	"avito-tech-task/config"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+const (
+	cbrJSONResponse = `{"disclaimer":"https://www.cbr-xml-daily.ru/#terms","date":"2022-01-19","timestamp":1642539600,` +
+		`"base":"RUB","rates":{"USD":0.0131,"EUR":0.0115}}`
+	cbrXMLResponse = `<?xml version="1.0" encoding="windows-1251"?>` +
+		`<ValCurs Date="19.01.2022" name="Foreign Currency Market">` +
+		`<Valute ID="R01235"><NumCode>840</NumCode><CharCode>USD</CharCode><Nominal>1</Nominal>` +
+		`<Name>Доллар США</Name><Value>76,3508</Value></Valute>` +
+		`<Valute ID="R01820"><NumCode>392</NumCode><CharCode>JPY</CharCode><Nominal>100</Nominal>` +
+		`<Name>Японских иен</Name><Value>66,4423</Value></Valute>` +
+		`</ValCurs>`
+	// ecbResponse is eurofxref-daily.xml with a part of the rates, RUB is not quoted there
+	ecbResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time='2022-01-19'>
+			<Cube currency='USD' rate='1.1345'/>
+			<Cube currency='JPY' rate='130.33'/>
+			<Cube currency='GBP' rate='0.83290'/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+)
+
+func newTestServer(t *testing.T, status int, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf("Could not write response: %s", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func windows1251(t *testing.T, s string) string {
+	encoded, err := charmap.Windows1251.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("Could not encode response: %s", err)
+	}
+
+	return encoded
+}
+
+func TestProviders_Rates(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    func(t *testing.T) RateProvider
+		expected    *Rates
+		expectedErr bool
+	}{
+		{
+			name: "CBR JSON",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusOK, cbrJSONResponse)
+				return NewCBRJSONProvider(server.URL, server.Client())
+			},
+			expected: &Rates{Base: "RUB", Values: map[string]float64{"USD": 0.0131, "EUR": 0.0115}, Date: time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "CBR XML in windows-1251",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusOK, windows1251(t, cbrXMLResponse))
+				return NewCBRXMLProvider(server.URL, server.Client())
+			},
+			expected: &Rates{
+				Base:   "RUB",
+				Values: map[string]float64{"USD": 1 / 76.3508, "JPY": 100 / 66.4423},
+				Date:   time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "ECB",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusOK, ecbResponse)
+				return NewECBProvider(server.URL, server.Client())
+			},
+			expected: &Rates{
+				Base:   "EUR",
+				Values: map[string]float64{"USD": 1.1345, "JPY": 130.33, "GBP": 0.8329},
+				Date:   time.Date(2022, 1, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "Static rates from config",
+			provider: func(t *testing.T) RateProvider {
+				return NewStaticProvider("", "", map[string]float64{"USD": 0.013})
+			},
+			expected: &Rates{Base: "RUB", Values: map[string]float64{"USD": 0.013}},
+		},
+		{
+			name: "Static rates from file",
+			provider: func(t *testing.T) RateProvider {
+				path := filepath.Join(t.TempDir(), "rates.json")
+				if err := os.WriteFile(path, []byte(`{"base":"EUR","rates":{"USD":1.13}}`), 0600); err != nil {
+					t.Fatalf("Could not write rates file: %s", err)
+				}
+				return NewStaticProvider(path, "", nil)
+			},
+			expected: &Rates{Base: "EUR", Values: map[string]float64{"USD": 1.13}},
+		},
+		{
+			name: "Bad response status",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusServiceUnavailable, "")
+				return NewCBRJSONProvider(server.URL, server.Client())
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Malformed response",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusOK, "<html></html>")
+				return NewCBRJSONProvider(server.URL, server.Client())
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Response without rates",
+			provider: func(t *testing.T) RateProvider {
+				server := newTestServer(t, http.StatusOK, `<gesmes:Envelope></gesmes:Envelope>`)
+				return NewECBProvider(server.URL, server.Client())
+			},
+			expectedErr: true,
+		},
+		{
+			name: "Missing file",
+			provider: func(t *testing.T) RateProvider {
+				return NewStaticProvider(filepath.Join(t.TempDir(), "rates.json"), "", nil)
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			rates, err := test.provider(t).Rates(context.Background())
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected.Base, rates.Base)
+				assert.Equal(t, test.expected.Date, rates.Date)
+				assert.InDeltaMapValues(t, test.expected.Values, rates.Values, 1e-9)
+			}
+		})
+	}
+}
+
+func TestChain_Rates(t *testing.T) {
+	logger := logrus.New()
+	down := newTestServer(t, http.StatusInternalServerError, "")
+	ecb := newTestServer(t, http.StatusOK, ecbResponse)
+
+	tests := []struct {
+		name        string
+		base        string
+		providers   []RateProvider
+		expected    map[string]float64
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "First provider is used",
+			providers: []RateProvider{
+				NewStaticProvider("", "", map[string]float64{"USD": 0.013}),
+				NewStaticProvider("", "", map[string]float64{"USD": 0.014}),
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.013},
+		},
+		{
+			name: "Failed providers are skipped",
+			providers: []RateProvider{
+				NewCBRJSONProvider(down.URL, down.Client()),
+				NewStaticProvider("", "", map[string]float64{"USD": 0.014}),
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.014},
+		},
+		{
+			name: "Rates are rebased",
+			providers: []RateProvider{
+				NewStaticProvider("", "EUR", map[string]float64{"USD": 1.1345, "RUB": 86.9}),
+			},
+			expected: map[string]float64{"RUB": 1, "EUR": 1 / 86.9, "USD": 1.1345 / 86.9},
+		},
+		{
+			name: "ECB is skipped with RUB base",
+			providers: []RateProvider{
+				NewECBProvider(ecb.URL, ecb.Client()),
+				NewStaticProvider("", "", map[string]float64{"USD": 0.014}),
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.014},
+		},
+		{
+			name: "ECB with EUR base",
+			base: "EUR",
+			providers: []RateProvider{
+				NewECBProvider(ecb.URL, ecb.Client()),
+			},
+			expected: map[string]float64{"EUR": 1, "USD": 1.1345, "JPY": 130.33, "GBP": 0.8329},
+		},
+		{
+			name: "Provider without base currency is skipped",
+			providers: []RateProvider{
+				NewStaticProvider("", "EUR", map[string]float64{"USD": 1.13}),
+				NewStaticProvider("", "", map[string]float64{"USD": 0.014}),
+			},
+			expected: map[string]float64{"RUB": 1, "USD": 0.014},
+		},
+		{
+			name: "All providers failed",
+			providers: []RateProvider{
+				NewCBRJSONProvider(down.URL, down.Client()),
+				NewStaticProvider("", "", nil),
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrRatesUnavailable,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			base := test.base
+			if base == "" {
+				base = "RUB"
+			}
+			rates, err := NewChain(base, test.providers, logger).Rates(context.Background())
+			if test.expectedErr {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, base, rates.Base)
+				assert.InDeltaMapValues(t, test.expected, rates.Values, 1e-9)
+			}
+		})
+	}
+}
+
+func TestNewProviders(t *testing.T) {
+	providers, err := NewProviders([]config.RateProviderConfig{
+		{Type: CBRJSON}, {Type: CBRXML}, {Type: Static},
+	})
+	if assert.NoError(t, err) {
+		names := make([]string, 0, len(providers))
+		for _, provider := range providers {
+			names = append(names, provider.Name())
+		}
+		assert.Equal(t, []string{CBRJSON, CBRXML, Static}, names)
+	}
+
+	_, err = NewProviders([]config.RateProviderConfig{{Type: "unknown"}})
+	assert.ErrorIs(t, err, createdErrors.ErrUnknownRateProvider)
+
+	_, err = NewProviders([]config.RateProviderConfig{{Type: CBRJSON}, {Type: ECB}})
+	assert.ErrorIs(t, err, createdErrors.ErrUnsupportedRateProvider)
+}
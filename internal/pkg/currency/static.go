@@ -0,0 +1,48 @@
+package currency
+
+import (
+	"context"
+	"os"
+
+	"avito-tech-task/internal/pkg/constants"
+)
+
+// StaticProvider returns rates from a JSON file or the config, it works without network.
+type StaticProvider struct {
+	path  string
+	base  string
+	rates map[string]float64
+}
+
+func NewStaticProvider(path string, base string, rates map[string]float64) *StaticProvider {
+	if base == "" {
+		base = constants.BaseCurrency
+	}
+
+	return &StaticProvider{
+		path:  path,
+		base:  base,
+		rates: rates,
+	}
+}
+
+func (p *StaticProvider) Name() string {
+	return Static
+}
+
+func (p *StaticProvider) Rates(ctx context.Context) (*Rates, error) {
+	if p.path == "" {
+		values := make(map[string]float64, len(p.rates))
+		for code, value := range p.rates {
+			values[code] = value
+		}
+		return newRates(p.base, values)
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRateFile(data)
+}
@@ -0,0 +1,88 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"avito-tech-task/internal/pkg/utils"
+)
+
+type Storage struct {
+	db utils.PgxIface
+}
+
+func NewStorage(conn utils.PgxIface) *Storage {
+	return &Storage{conn}
+}
+
+const (
+	querySaveRate = `
+		INSERT INTO currency_rates (currency, date, rate, source) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (currency, date) DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, created = now()`
+	// queryGetRateAt selects the closest rate at or before the date, or the earliest one if there is no such rate
+	queryGetRateAt = `
+		SELECT rate, date FROM currency_rates WHERE currency = $1
+		ORDER BY date <= $2 DESC, CASE WHEN date <= $2 THEN date END DESC, date
+		LIMIT 1`
+)
+
+// SaveRates stores the rates of every currency except the base one on the date of the rates.
+func (s *Storage) SaveRates(ctx context.Context, rates *Rates) (err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	codes := make([]string, 0, len(rates.Values))
+	for code := range rates.Values {
+		if code != rates.Base {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes) // rows are always locked in the same order
+
+	for _, code := range codes {
+		if _, err = transaction.Exec(ctx, querySaveRate, code, rates.Date, rates.Values[code], rates.Source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetRateAt returns the rate of the currency on the date or nil if the currency was never stored.
+func (s *Storage) GetRateAt(ctx context.Context, currency string, date time.Time) (_ *Rate, err error) {
+	transaction, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = transaction.Rollback(context.Background())
+		} else {
+			err = transaction.Commit(ctx)
+		}
+	}()
+
+	rate := &Rate{}
+	if err = transaction.QueryRow(ctx, queryGetRateAt, currency, date).Scan(&rate.Value, &rate.Date); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		err = nil
+		return nil, nil
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,177 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+	_ "time/tzdata" // the refresh time zone is loaded in containers without zoneinfo
+
+	"github.com/sirupsen/logrus"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/pkg/constants"
+)
+
+// Clock is the time source of the updater, tests replace it with a fake one.
+type Clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// RealClock returns the system clock.
+func RealClock() Clock {
+	return realClock{}
+}
+
+// Schedule is the wall-clock time of the day rates are refreshed at.
+type Schedule struct {
+	hour     int
+	minute   int
+	location *time.Location
+}
+
+// NewSchedule parses the time of the day in the HH:MM format and the IANA time zone of it.
+func NewSchedule(at string, timezone string) (Schedule, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid refresh time zone: %w", err)
+	}
+	parsed, err := time.Parse("15:04", at)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid refresh time, it must look like 11:30: %w", err)
+	}
+
+	return Schedule{hour: parsed.Hour(), minute: parsed.Minute(), location: location}, nil
+}
+
+// Next returns the first refresh time after t.
+func (s Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.hour, s.minute, 0, 0, s.location)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, s.hour, s.minute, 0, 0, s.location)
+	}
+
+	return next
+}
+
+// Updater refreshes the rates of the converter every day at the scheduled time. Failed refreshes are
+// retried with exponential backoff and jitter until the next scheduled refresh.
+type Updater struct {
+	converter  ConverterIface
+	schedule   Schedule
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	clock      Clock
+	// jitter returns a random duration from 0 to max
+	jitter func(max time.Duration) time.Duration
+	logger *logrus.Logger
+}
+
+func NewUpdater(converter ConverterIface, config config.CurrencyConfig, clock Clock,
+	logger *logrus.Logger) (*Updater, error) {
+	at, timezone := config.RefreshAt, config.RefreshTimezone
+	if at == "" {
+		at = constants.DefaultRatesRefreshAt
+	}
+	if timezone == "" {
+		timezone = constants.DefaultRatesRefreshTimezone
+	}
+	schedule, err := NewSchedule(at, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	minBackoff, maxBackoff := config.RetryMinBackoff.Duration, config.RetryMaxBackoff.Duration
+	if minBackoff <= 0 {
+		minBackoff = constants.DefaultRatesRetryMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = constants.DefaultRatesRetryMaxBackoff
+	}
+	if maxBackoff < minBackoff {
+		return nil, fmt.Errorf("retry max backoff %s is less than min backoff %s", maxBackoff, minBackoff)
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec // jitter does not need a secure source
+
+	return &Updater{
+		converter:  converter,
+		schedule:   schedule,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		clock:      clock,
+		jitter: func(max time.Duration) time.Duration {
+			return time.Duration(random.Int63n(int64(max) + 1))
+		},
+		logger: logger,
+	}, nil
+}
+
+// Run refreshes the rates until ctx is cancelled. If the rates were not fetched on startup, e.g. the cached
+// or static ones are used, they are retried right away instead of waiting for the scheduled time.
+func (u *Updater) Run(ctx context.Context) {
+	if snapshot := u.converter.Snapshot(); snapshot.Fetched.IsZero() {
+		u.update(ctx)
+	}
+
+	for {
+		next := u.schedule.Next(u.clock.Now())
+		u.logger.Infof("Next refresh of currency data is scheduled at %s", next.Format(time.RFC3339))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.clock.After(next.Sub(u.clock.Now())):
+			u.update(ctx)
+		}
+	}
+}
+
+// update refreshes the rates, retrying failures until the attempt would overlap the next scheduled refresh.
+func (u *Updater) update(ctx context.Context) {
+	deadline := u.schedule.Next(u.clock.Now())
+	for attempt := 1; ; attempt++ {
+		if err := u.converter.Update(ctx); err == nil {
+			return
+		}
+
+		delay := u.backoff(attempt)
+		if !u.clock.Now().Add(delay).Before(deadline) {
+			u.logger.Warnf("Giving up refreshing currency data after %d attempts until the scheduled refresh", attempt)
+			return
+		}
+		u.logger.Warnf("Refreshing currency data failed %d times, retrying in %s", attempt, delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.clock.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay before the retry after the attempt: the half of an exponentially growing
+// interval is fixed and the other half is random, so instances do not retry at the same time.
+func (u *Updater) backoff(attempt int) time.Duration {
+	interval := u.minBackoff
+	for retry := 1; retry < attempt && interval < u.maxBackoff; retry++ {
+		interval *= 2
+	}
+	if interval > u.maxBackoff {
+		interval = u.maxBackoff
+	}
+
+	return interval/2 + u.jitter(interval/2)
+}
@@ -0,0 +1,264 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/config"
+)
+
+// fakeClock fires timers only when the test advances it and reports every started timer to waits.
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	waits  chan time.Duration
+}
+
+type fakeTimer struct {
+	at      time.Time
+	channel chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waits: make(chan time.Duration, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	channel := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), channel: channel})
+	c.mutex.Unlock()
+
+	c.waits <- d
+	return channel
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.channel <- c.now
+	}
+	c.timers = pending
+}
+
+// expectWait waits until the updater starts a timer and checks its duration.
+func (c *fakeClock) expectWait(t *testing.T, expected time.Duration) {
+	t.Helper()
+	select {
+	case got := <-c.waits:
+		assert.Equal(t, expected, got)
+	case <-time.After(time.Second):
+		t.Fatalf("Updater did not wait for %s", expected)
+	}
+}
+
+// fakeConverter counts updates, the mock of ConverterIface can not be used inside the package.
+type fakeConverter struct {
+	Converter
+	mutex    sync.Mutex
+	snapshot *Snapshot
+	update   func() error
+	updates  int
+}
+
+func (c *fakeConverter) Snapshot() *Snapshot {
+	return c.snapshot
+}
+
+func (c *fakeConverter) Update(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.updates++
+	return c.update()
+}
+
+func (c *fakeConverter) Updates() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.updates
+}
+
+func newTestUpdater(t *testing.T, converter ConverterIface, clock Clock) *Updater {
+	updater, err := NewUpdater(converter, config.CurrencyConfig{
+		RefreshAt:       "11:45",
+		RefreshTimezone: "Europe/Moscow",
+		RetryMinBackoff: config.Duration{Duration: time.Minute},
+		RetryMaxBackoff: config.Duration{Duration: 4 * time.Minute},
+	}, clock, logrus.New())
+	if err != nil {
+		t.Fatalf("Could not create updater: %s", err)
+	}
+	updater.jitter = func(max time.Duration) time.Duration {
+		return max
+	}
+
+	return updater
+}
+
+func TestUpdater_RetriesFailedRefresh(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Could not load location: %s", err)
+	}
+	clock := newFakeClock(time.Date(2022, 1, 19, 10, 0, 0, 0, moscow))
+	results := []error{errors.New("timeout"), errors.New("timeout"), nil}
+	converter := &fakeConverter{
+		snapshot: &Snapshot{Source: CBRJSON, Fetched: time.Date(2022, 1, 18, 11, 45, 0, 0, moscow)},
+		update: func() error {
+			err := results[0]
+			results = results[1:]
+			return err
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		newTestUpdater(t, converter, clock).Run(ctx)
+		close(done)
+	}()
+
+	clock.expectWait(t, time.Hour+45*time.Minute)
+	clock.Advance(time.Hour + 45*time.Minute)
+	clock.expectWait(t, time.Minute)
+	clock.Advance(time.Minute)
+	clock.expectWait(t, 2*time.Minute)
+	clock.Advance(2 * time.Minute)
+	clock.expectWait(t, 23*time.Hour+57*time.Minute) // the next day after the successful retry
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Updater was not stopped by the context")
+	}
+	assert.Equal(t, 3, converter.Updates())
+}
+
+func TestUpdater_RetriesStartupUntilScheduledRefresh(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Could not load location: %s", err)
+	}
+	clock := newFakeClock(time.Date(2022, 1, 19, 11, 40, 0, 0, moscow))
+	converter := &fakeConverter{
+		snapshot: &Snapshot{Source: Cache},
+		update: func() error {
+			return errors.New("timeout")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newTestUpdater(t, converter, clock).Run(ctx)
+
+	clock.expectWait(t, time.Minute)
+	clock.Advance(time.Minute)
+	clock.expectWait(t, 2*time.Minute)
+	clock.Advance(2 * time.Minute)
+	// the next retry at 11:47 would overlap the scheduled refresh, so the updater waits for it
+	clock.expectWait(t, 2*time.Minute)
+	assert.Equal(t, 3, converter.Updates())
+}
+
+func TestUpdater_Backoff(t *testing.T) {
+	updater := newTestUpdater(t, &fakeConverter{}, newFakeClock(time.Now()))
+	delays := make([]time.Duration, 0, 5)
+	for attempt := 1; attempt <= 5; attempt++ {
+		delays = append(delays, updater.backoff(attempt))
+	}
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	assert.Equal(t, expected, delays)
+
+	updater.jitter = func(max time.Duration) time.Duration {
+		return 0
+	}
+	assert.Equal(t, 2*time.Minute, updater.backoff(3), "the half of the interval is not random")
+}
+
+func TestSchedule_Next(t *testing.T) {
+	schedule, err := NewSchedule("11:45", "Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Could not create schedule: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Later the same day",
+			now:      time.Date(2022, 1, 19, 6, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 1, 19, 8, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "Next day after the refresh time",
+			now:      time.Date(2022, 1, 19, 9, 0, 0, 0, time.UTC),
+			expected: time.Date(2022, 1, 20, 8, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "Next day at the refresh time",
+			now:      time.Date(2022, 1, 19, 8, 45, 0, 0, time.UTC),
+			expected: time.Date(2022, 1, 20, 8, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day in the time zone of the schedule",
+			now:      time.Date(2022, 1, 19, 22, 0, 0, 0, time.UTC), // 01:00 of January 20 in Moscow
+			expected: time.Date(2022, 1, 20, 8, 45, 0, 0, time.UTC),
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			assert.True(t, test.expected.Equal(schedule.Next(test.now)), "got %s", schedule.Next(test.now))
+		})
+	}
+}
+
+func TestNewUpdater(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.CurrencyConfig
+	}{
+		{name: "Invalid refresh time", config: config.CurrencyConfig{RefreshAt: "25:00"}},
+		{name: "Invalid time zone", config: config.CurrencyConfig{RefreshTimezone: "Mars/Olympus"}},
+		{
+			name: "Max backoff is less than min backoff",
+			config: config.CurrencyConfig{
+				RetryMinBackoff: config.Duration{Duration: time.Hour},
+				RetryMaxBackoff: config.Duration{Duration: time.Minute},
+			},
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewUpdater(&fakeConverter{}, test.config, RealClock(), logrus.New())
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewUpdater(&fakeConverter{}, config.CurrencyConfig{}, RealClock(), logrus.New())
+	assert.NoError(t, err, "defaults are used for empty config")
+}
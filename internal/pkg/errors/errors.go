@@ -1,17 +1,92 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
+// Errors created with newError are shown to clients, the others are internal and only logged.
 var (
-	ErrNegativeUserID            = errors.New("user id must be positive integer")
-	ErrNotEnoughMoney            = errors.New("not enough money on balance")
-	ErrNotSupportedOperationType = errors.New("not supported operation type")
-	ErrAmountFiledIsRequired     = errors.New("amount field is required and must be greater than zero")
-	ErrUserDoesNotExist          = errors.New("user does not exist")
-	ErrSenderDoesNotExist        = errors.New("sender does not exist")
-	ErrReceiverDoesNotExist      = errors.New("receiver does not exist")
-	ErrSenderIDisRequired        = errors.New("sender_id is required")
-	ErrReceiverIDisRequired      = errors.New("receiver_id is required")
-	ErrNotSupportedCurrency      = errors.New("currency is not supported")
-	ErrNegativeLimit             = errors.New("limit value must be positive integer")
+	ErrNegativeUserID            = newError("negative_user_id", http.StatusUnprocessableEntity, "user id must be positive integer")
+	ErrNotEnoughMoney            = newError("not_enough_money", http.StatusUnprocessableEntity, "not enough money on balance")
+	ErrNotSupportedOperationType = newError("not_supported_operation_type", http.StatusUnprocessableEntity, "not supported operation type")
+	ErrAmountFiledIsRequired     = newError("amount_is_required", http.StatusUnprocessableEntity, "amount field is required and must be greater than zero")
+	ErrUserDoesNotExist          = newError("user_not_found", http.StatusNotFound, "user does not exist")
+	ErrSenderDoesNotExist        = newError("sender_not_found", http.StatusNotFound, "sender does not exist")
+	ErrReceiverDoesNotExist      = newError("receiver_not_found", http.StatusNotFound, "receiver does not exist")
+	ErrSenderIDisRequired        = newError("sender_id_is_required", http.StatusUnprocessableEntity, "sender_id is required")
+	ErrReceiverIDisRequired      = newError("receiver_id_is_required", http.StatusUnprocessableEntity, "receiver_id is required")
+	ErrTransferToSelf            = newError("transfer_to_self", http.StatusUnprocessableEntity, "sender and receiver must be different users")
+	ErrNotSupportedCurrency      = newError("not_supported_currency", http.StatusUnprocessableEntity, "currency is not supported")
+	ErrNegativeLimit             = newError("negative_limit", http.StatusUnprocessableEntity, "limit value must be positive integer")
+	ErrOrderIDisRequired         = newError("order_id_is_required", http.StatusUnprocessableEntity, "order_id is required and must be positive integer")
+	ErrServiceIDisRequired       = newError("service_id_is_required", http.StatusUnprocessableEntity, "service_id is required and must be positive integer")
+	ErrReservationAlreadyExists  = newError("reservation_already_exists", http.StatusConflict, "reservation for this order and service already exists")
+	ErrReservationDoesNotExist   = newError("reservation_not_found", http.StatusNotFound, "active reservation for this order and service does not exist")
+	ErrInvalidIdempotencyKey     = newError("invalid_idempotency_key", http.StatusBadRequest, "idempotency key must be from 1 to 255 characters long")
+	ErrIdempotencyKeyReused      = newError("idempotency_key_reused", http.StatusConflict, "idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress  = newError("idempotency_key_in_progress", http.StatusConflict, "request with this idempotency key is still being processed")
+	ErrIdempotencyKeyTakenOver   = newError("idempotency_key_taken_over", http.StatusConflict, "request with this idempotency key was taken over by a retry")
+	ErrInvalidAmount             = newError("invalid_amount", http.StatusBadRequest, "amount must be a decimal number with at most two fractional digits")
+	ErrUnbalancedEntry           = errors.New("postings of the ledger entry must have at least two accounts and sum to zero")
+	ErrInvalidMigrationFile      = errors.New("migration file name must look like 0001_name.up.sql or 0001_name.down.sql")
+	ErrUnknownMigrationVersion   = errors.New("migration with this version does not exist")
+	ErrInvalidPurpose            = newError("invalid_purpose", http.StatusUnprocessableEntity, "comment must be at most 1024 characters, reason and promo_code at most 64, order_id and service_id must not be negative")
+	ErrInvalidCursor             = newError("invalid_cursor", http.StatusUnprocessableEntity, "cursor is invalid or was created for another sorting")
+	ErrInvalidSortOrder          = newError("invalid_sort_order", http.StatusUnprocessableEntity, "order must be asc or desc")
+	ErrInvalidSort               = newError("invalid_sort", http.StatusUnprocessableEntity, "sort must be a list of amount and created keys with optional :asc or :desc, e.g. amount:asc,created:desc")
+	ErrInvalidAmountFilter       = newError("invalid_amount_filter", http.StatusUnprocessableEntity, "min_amount and max_amount must not be negative")
+	ErrUnknownRateProvider       = errors.New("rate provider type must be cbr_json, cbr_xml or static")
+	ErrUnsupportedRateProvider   = errors.New("rate provider does not quote the base currency")
+	ErrRatesUnavailable          = newError("rates_unavailable", http.StatusBadGateway, "exchange rates are unavailable from all providers")
+	ErrAmountTooSmallToConvert   = newError("amount_too_small_to_convert", http.StatusUnprocessableEntity, "amount is too small to be converted to the receiver currency")
+	ErrExchangeSameCurrency      = newError("exchange_same_currency", http.StatusUnprocessableEntity, "currencies of the exchange must be different")
+	ErrNotSupportedWallet        = newError("not_supported_wallet", http.StatusUnprocessableEntity, "wallets can only be kept in currencies with two minor unit digits")
+	ErrWalletDoesNotExist        = newError("wallet_not_found", http.StatusNotFound, "wallet in this currency does not exist")
+	ErrQuoteDoesNotExist         = newError("quote_not_found", http.StatusNotFound, "exchange quote does not exist or has expired")
+	ErrQuoteMismatch             = newError("quote_mismatch", http.StatusUnprocessableEntity, "exchange quote was made for other currencies")
+	ErrNegativeMaxRateAge        = newError("negative_max_rate_age", http.StatusUnprocessableEntity, "max age of exchange rates must not be negative")
+	ErrStaleRates                = newError("stale_rates", http.StatusUnprocessableEntity, "exchange rates are older than the requested max age or were not updated for too long")
+	ErrInvalidBody               = newError("invalid_body", http.StatusBadRequest, "invalid body")
+	ErrInvalidUserID             = newError("invalid_user_id", http.StatusBadRequest, "invalid user id")
+	ErrInvalidQueryParams        = newError("invalid_query_params", http.StatusBadRequest, "invalid query params")
+	ErrInvalidAdminToken         = newError("invalid_admin_token", http.StatusUnauthorized, "invalid admin token")
+	ErrAdminDisabled             = newError("admin_disabled", http.StatusForbidden, "admin endpoints are disabled")
+	ErrUnknownLogOutput          = errors.New("log output must be stdout or file")
+	ErrUnknownLogFormat          = errors.New("log format must be json or text")
+	ErrDatabaseUnavailable       = newError("database_unavailable", http.StatusServiceUnavailable, "database is unavailable")
+	ErrInvalidLogLevel           = newError("invalid_log_level", http.StatusUnprocessableEntity, "log level must be panic, fatal, error, warn, info, debug or trace")
 )
+
+// Error is a domain error, it is shown to clients as problem details with its code and HTTP status.
+type Error struct {
+	// Code is a stable machine-readable identifier of the error, e.g. not_enough_money
+	Code    string
+	Status  int
+	message string
+}
+
+func newError(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, message: message}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// NotSupportedCurrencyError is ErrNotSupportedCurrency with the list of supported currencies.
+type NotSupportedCurrencyError struct {
+	Currency  string
+	Supported []string
+}
+
+func (e *NotSupportedCurrencyError) Error() string {
+	return fmt.Sprintf("currency %q is not supported, supported currencies: %s", e.Currency,
+		strings.Join(e.Supported, ", "))
+}
+
+func (e *NotSupportedCurrencyError) Unwrap() error {
+	return ErrNotSupportedCurrency
+}
@@ -0,0 +1,155 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
+)
+
+// System accounts are the counterparties of money coming from and going out of the service.
+const (
+	TopUps       = "top_ups"
+	WriteOffs    = "write_offs"
+	Reservations = "reservations"
+	Revenue      = "revenue"
+	// Exchange takes money in one currency and gives it out in another one, so entries with a conversion
+	// are balanced in every currency
+	Exchange = "exchange"
+)
+
+const (
+	querySaveEntry = `
+		INSERT INTO transactions(operation_type, sender, receiver, amount, comment, reason, source, currency,
+			receiver_currency, receiver_amount, rate)
+		VALUES ($1, $2, NULLIF($3, 0), $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, $11)
+		RETURNING id`
+	querySavePosting = `
+		INSERT INTO postings(transaction_id, user_id, system_account, amount, balance_after, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+)
+
+//go:generate moq -out ./mock/ledger_mock.go -pkg mock . Writer:MockWriter
+
+// Writer saves entries to the ledger, repositories use it so that their tests do not depend on the ledger queries.
+type Writer interface {
+	Write(ctx context.Context, transaction pgx.Tx, entry *Entry) (int64, error)
+}
+
+// Journal is the Writer saving entries with Write.
+type Journal struct{}
+
+func (Journal) Write(ctx context.Context, transaction pgx.Tx, entry *Entry) (int64, error) {
+	return Write(ctx, transaction, entry)
+}
+
+// Entry is one operation written to the ledger. Amounts of its postings must sum to zero.
+type Entry struct {
+	OperationType string
+	SenderID      int64
+	ReceiverID    int64
+	Amount        money.Money
+	Postings      []Posting
+	// Comment, Reason and Source describe where and why the money was moved, they are optional
+	Comment string
+	Reason  string
+	// Source is a JSON object, nil is saved as NULL
+	Source []byte
+	// Currency is the currency of Amount and of the postings without currency, it is the base currency if empty
+	Currency string
+	// Conversion is set if the receiver got the money in another currency
+	Conversion *Conversion
+}
+
+// Conversion is the amount of the entry credited to the receiver in another currency.
+type Conversion struct {
+	Currency string
+	Amount   money.Money
+	// Rate is the amount of Currency given for one unit of the entry currency
+	Rate float64
+}
+
+// Posting credits (positive amount) or debits (negative amount) one account.
+type Posting struct {
+	UserID        int64
+	SystemAccount string
+	Amount        money.Money
+	// BalanceAfter is the user balance after the posting, it is not tracked for system accounts
+	BalanceAfter money.Money
+	// Currency is the currency of the account, it is the entry currency if empty
+	Currency string
+}
+
+// User creates a posting to the user account, balanceAfter must be the updated cached balance.
+func User(userID int64, amount, balanceAfter money.Money) Posting {
+	return Posting{UserID: userID, Amount: amount, BalanceAfter: balanceAfter}
+}
+
+// System creates a posting to one of the system accounts.
+func System(account string, amount money.Money) Posting {
+	return Posting{SystemAccount: account, Amount: amount}
+}
+
+// In returns the posting to the account in the currency.
+func (p Posting) In(currency string) Posting {
+	p.Currency = currency
+	return p
+}
+
+// Write saves the entry and its postings in the given transaction and returns the entry ID.
+func Write(ctx context.Context, transaction pgx.Tx, entry *Entry) (int64, error) {
+	currency := entry.Currency
+	if currency == "" {
+		currency = constants.BaseCurrency
+	}
+
+	sums := make(map[string]money.Money) // postings must sum to zero in every currency
+	for _, posting := range entry.Postings {
+		if posting.Currency == "" {
+			posting.Currency = currency
+		}
+		sums[posting.Currency] += posting.Amount
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return 0, createdErrors.ErrUnbalancedEntry
+		}
+	}
+	if len(entry.Postings) < 2 {
+		return 0, createdErrors.ErrUnbalancedEntry
+	}
+
+	var receiverCurrency, receiverAmount, rate interface{}
+	if entry.Conversion != nil {
+		receiverCurrency, receiverAmount, rate = entry.Conversion.Currency, entry.Conversion.Amount, entry.Conversion.Rate
+	}
+
+	var entryID int64
+	if err := transaction.QueryRow(ctx, querySaveEntry, entry.OperationType, entry.SenderID, entry.ReceiverID,
+		entry.Amount, entry.Comment, entry.Reason, entry.Source, currency, receiverCurrency, receiverAmount,
+		rate).Scan(&entryID); err != nil {
+		return 0, err
+	}
+
+	for _, posting := range entry.Postings {
+		var userID, systemAccount, balanceAfter interface{}
+		if posting.SystemAccount != "" {
+			systemAccount = posting.SystemAccount
+		} else {
+			userID, balanceAfter = posting.UserID, posting.BalanceAfter
+		}
+		if posting.Currency == "" {
+			posting.Currency = currency
+		}
+
+		if _, err := transaction.Exec(ctx, querySavePosting, entryID, userID, systemAccount, posting.Amount,
+			balanceAfter, posting.Currency); err != nil {
+			return 0, err
+		}
+	}
+
+	return entryID, nil
+}
@@ -0,0 +1,211 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/money"
+)
+
+func TestWrite(t *testing.T) {
+	dbErr := errors.New("Error in database")
+
+	tests := []struct {
+		name        string
+		entry       *Entry
+		mock        func(mock pgxmock.PgxPoolIface)
+		expected    int64
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Transfer between users",
+			entry: &Entry{
+				OperationType: "transfer",
+				SenderID:      1,
+				ReceiverID:    2,
+				Amount:        500,
+				Postings:      []Posting{User(1, -500, 1000), User(2, 500, 700)},
+			},
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(querySaveEntry)).
+					WithArgs("transfer", int64(1), int64(2), money.Money(500), "", "", []byte(nil), "RUB", nil, nil, nil).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(10)))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(10), int64(1), nil, money.Money(-500), money.Money(1000), "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(10), int64(2), nil, money.Money(500), money.Money(700), "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+			expected: 10,
+		},
+		{
+			name: "Top up from the system account",
+			entry: &Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Amount:        500,
+				Postings:      []Posting{User(1, 500, 500), System(TopUps, -500)},
+			},
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(querySaveEntry)).
+					WithArgs("add", int64(1), int64(0), money.Money(500), "", "", []byte(nil), "RUB", nil, nil, nil).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(11)))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(11), int64(1), nil, money.Money(500), money.Money(500), "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(11), nil, TopUps, money.Money(-500), nil, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+			expected: 11,
+		},
+		{
+			name: "Entry with comment, reason and source",
+			entry: &Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Amount:        500,
+				Postings:      []Posting{User(1, 500, 500), System(TopUps, -500)},
+				Comment:       "Welcome bonus",
+				Reason:        "promo",
+				Source:        []byte(`{"promo_code":"WELCOME100"}`),
+			},
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(querySaveEntry)).
+					WithArgs("add", int64(1), int64(0), money.Money(500), "Welcome bonus", "promo",
+						[]byte(`{"promo_code":"WELCOME100"}`), "RUB", nil, nil, nil).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(13)))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(13), int64(1), nil, money.Money(500), money.Money(500), "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(13), nil, TopUps, money.Money(-500), nil, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+			expected: 13,
+		},
+		{
+			name: "Transfer with conversion",
+			entry: &Entry{
+				OperationType: "transfer",
+				SenderID:      1,
+				ReceiverID:    2,
+				Amount:        10000,
+				Conversion:    &Conversion{Currency: "USD", Amount: 131, Rate: 0.0131},
+				Postings: []Posting{
+					User(1, -10000, 0),
+					System(Exchange, 10000),
+					System(Exchange, -131).In("USD"),
+					User(2, 131, 131).In("USD"),
+				},
+			},
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(querySaveEntry)).
+					WithArgs("transfer", int64(1), int64(2), money.Money(10000), "", "", []byte(nil), "RUB", "USD",
+						money.Money(131), 0.0131).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(14)))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(14), int64(1), nil, money.Money(-10000), money.Money(0), "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(14), nil, Exchange, money.Money(10000), nil, "RUB").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(14), nil, Exchange, money.Money(-131), nil, "USD").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(14), int64(2), nil, money.Money(131), money.Money(131), "USD").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+			expected: 14,
+		},
+		{
+			name: "Postings are not balanced in every currency",
+			entry: &Entry{
+				OperationType: "transfer",
+				SenderID:      1,
+				ReceiverID:    2,
+				Amount:        500,
+				Postings:      []Posting{User(1, -500, 0), User(2, 500, 500).In("USD")},
+			},
+			mock:        func(mock pgxmock.PgxPoolIface) {},
+			expectedErr: true,
+			err:         createdErrors.ErrUnbalancedEntry,
+		},
+		{
+			name: "Postings do not sum to zero",
+			entry: &Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Amount:        500,
+				Postings:      []Posting{User(1, 500, 500), System(TopUps, -400)},
+			},
+			mock:        func(mock pgxmock.PgxPoolIface) {},
+			expectedErr: true,
+			err:         createdErrors.ErrUnbalancedEntry,
+		},
+		{
+			name: "Entry with one posting",
+			entry: &Entry{
+				OperationType: "add",
+				SenderID:      1,
+				Postings:      []Posting{User(1, 0, 0)},
+			},
+			mock:        func(mock pgxmock.PgxPoolIface) {},
+			expectedErr: true,
+			err:         createdErrors.ErrUnbalancedEntry,
+		},
+		{
+			name: "Error in database during saving posting",
+			entry: &Entry{
+				OperationType: "write_off",
+				SenderID:      1,
+				Amount:        500,
+				Postings:      []Posting{User(1, -500, 0), System(WriteOffs, 500)},
+			},
+			mock: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(querySaveEntry)).
+					WithArgs("write_off", int64(1), int64(0), money.Money(500), "", "", []byte(nil), "RUB", nil, nil, nil).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(12)))
+				mock.ExpectExec(regexp.QuoteMeta(querySavePosting)).
+					WithArgs(int64(12), int64(1), nil, money.Money(-500), money.Money(0), "RUB").
+					WillReturnError(dbErr)
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			if err != nil {
+				t.Errorf("Could not mock database connection: %s", err)
+			}
+			mock.ExpectBegin()
+			transaction, err := mock.Begin(context.Background())
+			assert.NoError(t, err)
+
+			test.mock(mock)
+			got, err := Write(context.Background(), transaction, test.entry)
+
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"avito-tech-task/internal/pkg/ledger"
+	"context"
+	"github.com/jackc/pgx/v4"
+	"sync"
+)
+
+// Ensure, that MockWriter does implement ledger.Writer.
+// If this is not the case, regenerate this file with moq.
+var _ ledger.Writer = &MockWriter{}
+
+// MockWriter is a mock implementation of ledger.Writer.
+//
+//	func TestSomethingThatUsesWriter(t *testing.T) {
+//
+//		// make and configure a mocked ledger.Writer
+//		mockedWriter := &MockWriter{
+//			WriteFunc: func(ctx context.Context, transaction pgx.Tx, entry *ledger.Entry) (int64, error) {
+//				panic("mock out the Write method")
+//			},
+//		}
+//
+//		// use mockedWriter in code that requires ledger.Writer
+//		// and then make assertions.
+//
+//	}
+type MockWriter struct {
+	// WriteFunc mocks the Write method.
+	WriteFunc func(ctx context.Context, transaction pgx.Tx, entry *ledger.Entry) (int64, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Write holds details about calls to the Write method.
+		Write []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Transaction is the transaction argument value.
+			Transaction pgx.Tx
+			// Entry is the entry argument value.
+			Entry *ledger.Entry
+		}
+	}
+	lockWrite sync.RWMutex
+}
+
+// Write calls WriteFunc.
+func (mock *MockWriter) Write(ctx context.Context, transaction pgx.Tx, entry *ledger.Entry) (int64, error) {
+	if mock.WriteFunc == nil {
+		panic("MockWriter.WriteFunc: method is nil but Writer.Write was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		Transaction pgx.Tx
+		Entry       *ledger.Entry
+	}{
+		Ctx:         ctx,
+		Transaction: transaction,
+		Entry:       entry,
+	}
+	mock.lockWrite.Lock()
+	mock.calls.Write = append(mock.calls.Write, callInfo)
+	mock.lockWrite.Unlock()
+	return mock.WriteFunc(ctx, transaction, entry)
+}
+
+// WriteCalls gets all the calls that were made to Write.
+// Check the length with:
+//
+//	len(mockedWriter.WriteCalls())
+func (mock *MockWriter) WriteCalls() []struct {
+	Ctx         context.Context
+	Transaction pgx.Tx
+	Entry       *ledger.Entry
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Transaction pgx.Tx
+		Entry       *ledger.Entry
+	}
+	mock.lockWrite.RLock()
+	calls = mock.calls.Write
+	mock.lockWrite.RUnlock()
+	return calls
+}
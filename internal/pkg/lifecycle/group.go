@@ -0,0 +1,115 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrShutdownTimeout is returned by Wait if some workers did not stop before the deadline.
+var ErrShutdownTimeout = errors.New("workers were not stopped before the shutdown deadline")
+
+type worker struct {
+	name string
+	// stop asks the worker to return, by default it only cancels the context of the worker
+	stop   func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Group runs the long-lived workers of the service, e.g. the HTTP server and background jobs. Like errgroup,
+// the first failed worker stops the whole group. Unlike errgroup, the workers are stopped one by one in the
+// order they were started, so the HTTP server drains its requests before the jobs they may rely on are stopped.
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	logger  *logrus.Logger
+
+	mutex   sync.Mutex
+	workers []*worker
+	err     error
+}
+
+// NewGroup returns a group that starts shutting down when ctx is cancelled. All workers must stop
+// within timeout after that.
+func NewGroup(ctx context.Context, timeout time.Duration, logger *logrus.Logger) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Group{ctx: ctx, cancel: cancel, timeout: timeout, logger: logger}
+}
+
+// Go starts the worker. Run must return after its context is cancelled. Workers that do not watch
+// the context, like http.Server, pass stop, which is called with the shutdown deadline instead.
+func (g *Group) Go(name string, run func(ctx context.Context) error, stop func(ctx context.Context) error) {
+	// the worker is not stopped with the group context, it waits for its turn in Wait
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, stop: stop, cancel: cancel, done: make(chan struct{})}
+
+	g.mutex.Lock()
+	g.workers = append(g.workers, w)
+	g.mutex.Unlock()
+
+	go func() {
+		defer close(w.done)
+		if err := run(ctx); err != nil {
+			g.fail(fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		if g.ctx.Err() == nil {
+			g.logger.Warnf("%s stopped before shutdown", name)
+		}
+	}()
+}
+
+// Wait blocks until the context of the group is cancelled or a worker fails, then stops the workers and
+// returns the first error of them.
+func (g *Group) Wait() error {
+	<-g.ctx.Done()
+	g.logger.Info("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	g.mutex.Lock()
+	workers := g.workers
+	g.mutex.Unlock()
+
+	for _, w := range workers {
+		g.logger.Infof("Stopping %s", w.name)
+		if w.stop != nil {
+			if err := w.stop(ctx); err != nil {
+				g.fail(fmt.Errorf("could not stop %s: %w", w.name, err))
+			}
+		}
+		w.cancel()
+
+		select {
+		case <-w.done:
+			g.logger.Infof("Stopped %s", w.name)
+		case <-ctx.Done():
+			g.logger.Errorf("%s was not stopped in %s", w.name, g.timeout)
+			g.fail(fmt.Errorf("%w: %s", ErrShutdownTimeout, w.name))
+		}
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.err
+}
+
+// fail records the first error and starts shutting down the group.
+func (g *Group) fail(err error) {
+	g.mutex.Lock()
+	if g.err == nil {
+		g.err = err
+	}
+	g.mutex.Unlock()
+
+	g.cancel()
+}
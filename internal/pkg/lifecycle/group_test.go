@@ -0,0 +1,112 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder remembers the order workers were stopped in.
+type recorder struct {
+	mutex   sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) worker(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.stopped = append(r.stopped, name)
+		return nil
+	}
+}
+
+func (r *recorder) Stopped() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.stopped
+}
+
+func waitGroup(t *testing.T, group *Group) error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		result <- group.Wait()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Group was not stopped")
+		return nil
+	}
+}
+
+func TestGroup_StopsWorkersInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group := NewGroup(ctx, time.Second, logrus.New())
+	r := &recorder{}
+
+	// the server does not watch the context and is stopped by its stop function
+	serverStopped := make(chan struct{})
+	group.Go("server", func(ctx context.Context) error {
+		<-serverStopped
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.stopped = append(r.stopped, "server")
+		return nil
+	}, func(ctx context.Context) error {
+		close(serverStopped)
+		return nil
+	})
+	group.Go("updater", r.worker("updater"), nil)
+	group.Go("cleaner", r.worker("cleaner"), nil)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, r.Stopped(), "workers run until the group is cancelled")
+
+	cancel()
+	assert.NoError(t, waitGroup(t, group))
+	assert.Equal(t, []string{"server", "updater", "cleaner"}, r.Stopped())
+}
+
+func TestGroup_FailedWorkerStopsGroup(t *testing.T) {
+	group := NewGroup(context.Background(), time.Second, logrus.New())
+	r := &recorder{}
+	startErr := errors.New("address already in use")
+
+	group.Go("updater", r.worker("updater"), nil)
+	group.Go("server", func(ctx context.Context) error {
+		return startErr
+	}, nil)
+
+	err := waitGroup(t, group)
+	assert.ErrorIs(t, err, startErr)
+	assert.Equal(t, []string{"updater"}, r.Stopped())
+}
+
+func TestGroup_ShutdownTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group := NewGroup(ctx, 10*time.Millisecond, logrus.New())
+	r := &recorder{}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	group.Go("stuck", func(ctx context.Context) error {
+		<-stuck
+		return nil
+	}, nil)
+	group.Go("cleaner", r.worker("cleaner"), nil)
+
+	cancel()
+	err := waitGroup(t, group)
+	assert.ErrorIs(t, err, ErrShutdownTimeout)
+	assert.Contains(t, err.Error(), "stuck")
+}
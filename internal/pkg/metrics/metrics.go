@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"avito-tech-task/internal/pkg/money"
+)
+
+const (
+	namespace = "balance"
+	// unmatchedRoute is the route of requests to unknown paths, so that they do not make new series
+	unmatchedRoute = "unmatched"
+)
+
+// Metrics are the Prometheus metrics of the service. They are recorded by the HTTP middleware and
+// by the hooks called from usecases and the currency converter, and exposed by Handler.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	transactions       *prometheus.CounterVec
+	transactionsAmount *prometheus.CounterVec
+	insufficientFunds  *prometheus.CounterVec
+	convertedHistory   *prometheus.CounterVec
+
+	rateUpdates          *prometheus.CounterVec
+	lastRateUpdate       prometheus.Gauge
+	lastRateUpdateStatus prometheus.Gauge
+
+	// fetched is the time the actual rates were received at
+	mutex   sync.RWMutex
+	fetched time.Time
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of handled HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_total",
+			Help:      "Number of committed transactions.",
+		}, []string{"operation_type", "currency"}),
+		transactionsAmount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_amount_total",
+			Help:      "Sum of amounts of committed transactions in major units of the currency.",
+		}, []string{"operation_type", "currency"}),
+		insufficientFunds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "insufficient_funds_total",
+			Help:      "Number of operations rejected because of not enough money on balance.",
+		}, []string{"operation_type"}),
+		convertedHistory: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "history_converted_transactions_total",
+			Help:      "Number of transactions of the history converted at historical rates.",
+		}, []string{"currency"}),
+		rateUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rates_updates_total",
+			Help:      "Number of updates of exchange rates by result.",
+		}, []string{"result"}),
+		lastRateUpdate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rates_last_update_timestamp_seconds",
+			Help:      "Time of the last update of exchange rates.",
+		}),
+		lastRateUpdateStatus: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rates_last_update_success",
+			Help:      "Whether the last update of exchange rates succeeded.",
+		}),
+	}
+
+	rateAge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rates_age_seconds",
+		Help:      "Time since the actual exchange rates were received, +Inf if they never were.",
+	}, m.rateAge)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration,
+		m.transactions, m.transactionsAmount, m.insufficientFunds, m.convertedHistory,
+		m.rateUpdates, m.lastRateUpdate, m.lastRateUpdateStatus, rateAge,
+	)
+
+	return m
+}
+
+// RegisterPool adds the statistics of the database pool, they are read on every scrape.
+func (m *Metrics) RegisterPool(pool PoolStater) {
+	m.registry.MustRegister(newPoolCollector(pool))
+}
+
+// Handler exposes the metrics in the Prometheus text format.
+func (m *Metrics) Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware counts requests and observes their latency by route and status. It renders the error of
+// the handler, so it must be the first middleware to see the status the client gets.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			route := ctx.Path() // the pattern of the route, so IDs of users do not make new series
+			err := next(ctx)
+			if err != nil {
+				ctx.Error(err)
+			}
+			if errors.Is(err, echo.ErrNotFound) { // the router did not find the route and the path is set as it is
+				route = unmatchedRoute
+			}
+
+			labels := prometheus.Labels{
+				"method": ctx.Request().Method,
+				"route":  route,
+				"status": strconv.Itoa(ctx.Response().Status),
+			}
+			m.requests.With(labels).Inc()
+			m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
+
+// TransactionCommitted counts the transaction written to the ledger and its amount.
+func (m *Metrics) TransactionCommitted(operationType, currency string, amount money.Money) {
+	m.transactions.WithLabelValues(operationType, currency).Inc()
+	m.transactionsAmount.WithLabelValues(operationType, currency).Add(amount.Float())
+}
+
+// InsufficientFunds counts the operation rejected because of not enough money on balance.
+func (m *Metrics) InsufficientFunds(operationType string) {
+	m.insufficientFunds.WithLabelValues(operationType).Inc()
+}
+
+// HistoryConverted counts transactions of the history converted to the currency.
+func (m *Metrics) HistoryConverted(currency string, transactions int) {
+	m.convertedHistory.WithLabelValues(currency).Add(float64(transactions))
+}
+
+// RatesUpdated records the result of the update of exchange rates, err is nil on success.
+func (m *Metrics) RatesUpdated(err error) {
+	m.lastRateUpdate.SetToCurrentTime()
+	if err != nil {
+		m.rateUpdates.WithLabelValues("failure").Inc()
+		m.lastRateUpdateStatus.Set(0)
+		return
+	}
+	m.rateUpdates.WithLabelValues("success").Inc()
+	m.lastRateUpdateStatus.Set(1)
+}
+
+// RatesFetched sets the time the actual exchange rates were received at, the age of the rates is counted from it.
+func (m *Metrics) RatesFetched(fetched time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.fetched = fetched
+}
+
+func (m *Metrics) rateAge() float64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.fetched.IsZero() {
+		return math.Inf(1)
+	}
+
+	return time.Since(m.fetched).Seconds()
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+func scrape(t *testing.T, server *echo.Echo) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("Could not read metrics: %s", err)
+	}
+	return string(body)
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	metrics := New()
+	server := echo.New()
+	server.HTTPErrorHandler = utils.NewHTTPErrorHandler()
+	server.Use(metrics.Middleware())
+	server.GET("/metrics", metrics.Handler())
+	server.GET("/api/v1/balance/:user_id", func(ctx echo.Context) error {
+		if ctx.Param("user_id") == "0" {
+			return createdErrors.ErrUserDoesNotExist
+		}
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/api/v1/balance/1", "/api/v1/balance/2", "/api/v1/balance/0", "/unknown/1"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, server)
+	assert.Contains(t, body, `balance_http_requests_total{method="GET",route="/api/v1/balance/:user_id",status="200"} 2`)
+	assert.Contains(t, body, `balance_http_requests_total{method="GET",route="/api/v1/balance/:user_id",status="404"} 1`,
+		"status of the rendered error is counted")
+	assert.Contains(t, body, `balance_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body,
+		`balance_http_request_duration_seconds_count{method="GET",route="/api/v1/balance/:user_id",status="200"} 2`)
+}
+
+func TestMetrics_Hooks(t *testing.T) {
+	metrics := New()
+	server := echo.New()
+	server.GET("/metrics", metrics.Handler())
+
+	body := scrape(t, server)
+	assert.Contains(t, body, "balance_rates_age_seconds +Inf", "rates were never received")
+
+	metrics.TransactionCommitted("add", "RUB", 1050)
+	metrics.TransactionCommitted("add", "RUB", 25)
+	metrics.TransactionCommitted("transfer", "USD", 100)
+	metrics.InsufficientFunds("write_off")
+	metrics.HistoryConverted("EUR", 3)
+	metrics.RatesUpdated(errors.New("timeout"))
+	metrics.RatesUpdated(nil)
+	metrics.RatesFetched(time.Now().Add(-time.Hour))
+
+	body = scrape(t, server)
+	assert.Contains(t, body, `balance_transactions_total{currency="RUB",operation_type="add"} 2`)
+	assert.Contains(t, body, `balance_transactions_amount_total{currency="RUB",operation_type="add"} 10.75`)
+	assert.Contains(t, body, `balance_transactions_amount_total{currency="USD",operation_type="transfer"} 1`)
+	assert.Contains(t, body, `balance_insufficient_funds_total{operation_type="write_off"} 1`)
+	assert.Contains(t, body, `balance_history_converted_transactions_total{currency="EUR"} 3`)
+	assert.Contains(t, body, `balance_rates_updates_total{result="failure"} 1`)
+	assert.Contains(t, body, `balance_rates_updates_total{result="success"} 1`)
+	assert.Contains(t, body, "balance_rates_last_update_success 1")
+	assert.Contains(t, body, "balance_rates_age_seconds 3600")
+}
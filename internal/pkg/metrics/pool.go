@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStater is the database pool, e.g. *pgxpool.Pool.
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolCollector reads the statistics of the pool once per scrape.
+type poolCollector struct {
+	pool PoolStater
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+func newPoolCollector(pool PoolStater) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool:                 pool,
+		acquiredConns:        desc("acquired_connections", "Number of connections currently in use."),
+		idleConns:            desc("idle_connections", "Number of idle connections."),
+		constructingConns:    desc("constructing_connections", "Number of connections being established."),
+		totalConns:           desc("total_connections", "Number of open connections."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		acquireCount:         desc("acquires_total", "Number of successful acquires of connections."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time of successful acquires."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Number of acquires that waited for a connection."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Number of acquires cancelled by the context."),
+	}
+}
+
+func (c *poolCollector) Describe(descs chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, descs)
+}
+
+func (c *poolCollector) Collect(metrics chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		metrics <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		metrics <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+}
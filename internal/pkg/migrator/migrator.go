@@ -0,0 +1,351 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sirupsen/logrus"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+	"avito-tech-task/internal/pkg/utils"
+)
+
+const (
+	// lockID is the key of the advisory lock, which serializes migrations run by several instances at once
+	lockID = 7342105
+
+	queryLock                  = `SELECT pg_advisory_xact_lock($1)`
+	queryCreateMigrationsTable = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint                   NOT NULL PRIMARY KEY,
+			name    varchar(255)             NOT NULL,
+			applied timestamp with time zone NOT NULL DEFAULT now()
+		)`
+	queryGetAppliedMigrations = `SELECT version, applied FROM schema_migrations`
+	queryIsMigrationApplied   = `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)`
+	querySaveMigration        = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	queryDeleteMigration      = `DELETE FROM schema_migrations WHERE version = $1`
+)
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	// AppliedAt is zero if the migration is not applied
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         utils.PgxIface
+	migrations []*Migration
+	logger     *logrus.Logger
+}
+
+// NewMigrator loads migrations from the root of files, see Load.
+func NewMigrator(db utils.PgxIface, files fs.FS, logger *logrus.Logger) (*Migrator, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// Load reads pairs of files <version>_<name>.up.sql and <version>_<name>.down.sql
+// and returns migrations sorted by version.
+func Load(files fs.FS) ([]*Migration, error) {
+	paths, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, path := range paths {
+		match := fileNameRegexp.FindStringSubmatch(path)
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", createdErrors.ErrInvalidMigrationFile, path)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", createdErrors.ErrInvalidMigrationFile, path)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: %s, version %d is already used by %s",
+				createdErrors.ErrInvalidMigrationFile, path, version, migration.Name)
+		}
+
+		content, err := fs.ReadFile(files, path)
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: migration %d must have both up and down files",
+				createdErrors.ErrInvalidMigrationFile, migration.Version)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest returns the version of the newest known migration or 0 if there are no migrations.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			return m.step(ctx, m.migrations[i], false)
+		}
+	}
+	m.logger.Info("There are no applied migrations to revert")
+
+	return nil
+}
+
+// To applies pending migrations up to version and reverts applied migrations above it,
+// version 0 reverts all migrations.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", createdErrors.ErrUnknownMigrationVersion, version)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			if err = m.step(ctx, migration, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			if err = m.step(ctx, migration, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Status returns all known migrations with the time they were applied at.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, &MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Force marks migrations up to version as applied and the rest as not applied without running them.
+// It is used to adopt a database whose schema was created before migrations were introduced.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", createdErrors.ErrUnknownMigrationVersion, version)
+	}
+
+	transaction, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback(context.Background()) // does nothing after commit
+	}()
+
+	for _, migration := range m.migrations {
+		if _, err = transaction.Exec(ctx, queryDeleteMigration, migration.Version); err != nil {
+			return err
+		}
+		if migration.Version <= version {
+			if _, err = transaction.Exec(ctx, querySaveMigration, migration.Version, migration.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = transaction.Commit(ctx); err != nil {
+		return err
+	}
+	m.logger.Infof("Schema version was forced to %d", version)
+
+	return nil
+}
+
+// step applies or reverts one migration together with its record in schema_migrations.
+func (m *Migrator) step(ctx context.Context, migration *Migration, up bool) error {
+	transaction, err := m.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback(context.Background()) // does nothing after commit
+	}()
+
+	// another instance could have run the migration while we were waiting for the lock
+	var applied bool
+	if err = transaction.QueryRow(ctx, queryIsMigrationApplied, migration.Version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied == up {
+		return nil
+	}
+
+	action, query := "Reverted", migration.Down
+	if up {
+		action, query = "Applied", migration.Up
+	}
+	if _, err = transaction.Exec(ctx, query); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if up {
+		_, err = transaction.Exec(ctx, querySaveMigration, migration.Version, migration.Name)
+	} else {
+		_, err = transaction.Exec(ctx, queryDeleteMigration, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = transaction.Commit(ctx); err != nil {
+		return err
+	}
+	m.logger.Infof("%s migration %d_%s", action, migration.Version, migration.Name)
+
+	return nil
+}
+
+// begin starts a transaction holding the migrations lock and makes sure that schema_migrations exists.
+func (m *Migrator) begin(ctx context.Context) (pgx.Tx, error) {
+	transaction, err := m.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = transaction.Exec(ctx, queryLock, lockID); err != nil {
+		_ = transaction.Rollback(context.Background())
+		return nil, err
+	}
+	if _, err = transaction.Exec(ctx, queryCreateMigrationsTable); err != nil {
+		_ = transaction.Rollback(context.Background())
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// applied returns versions of applied migrations with the time they were applied at.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	transaction, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback(context.Background()) // does nothing after commit
+	}()
+
+	rows, err := transaction.Query(ctx, queryGetAppliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return applied, transaction.Commit(ctx)
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/db"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_init.up.sql":      {Data: []byte("create table first (id int);")},
+	"0001_init.down.sql":    {Data: []byte("drop table first;")},
+	"0002_second.up.sql":    {Data: []byte("create table second (id int);")},
+	"0002_second.down.sql":  {Data: []byte("drop table second;")},
+	"0010_skipped.up.sql":   {Data: []byte("create table skipped (id int);")},
+	"0010_skipped.down.sql": {Data: []byte("drop table skipped;")},
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       fstest.MapFS
+		expected    []int64
+		expectedErr bool
+		err         error
+	}{
+		{
+			name:     "Migrations are sorted by version",
+			files:    testMigrations,
+			expected: []int64{1, 2, 10},
+		},
+		{
+			name: "Invalid file name",
+			files: fstest.MapFS{
+				"init.up.sql": {Data: []byte("select 1;")},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidMigrationFile,
+		},
+		{
+			name: "Down migration is missing",
+			files: fstest.MapFS{
+				"0001_init.up.sql": {Data: []byte("select 1;")},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidMigrationFile,
+		},
+		{
+			name: "Version is used twice",
+			files: fstest.MapFS{
+				"0001_init.up.sql":    {Data: []byte("select 1;")},
+				"0001_init.down.sql":  {Data: []byte("select 1;")},
+				"0001_other.up.sql":   {Data: []byte("select 1;")},
+				"0001_other.down.sql": {Data: []byte("select 1;")},
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrInvalidMigrationFile,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			migrations, err := Load(test.files)
+
+			if test.expectedErr {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				versions := make([]int64, 0, len(migrations))
+				for _, migration := range migrations {
+					versions = append(versions, migration.Version)
+				}
+				assert.Equal(t, test.expected, versions)
+			}
+		})
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	files, err := fs.Sub(db.Migrations, "migrations")
+	assert.NoError(t, err)
+
+	migrations, err := Load(files)
+	assert.NoError(t, err)
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "versions of embedded migrations must go without gaps")
+	}
+}
+
+func expectBegin(mock pgxmock.PgxPoolIface) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(queryLock)).WithArgs(lockID).WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec(regexp.QuoteMeta(queryCreateMigrationsTable)).WillReturnResult(pgxmock.NewResult("CREATE", 0))
+}
+
+func expectApplied(mock pgxmock.PgxPoolIface, versions ...int64) {
+	rows := pgxmock.NewRows([]string{"version", "applied"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Now())
+	}
+
+	expectBegin(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetAppliedMigrations)).WillReturnRows(rows)
+	mock.ExpectCommit()
+}
+
+func expectStep(mock pgxmock.PgxPoolIface, version int64, name, query string, up bool) {
+	expectBegin(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(queryIsMigrationApplied)).WithArgs(version).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(!up))
+	mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(pgxmock.NewResult("CREATE", 0))
+	if up {
+		mock.ExpectExec(regexp.QuoteMeta(querySaveMigration)).WithArgs(version, name).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	} else {
+		mock.ExpectExec(regexp.QuoteMeta(queryDeleteMigration)).WithArgs(version).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+}
+
+func TestMigrator_To(t *testing.T) {
+	dbErr := errors.New("Error in database")
+
+	tests := []struct {
+		name        string
+		mock        func(mock pgxmock.PgxPoolIface)
+		action      func(migrator *Migrator) error
+		expectedErr bool
+		err         error
+	}{
+		{
+			name: "Up applies pending migrations in order",
+			mock: func(mock pgxmock.PgxPoolIface) {
+				expectApplied(mock, 1)
+				expectStep(mock, 2, "second", "create table second (id int);", true)
+				expectStep(mock, 10, "skipped", "create table skipped (id int);", true)
+			},
+			action: func(migrator *Migrator) error {
+				return migrator.Up(context.Background())
+			},
+		},
+		{
+			name: "Down reverts the last applied migration",
+			mock: func(mock pgxmock.PgxPoolIface) {
+				expectApplied(mock, 1, 2)
+				expectStep(mock, 2, "second", "drop table second;", false)
+			},
+			action: func(migrator *Migrator) error {
+				return migrator.Down(context.Background())
+			},
+		},
+		{
+			name: "To reverts migrations above the version",
+			mock: func(mock pgxmock.PgxPoolIface) {
+				expectApplied(mock, 1, 2, 10)
+				expectStep(mock, 10, "skipped", "drop table skipped;", false)
+				expectStep(mock, 2, "second", "drop table second;", false)
+			},
+			action: func(migrator *Migrator) error {
+				return migrator.To(context.Background(), 1)
+			},
+		},
+		{
+			name: "Migration was applied by another instance",
+			mock: func(mock pgxmock.PgxPoolIface) {
+				expectApplied(mock, 1)
+				expectBegin(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(queryIsMigrationApplied)).WithArgs(int64(2)).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			action: func(migrator *Migrator) error {
+				return migrator.To(context.Background(), 2)
+			},
+		},
+		{
+			name: "Failed migration is rolled back",
+			mock: func(mock pgxmock.PgxPoolIface) {
+				expectApplied(mock)
+				expectBegin(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(queryIsMigrationApplied)).WithArgs(int64(1)).
+					WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(regexp.QuoteMeta("create table first (id int);")).WillReturnError(dbErr)
+				mock.ExpectRollback()
+			},
+			action: func(migrator *Migrator) error {
+				return migrator.Up(context.Background())
+			},
+			expectedErr: true,
+			err:         dbErr,
+		},
+		{
+			name: "Unknown version",
+			mock: func(mock pgxmock.PgxPoolIface) {},
+			action: func(migrator *Migrator) error {
+				return migrator.To(context.Background(), 3)
+			},
+			expectedErr: true,
+			err:         createdErrors.ErrUnknownMigrationVersion,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool()
+			if err != nil {
+				t.Errorf("Could not mock database connection: %s", err)
+			}
+			migrator, err := NewMigrator(mock, testMigrations, logrus.New())
+			assert.NoError(t, err)
+
+			test.mock(mock)
+			err = test.action(migrator)
+
+			if test.expectedErr {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Errorf("Could not mock database connection: %s", err)
+	}
+	migrator, err := NewMigrator(mock, testMigrations, logrus.New())
+	assert.NoError(t, err)
+
+	applied := time.Date(2022, 1, 15, 21, 37, 23, 0, time.UTC)
+	expectBegin(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetAppliedMigrations)).
+		WillReturnRows(pgxmock.NewRows([]string{"version", "applied"}).AddRow(int64(1), applied))
+	mock.ExpectCommit()
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*MigrationStatus{
+		{Version: 1, Name: "init", Applied: true, AppliedAt: applied},
+		{Version: 2, Name: "second"},
+		{Version: 10, Name: "skipped"},
+	}, statuses)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Amount is an amount of money in a currency with any number of minor unit digits. It is used for
+// converted amounts, which are only shown and never stored.
+type Amount struct {
+	// Minor is the amount in minor units of the currency, e.g. cents or yen
+	Minor int64
+	// Digits is the number of minor unit digits of the currency, 2 for USD and 0 for JPY
+	Digits int
+}
+
+// Convert converts the amount at the rate to a currency with the given number of minor unit digits.
+// The rate is taken as its shortest decimal form, so the result does not depend on binary floating
+// point errors, and it is rounded half to even.
+func (m Money) Convert(rate float64, digits int) Amount {
+	value, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	if !ok { // NaN and infinities are not valid rates
+		return Amount{Digits: digits}
+	}
+	value.Mul(value, new(big.Rat).SetInt64(int64(m)))
+	value.Mul(value, new(big.Rat).SetFrac(pow10(digits), pow10(Precision)))
+
+	return Amount{Minor: roundHalfEven(value).Int64(), Digits: digits}
+}
+
+// String formats the amount with exactly Digits fractional digits, e.g. "10.50" or "1050".
+func (a Amount) String() string {
+	sign := ""
+	value := a.Minor
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	if a.Digits <= 0 {
+		return fmt.Sprintf("%s%d", sign, value)
+	}
+
+	digits := fmt.Sprintf("%0*d", a.Digits+1, value)
+	point := len(digits) - a.Digits
+
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// MarshalJSON encodes the amount as a JSON number with Digits fractional digits.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func roundHalfEven(value *big.Rat) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	// the remainder has the sign of the numerator and is compared with the half of the denominator
+	double := new(big.Int).Abs(remainder)
+	double.Lsh(double, 1)
+	switch double.Cmp(value.Denom()) {
+	case -1:
+		return quotient
+	case 0:
+		if quotient.Bit(0) == 0 {
+			return quotient
+		}
+	}
+	if remainder.Sign() < 0 {
+		return quotient.Sub(quotient, big.NewInt(1))
+	}
+
+	return quotient.Add(quotient, big.NewInt(1))
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
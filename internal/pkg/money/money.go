@@ -0,0 +1,136 @@
+package money
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+const (
+	// Precision is the number of fractional digits allowed in an amount.
+	Precision = 2
+	// minorUnits is the number of minor units (kopecks) in one major unit (ruble).
+	minorUnits = 100
+)
+
+// Money is an amount of money stored as an integer number of minor units (kopecks),
+// so that repeated credits and debits never accumulate floating point errors.
+type Money int64
+
+// FromMinor creates Money from a number of minor units.
+func FromMinor(minor int64) Money {
+	return Money(minor)
+}
+
+// Parse parses a decimal string like "1000", "10.5" or "-0.01" into Money.
+// Amounts with more than two fractional digits or in exponent form are rejected.
+func Parse(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, createdErrors.ErrInvalidAmount
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	integer, fraction := value, ""
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		integer, fraction = value[:dot], value[dot+1:]
+	}
+	if integer == "" || !isDigits(integer) || !isDigits(fraction) || len(fraction) > Precision {
+		return 0, createdErrors.ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", Precision-len(fraction))
+
+	major, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil || major > math.MaxInt64/minorUnits-1 {
+		return 0, createdErrors.ErrInvalidAmount
+	}
+	minor, _ := strconv.ParseInt(fraction, 10, 64)
+
+	amount := major*minorUnits + minor
+	if negative {
+		amount = -amount
+	}
+
+	return Money(amount), nil
+}
+
+// Minor returns the amount as a number of minor units.
+func (m Money) Minor() int64 {
+	return int64(m)
+}
+
+// Float returns the amount in major units. It may be inexact, so it is only used for reporting, e.g. in metrics.
+func (m Money) Float() float64 {
+	return float64(m) / minorUnits
+}
+
+// Mul multiplies the amount by a rate and rounds the result to the nearest minor unit.
+func (m Money) Mul(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// String formats the amount with exactly two fractional digits, e.g. "10.50".
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, value/minorUnits, value%minorUnits)
+}
+
+// MarshalJSON encodes the amount as a JSON number with two fractional digits.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes the amount from a JSON number or a string containing a number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if string(data) == "null" {
+		return nil
+	}
+
+	parsed, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
+
+// UnmarshalParam decodes the amount from query and form params.
+func (m *Money) UnmarshalParam(param string) error {
+	parsed, err := Parse(param)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
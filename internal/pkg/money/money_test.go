@@ -0,0 +1,100 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    Money
+		expectedErr bool
+	}{
+		{name: "Integer amount", value: "1000", expected: 100000},
+		{name: "One fractional digit", value: "10.5", expected: 1050},
+		{name: "Two fractional digits", value: "0.01", expected: 1},
+		{name: "Negative amount", value: "-12.34", expected: -1234},
+		{name: "Too many fractional digits", value: "10.505", expectedErr: true},
+		{name: "Exponent form", value: "1e3", expectedErr: true},
+		{name: "Empty fraction part", value: "10.", expected: 1000},
+		{name: "Empty integer part", value: ".5", expectedErr: true},
+		{name: "Not a number", value: "ten", expectedErr: true},
+		{name: "Empty string", value: "", expectedErr: true},
+		{name: "Overflow", value: "999999999999999999999", expectedErr: true},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.value)
+
+			if test.expectedErr {
+				assert.Equal(t, createdErrors.ErrInvalidAmount, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var data struct {
+		Amount Money `json:"amount"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 1000.5}`), &data))
+	assert.Equal(t, Money(100050), data.Amount)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "0.10"}`), &data))
+	assert.Equal(t, Money(10), data.Amount)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 0.001}`), &data))
+
+	encoded, err := json.Marshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":0.10}`, string(encoded))
+
+	encoded, err = json.Marshal(Money(-5))
+	assert.NoError(t, err)
+	assert.Equal(t, `-0.05`, string(encoded))
+}
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		rate     float64
+		digits   int
+		expected string
+	}{
+		{name: "Two digits", amount: 100000, rate: 0.0131, digits: 2, expected: "13.10"},
+		{name: "No minor units", amount: 100000, rate: 1.5155, digits: 0, expected: "1516"},
+		{name: "Three digits", amount: 100000, rate: 0.004, digits: 3, expected: "4.000"},
+		{name: "Half is rounded to even down", amount: 1, rate: 0.5, digits: 2, expected: "0.00"},
+		{name: "Half is rounded to even up", amount: 3, rate: 0.5, digits: 2, expected: "0.02"},
+		{name: "Rate without binary representation", amount: 5, rate: 0.1, digits: 3, expected: "0.005"},
+		{name: "Half of negative amount", amount: -5, rate: 0.1, digits: 2, expected: "0.00"},
+		{name: "Negative amount", amount: -1234, rate: 0.5, digits: 2, expected: "-6.17"},
+		{name: "Zero", amount: 0, rate: 0.0131, digits: 2, expected: "0.00"},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			got := test.amount.Convert(test.rate, test.digits)
+
+			assert.Equal(t, test.digits, got.Digits)
+			assert.Equal(t, test.expected, got.String())
+			encoded, err := json.Marshal(got)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(encoded))
+		})
+	}
+}
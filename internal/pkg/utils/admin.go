@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+// AdminOnly allows requests with the admin token in the "Authorization: Bearer <token>" header,
+// all requests are forbidden if the token is not configured.
+func AdminOnly(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if token == "" {
+				return createdErrors.ErrAdminDisabled
+			}
+
+			authorization := ctx.Request().Header.Get(echo.HeaderAuthorization)
+			given := strings.TrimPrefix(authorization, "Bearer ")
+			if given == authorization || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return createdErrors.ErrInvalidAdminToken
+			}
+
+			return next(ctx)
+		}
+	}
+}
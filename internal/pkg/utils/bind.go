@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"errors"
+
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+// BindError is the error shown to the client when the request body could not be bound. A malformed amount
+// is reported as such, so that clients can tell it from a malformed body.
+func BindError(err error) error {
+	if errors.Is(err, createdErrors.ErrInvalidAmount) {
+		return createdErrors.ErrInvalidAmount
+	}
+
+	return createdErrors.ErrInvalidBody
+}
@@ -1,55 +1,134 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"time"
+	"syscall"
 
+	"github.com/BurntSushi/toml"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"avito-tech-task/config"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
 )
 
+const hoursInDay = 24
+
+// NewLogger creates the logger writing to the outputs of the config, the returned function closes the log file.
 func NewLogger(config *config.Config) (*logrus.Logger, func() error) {
-	doesLogDirExist := true
-	if _, err := os.Stat(config.LoggingFilePath); err != nil {
-		if os.IsNotExist(err) {
-			doesLogDirExist = false
-		} else {
-			logrus.Fatalf("Could not check if logs directory exist: %s", err)
-		}
-	}
-	if !doesLogDirExist {
-		if err := os.Mkdir(config.LoggingFilePath, 0750); err != nil {
-			logrus.Fatalf("Could not create  directory %s: %s", config.LoggingFilePath, err)
-		}
+	logger, closeF, err := newLogger(config)
+	if err != nil {
+		logrus.Fatalf("Could not create logger: %s", err)
 	}
 
+	return logger, closeF
+}
+
+func newLogger(config *config.Config) (*logrus.Logger, func() error, error) {
 	level, err := logrus.ParseLevel(config.LoggingLevel)
 	if err != nil {
-		logrus.Fatalf("Could not parse logging level: %s", err)
+		return nil, nil, fmt.Errorf("could not parse logging level: %w", err)
 	}
 
 	logger := logrus.New()
+	logger.SetLevel(level)
 
-	format := fmt.Sprintf("%d-%02d-%02dT%02d:%02d:%02d",
-		time.Now().Year(),
-		time.Now().Month(),
-		time.Now().Day(),
-		time.Now().Hour(),
-		time.Now().Minute(),
-		time.Now().Second()) + ".log"
+	switch config.LoggingFormat {
+	case "", constants.LogFormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case constants.LogFormatText:
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true, DisableColors: true})
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", createdErrors.ErrUnknownLogFormat, config.LoggingFormat)
+	}
 
-	file, err := os.OpenFile(filepath.Clean(config.LoggingFilePath+format), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	outputs := config.LoggingOutputs
+	if len(outputs) == 0 {
+		outputs = []string{constants.LogOutputFile}
+	}
+	writers := make([]io.Writer, 0, len(outputs))
+	closeF := func() error { return nil }
+	for _, output := range outputs {
+		switch output {
+		case constants.LogOutputStdout:
+			writers = append(writers, os.Stdout)
+		case constants.LogOutputFile:
+			file, err := newLogFile(config)
+			if err != nil {
+				return nil, nil, err
+			}
+			writers = append(writers, file)
+			closeF = file.Close
+		default:
+			return nil, nil, fmt.Errorf("%w: %q", createdErrors.ErrUnknownLogOutput, output)
+		}
+	}
+	logger.SetOutput(io.MultiWriter(writers...))
+
+	return logger, closeF, nil
+}
+
+// newLogFile opens the log file that is rotated by size, rotated files are removed by age and count.
+func newLogFile(config *config.Config) (*lumberjack.Logger, error) {
+	if err := os.MkdirAll(config.LoggingFilePath, 0750); err != nil {
+		return nil, fmt.Errorf("could not create directory %s: %w", config.LoggingFilePath, err)
+	}
+
+	rotation := config.LogRotation
+	maxAgeDays := 0
+	if rotation.MaxAge.Duration > 0 {
+		maxAgeDays = int((rotation.MaxAge.Hours() + hoursInDay - 1) / hoursInDay)
+	}
+
+	return &lumberjack.Logger{
+		Filename:   filepath.Join(config.LoggingFilePath, constants.LogFileName),
+		MaxSize:    rotation.MaxSizeMB,
+		MaxAge:     maxAgeDays,
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+	}, nil
+}
+
+// SetLogLevel changes the level of the logger while the service is running.
+func SetLogLevel(logger *logrus.Logger, level string) error {
+	parsed, err := logrus.ParseLevel(level)
 	if err != nil {
-		logrus.Fatalf("Could not open file %s: %s", format, err)
+		return fmt.Errorf("%w: %q", createdErrors.ErrInvalidLogLevel, level)
 	}
 
-	logger.Writer()
-	logger.SetLevel(level)
-	logger.SetOutput(file)
-	logger.SetFormatter(&logrus.JSONFormatter{})
+	// logged before the change, so lowering of verbosity is seen too
+	logger.Infof("Log level is changed from %s to %s", logger.GetLevel(), parsed)
+	logger.SetLevel(parsed)
+
+	return nil
+}
 
-	return logger, file.Close
+// ReloadLogLevel sets the level of the logger from the config file each time the service receives SIGHUP,
+// until ctx is cancelled.
+func ReloadLogLevel(ctx context.Context, logger *logrus.Logger, configPath string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			var reloaded config.Config
+			if _, err := toml.DecodeFile(configPath, &reloaded); err != nil {
+				logger.Errorf("Could not reload config: %s", err)
+				continue
+			}
+			if err := SetLogLevel(logger, reloaded.LoggingLevel); err != nil {
+				logger.Errorf("Could not reload log level: %s", err)
+			}
+		}
+	}
 }
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/config"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      config.Config
+		expectedErr error
+		fileFormat  string // empty if the file is not written
+	}{
+		{
+			name:       "File in JSON by default",
+			config:     config.Config{LoggingLevel: "info"},
+			fileFormat: `"msg":"record"`,
+		},
+		{
+			name: "Stdout and file in text",
+			config: config.Config{LoggingLevel: "info", LoggingFormat: "text", LoggingOutputs: []string{"stdout", "file"},
+				LogRotation: config.LogRotationConfig{MaxSizeMB: 1, MaxAge: config.Duration{Duration: 36 * time.Hour}}},
+			fileFormat: `msg=record`,
+		},
+		{
+			name:   "Only stdout",
+			config: config.Config{LoggingLevel: "info", LoggingOutputs: []string{"stdout"}},
+		},
+		{
+			name:        "Unknown output",
+			config:      config.Config{LoggingLevel: "info", LoggingOutputs: []string{"syslog"}},
+			expectedErr: createdErrors.ErrUnknownLogOutput,
+		},
+		{
+			name:        "Unknown format",
+			config:      config.Config{LoggingLevel: "info", LoggingFormat: "xml"},
+			expectedErr: createdErrors.ErrUnknownLogFormat,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			test.config.LoggingFilePath = t.TempDir()
+
+			logger, closeF, err := newLogger(&test.config)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			logger.Info("record")
+			assert.NoError(t, closeF())
+
+			content, err := os.ReadFile(filepath.Join(test.config.LoggingFilePath, constants.LogFileName))
+			if test.fileFormat == "" {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Contains(t, string(content), test.fileFormat)
+			}
+		})
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	logger, hook := newTestLogger()
+	logger.SetLevel(logrus.InfoLevel)
+
+	assert.NoError(t, SetLogLevel(logger, "warn"))
+	assert.Equal(t, logrus.WarnLevel, logger.GetLevel())
+	assert.Len(t, hook.AllEntries(), 1, "the change is logged before the level is raised")
+
+	assert.ErrorIs(t, SetLogLevel(logger, "verbose"), createdErrors.ErrInvalidLogLevel)
+	assert.Equal(t, logrus.WarnLevel, logger.GetLevel())
+}
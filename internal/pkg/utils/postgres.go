@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/sirupsen/logrus"
 
 	"avito-tech-task/config"
@@ -11,14 +12,58 @@ import (
 
 type PgxIface interface {
 	Begin(context.Context) (pgx.Tx, error)
-	Close(context.Context) error
+	Ping(context.Context) error
+	Close()
 }
 
-func NewPostgresConnection(config *config.Config) *pgx.Conn {
-	conn, err := pgx.Connect(context.Background(), config.Server.DatabaseConnString)
+func NewPostgresPool(config *config.Config) *pgxpool.Pool {
+	poolConfig, err := pgxpool.ParseConfig(config.Server.DatabaseConnString)
+	if err != nil {
+		logrus.Fatalf("Could not parse database connection string: %s", err)
+	}
+
+	if config.Server.MaxConns > 0 {
+		poolConfig.MaxConns = config.Server.MaxConns
+	}
+	if config.Server.MinConns > 0 {
+		poolConfig.MinConns = config.Server.MinConns
+	}
+	if config.Server.MaxConnIdleTime.Duration > 0 {
+		poolConfig.MaxConnIdleTime = config.Server.MaxConnIdleTime.Duration
+	}
+	if config.Server.MaxConnLifetime.Duration > 0 {
+		poolConfig.MaxConnLifetime = config.Server.MaxConnLifetime.Duration
+	}
+	if config.Server.HealthCheckPeriod.Duration > 0 {
+		poolConfig.HealthCheckPeriod = config.Server.HealthCheckPeriod.Duration
+	}
+
+	// queries are logged with the request they are made for, the debug level can be enabled at runtime
+	poolConfig.ConnConfig.Logger = queryLogger{}
+	poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
 	if err != nil {
 		logrus.Fatalf("Could not establish connection to database: %s", err)
 	}
 
-	return conn
+	return pool
+}
+
+// queryLogger writes records of pgx, e.g. executed queries with their arguments and duration, to the logger
+// of the request from the query context.
+type queryLogger struct{}
+
+func (queryLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	entry := Log(ctx)
+	switch level {
+	case pgx.LogLevelError:
+		entry.WithFields(data).Error(msg)
+	case pgx.LogLevelWarn:
+		entry.WithFields(data).Warn(msg)
+	default: // every query is logged at the info level of pgx
+		if entry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+			entry.WithFields(data).Debug(msg)
+		}
+	}
 }
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+// NewProblem converts the error to problem details. Domain errors are shown with their code and message,
+// details of internal errors are hidden and only the correlation ID of their log record is shown.
+func NewProblem(err error, correlationID string) *models.Problem {
+	var domainErr *createdErrors.Error
+	var httpErr *echo.HTTPError
+	problem := &models.Problem{Type: "about:blank"}
+	switch {
+	case errors.As(err, &domainErr):
+		problem.Status, problem.Code = domainErr.Status, domainErr.Code
+		problem.Detail = err.Error()
+		if domainErr.Status >= http.StatusInternalServerError {
+			// wrapped errors of upstream services may contain their addresses
+			problem.Detail = domainErr.Error()
+		}
+	case errors.As(err, &httpErr):
+		problem.Status = httpErr.Code
+		problem.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(httpErr.Code), " ", "_"))
+		problem.Detail = fmt.Sprint(httpErr.Message)
+	default:
+		problem.Status, problem.Code = http.StatusInternalServerError, "internal"
+	}
+	problem.Title = http.StatusText(problem.Status)
+	if problem.Status >= http.StatusInternalServerError {
+		problem.CorrelationID = correlationID
+	}
+
+	var notSupported *createdErrors.NotSupportedCurrencyError
+	if errors.As(err, &notSupported) {
+		problem.Supported = notSupported.Supported
+	}
+
+	return problem
+}
+
+// NewHTTPErrorHandler renders errors returned by handlers and middlewares as application/problem+json.
+// The correlation ID of internal errors is the X-Request-ID of the response, a random one is used without it.
+func NewHTTPErrorHandler() echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		if ctx.Response().Committed {
+			return
+		}
+
+		correlationID := ctx.Response().Header().Get(echo.HeaderXRequestID)
+		if correlationID == "" {
+			correlationID = newRequestID()
+		}
+		problem := NewProblem(err, correlationID)
+		logger := Log(ctx.Request().Context())
+		if problem.Status >= http.StatusInternalServerError {
+			ctx.Response().Header().Set(echo.HeaderXRequestID, correlationID)
+			logger.Errorf("Internal server error %s: %s", correlationID, err)
+		} else {
+			logger.Warnf("Request failed with %s: %s", problem.Code, err)
+		}
+
+		if ctx.Request().Method == http.MethodHead {
+			err = ctx.NoContent(problem.Status)
+		} else {
+			ctx.Response().Header().Set(echo.HeaderContentType, constants.ProblemContentType)
+			ctx.Response().WriteHeader(problem.Status)
+			err = json.NewEncoder(ctx.Response()).Encode(problem)
+		}
+		if err != nil {
+			logger.Errorf("Could not send error response: %s", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"avito-tech-task/internal/app/models"
+	"avito-tech-task/internal/pkg/constants"
+	createdErrors "avito-tech-task/internal/pkg/errors"
+)
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected *models.Problem
+	}{
+		{
+			name: "Domain error",
+			err:  fmt.Errorf("%w: %q", createdErrors.ErrInvalidSort, "price"),
+			expected: &models.Problem{
+				Type:   "about:blank",
+				Title:  "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity,
+				Detail: createdErrors.ErrInvalidSort.Error() + `: "price"`,
+				Code:   "invalid_sort",
+			},
+		},
+		{
+			name: "Not supported currency with supported currencies",
+			err:  &createdErrors.NotSupportedCurrencyError{Currency: "GBP", Supported: []string{"RUB", "USD"}},
+			expected: &models.Problem{
+				Type:      "about:blank",
+				Title:     "Unprocessable Entity",
+				Status:    http.StatusUnprocessableEntity,
+				Detail:    `currency "GBP" is not supported, supported currencies: RUB, USD`,
+				Code:      "not_supported_currency",
+				Supported: []string{"RUB", "USD"},
+			},
+		},
+		{
+			name: "Details of upstream errors are hidden",
+			err:  fmt.Errorf("%w: cbr_json: Get http://cbr/latest.js: timeout", createdErrors.ErrRatesUnavailable),
+			expected: &models.Problem{
+				Type:          "about:blank",
+				Title:         "Bad Gateway",
+				Status:        http.StatusBadGateway,
+				Detail:        createdErrors.ErrRatesUnavailable.Error(),
+				Code:          "rates_unavailable",
+				CorrelationID: "id",
+			},
+		},
+		{
+			name: "Internal error",
+			err:  errors.New("pq: relation \"balance\" does not exist"),
+			expected: &models.Problem{
+				Type:          "about:blank",
+				Title:         "Internal Server Error",
+				Status:        http.StatusInternalServerError,
+				Code:          "internal",
+				CorrelationID: "id",
+			},
+		},
+		{
+			name: "Error of echo",
+			err:  echo.ErrNotFound,
+			expected: &models.Problem{
+				Type:   "about:blank",
+				Title:  "Not Found",
+				Status: http.StatusNotFound,
+				Detail: "Not Found",
+				Code:   "not_found",
+			},
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, NewProblem(test.err, "id"))
+		})
+	}
+}
+
+func TestNewHTTPErrorHandler(t *testing.T) {
+	handler := NewHTTPErrorHandler()
+	server := echo.New()
+
+	rec := httptest.NewRecorder()
+	ctx := server.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+	handler(createdErrors.ErrUserDoesNotExist, ctx)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, constants.ProblemContentType, rec.Header().Get(echo.HeaderContentType))
+	assert.Empty(t, rec.Header().Get(echo.HeaderXRequestID))
+
+	rec = httptest.NewRecorder()
+	ctx = server.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+	handler(errors.New("connection refused"), ctx)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	correlationID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, correlationID, 32, "random correlation ID is sent in the header")
+	assert.Contains(t, rec.Body.String(), correlationID)
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
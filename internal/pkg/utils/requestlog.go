@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const maxRequestIDLength = 128
+
+type requestLogKey struct{}
+
+// requestLog is the logger of one request, fields found while handling the request are added to it.
+type requestLog struct {
+	mutex sync.Mutex
+	entry *logrus.Entry
+}
+
+// WithLogger returns the context with the logger of the request.
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, requestLogKey{}, &requestLog{entry: entry})
+}
+
+// Log returns the logger of the request with its request ID, route and user ID. Outside of requests, e.g. in
+// background jobs, the standard logger is returned.
+func Log(ctx context.Context) *logrus.Entry {
+	if log, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		log.mutex.Lock()
+		defer log.mutex.Unlock()
+		return log.entry
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// AddLogFields adds the fields to all following records of the request, e.g. the user ID once it is parsed,
+// and returns the updated logger.
+func AddLogFields(ctx context.Context, fields logrus.Fields) *logrus.Entry {
+	log, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return Log(ctx).WithFields(fields)
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.entry = log.entry.WithFields(fields)
+
+	return log.entry
+}
+
+// RequestLogger takes the request ID from the X-Request-ID header or generates it, returns it in the response and
+// attaches the logger of the request to the request context. The status and latency are logged when it completes.
+func RequestLogger(logger *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			requestID := ctx.Request().Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			ctx.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			entry := logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"method":     ctx.Request().Method,
+				"route":      ctx.Path(),
+			})
+			ctx.SetRequest(ctx.Request().WithContext(WithLogger(ctx.Request().Context(), entry)))
+
+			if err := next(ctx); err != nil {
+				// the error is rendered here, so its status is logged
+				ctx.Error(err)
+			}
+
+			Log(ctx.Request().Context()).WithFields(logrus.Fields{
+				"status":     ctx.Response().Status,
+				"latency_ms": time.Since(start).Milliseconds(),
+			}).Info("Request completed")
+
+			return nil
+		}
+	}
+}
+
+// validRequestID accepts request IDs of clients that are safe to write to logs and headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, char := range id {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{
+			name:      "Request ID of client is used",
+			requestID: "client-request-id",
+		},
+		{
+			name:      "Request ID is generated without header",
+			generated: true,
+		},
+		{
+			name:      "Request ID with spaces is replaced",
+			requestID: "client request id",
+			generated: true,
+		},
+		{
+			name:      "Too long request ID is replaced",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+			generated: true,
+		},
+	}
+
+	for _, current := range tests {
+		test := current
+		t.Run(test.name, func(t *testing.T) {
+			logger, hook := newTestLogger()
+			server := echo.New()
+			server.HTTPErrorHandler = NewHTTPErrorHandler()
+			server.Use(RequestLogger(logger))
+			server.GET("/balance/:id", func(ctx echo.Context) error {
+				AddLogFields(ctx.Request().Context(), logrus.Fields{"user_id": ctx.Param("id")})
+				Log(ctx.Request().Context()).Info("Handled")
+				return ctx.NoContent(http.StatusNoContent)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/balance/7", nil)
+			if test.requestID != "" {
+				request.Header.Set(echo.HeaderXRequestID, test.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, request)
+
+			requestID := recorder.Header().Get(echo.HeaderXRequestID)
+			if test.generated {
+				assert.Len(t, requestID, 32)
+			} else {
+				assert.Equal(t, test.requestID, requestID)
+			}
+
+			if assert.Len(t, hook.AllEntries(), 2) {
+				handled, completed := hook.AllEntries()[0], hook.AllEntries()[1]
+				assert.Equal(t, requestID, handled.Data["request_id"])
+				assert.Equal(t, "/balance/:id", handled.Data["route"])
+				assert.Equal(t, "7", handled.Data["user_id"])
+				assert.Equal(t, "7", completed.Data["user_id"], "fields added by handler are kept")
+				assert.Equal(t, http.StatusNoContent, completed.Data["status"])
+				assert.Contains(t, completed.Data, "latency_ms")
+			}
+		})
+	}
+}
+
+func TestRequestLogger_ErrorStatus(t *testing.T) {
+	logger, hook := newTestLogger()
+	server := echo.New()
+	server.HTTPErrorHandler = NewHTTPErrorHandler()
+	server.Use(RequestLogger(logger))
+	server.GET("/", func(ctx echo.Context) error {
+		return echo.ErrNotFound
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, http.StatusNotFound, last.Data["status"])
+	}
+}
+
+func TestLog_OutsideOfRequest(t *testing.T) {
+	ctx := httptest.NewRequest(http.MethodGet, "/", nil).Context()
+	assert.NotNil(t, Log(ctx))
+	assert.Equal(t, "value", AddLogFields(ctx, logrus.Fields{"key": "value"}).Data["key"])
+}
+
+func newTestLogger() (*logrus.Logger, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	return logger, hook
+}
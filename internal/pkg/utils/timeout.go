@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ContextTimeout limits the request context, so database queries of the request are cancelled
+// when the timeout expires or the client disconnects.
+func ContextTimeout(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if timeout <= 0 {
+				return next(ctx)
+			}
+
+			timeoutCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+			defer cancel()
+			ctx.SetRequest(ctx.Request().WithContext(timeoutCtx))
+
+			return next(ctx)
+		}
+	}
+}